@@ -0,0 +1,115 @@
+package consistent
+
+import (
+	"math"
+	"sort"
+)
+
+// The number of placements AdviseRebalance tries when WithAdviseIterations
+// is not given.
+const defaultAdviseIterations = 200
+
+// The largest weight AdviseRebalance suggests, and the number of placements
+// without improvement after which it makes the placement finer.
+const (
+	maxAdviseWeight = 1 << 12
+	adviseStall     = 8
+)
+
+// Adjustment is a suggested weight for an item.
+type Adjustment struct {
+	Node   string
+	Weight int // The suggested weight
+	Delta  int // The change from the current weight
+}
+
+// Let AdviseRebalance try up to n placements.
+func WithAdviseIterations(n int) Option {
+	return func(m *Consistent) {
+		if n > 0 {
+			m.adviseIterations = n
+		}
+	}
+}
+
+// Suggest weights that bring Stats().Imbalance, the peak share over the
+// mean, to at most target. Placements are simulated on a copy of the ring
+// until the target is met or the iterations run out. Each one scales every
+// item's weight by the square root of the mean share over the item's share,
+// so overloaded items shrink and underloaded ones grow. Once adviseStall
+// placements in a row bring no improvement, the search goes on from the best
+// weights doubled, for a finer placement, up to maxAdviseWeight. The best
+// placement found is returned, sorted by name. The hash is not changed,
+// apply the advice with Update.
+func (m *Consistent) AdviseRebalance(target float64) []Adjustment {
+	m.RLock()
+	r := m.ring.clone()
+	iterations := m.adviseIterations
+	m.RUnlock()
+	r.sortKeys()
+
+	start := make(map[string]int, len(r.nodes))
+	for key, mem := range r.nodes {
+		start[key] = mem.weight
+	}
+
+	best := r.stats(m.strictPins, m.atCapacity).Imbalance
+	bestWeights := start
+	stalled, finer := 0, 0 // Doublings of the best weights since they were found
+	for n := 0; n < iterations && best > target; n++ {
+		s := r.stats(m.strictPins, m.atCapacity)
+		if len(s.Shares) < 2 || s.Mean == 0 {
+			break
+		}
+
+		weights := make(map[string]int, len(s.Shares))
+		if stalled < adviseStall {
+			for key, share := range s.Shares {
+				w := r.nodes[key].weight
+				if w == 0 {
+					continue // Drained on purpose
+				}
+				scaled := float64(w) * math.Sqrt(s.Mean/math.Max(share, s.Mean/4))
+				weights[key] = min(max(1, int(math.Round(scaled))), maxAdviseWeight)
+			}
+		} else {
+			stalled, finer = 0, finer+1
+			for key := range s.Shares {
+				if weights[key] = bestWeights[key] << finer; weights[key] > maxAdviseWeight {
+					return adjustments(start, bestWeights)
+				}
+			}
+		}
+		for _, key := range sortedKeys(weights) {
+			r.setWeight(key, weights[key])
+		}
+		r.sortKeys()
+
+		if imbalance := r.stats(m.strictPins, m.atCapacity).Imbalance; imbalance < best {
+			best, bestWeights, stalled, finer = imbalance, weights, 0, 0
+		} else {
+			stalled++
+		}
+	}
+	return adjustments(start, bestWeights)
+}
+
+// List the weights that differ from the starting ones, sorted by name.
+func adjustments(start, weights map[string]int) []Adjustment {
+	var advice []Adjustment
+	for _, key := range sortedKeys(weights) {
+		if w := weights[key]; w != start[key] {
+			advice = append(advice, Adjustment{Node: key, Weight: w, Delta: w - start[key]})
+		}
+	}
+	return advice
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
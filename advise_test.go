@@ -0,0 +1,114 @@
+package consistent
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Apply advice to a hash.
+func applyAdvice(t *testing.T, m *Consistent, advice []Adjustment) {
+	t.Helper()
+	err := m.Update(func(tx *Tx) error {
+		for _, a := range advice {
+			if err := tx.SetWeight(a.Node, a.Weight); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAdviseRebalanceReachesTarget(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		build  func(m *Consistent)
+		target float64
+	}{
+		{"one heavy item", func(m *Consistent) {
+			for i := 0; i < 10; i++ {
+				m.AddWithWeight(fmt.Sprint("n", i), 20)
+			}
+			m.SetWeight("n3", 60)
+		}, 1.25},
+		{"skewed weights", func(m *Consistent) {
+			for i := 0; i < 8; i++ {
+				m.AddWithWeight(fmt.Sprint("n", i), 1+i*i)
+			}
+		}, 1.2},
+		{"one point each", func(m *Consistent) {
+			for i := 0; i < 12; i++ {
+				m.Add(fmt.Sprint("n", i))
+			}
+		}, 1.3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(nil)
+			tc.build(m)
+			before, fp := m.Stats().Imbalance, m.Fingerprint()
+			advice := m.AdviseRebalance(tc.target)
+			if m.Fingerprint() != fp {
+				t.Fatal("advising changed the hash")
+			}
+			applyAdvice(t, m, advice)
+			after := m.Stats().Imbalance
+			if after > tc.target {
+				t.Fatalf("imbalance %.3f before, %.3f after, want at most %.2f", before, after, tc.target)
+			}
+			for _, a := range advice {
+				if a.Weight < 1 || a.Weight > maxAdviseWeight {
+					t.Fatalf("advised weight %d for %s", a.Weight, a.Node)
+				}
+			}
+
+			// Advice for a balanced hash is to leave it
+			if again := m.AdviseRebalance(tc.target); len(again) != 0 {
+				t.Fatalf("advice %v for a hash at the target", again)
+			}
+		})
+	}
+}
+
+// Weights settle rather than growing every round: chasing a target it
+// cannot reach, the advice after 300 rounds is the advice after 3000.
+func TestAdviseRebalanceConverges(t *testing.T) {
+	advise := func(iterations int) []Adjustment {
+		m := New(nil, WithAdviseIterations(iterations))
+		for i := 0; i < 10; i++ {
+			m.AddWithWeight(fmt.Sprint("n", i), 20)
+		}
+		m.SetWeight("n3", 60)
+		return m.AdviseRebalance(1)
+	}
+	short, long := advise(300), advise(3000)
+	if !reflect.DeepEqual(short, long) {
+		t.Fatalf("advice after 300 rounds %v, after 3000 %v", short, long)
+	}
+}
+
+func TestAdviseRebalanceSingleItem(t *testing.T) {
+	m := New(nil)
+	if advice := m.AdviseRebalance(1); advice != nil {
+		t.Fatalf("advice %v for an empty hash", advice)
+	}
+	m.Add("a")
+	if advice := m.AdviseRebalance(1); advice != nil {
+		t.Fatalf("advice %v for one item", advice)
+	}
+}
+
+func TestAdviseRebalanceKeepsDrained(t *testing.T) {
+	m := New(nil)
+	for i := 0; i < 6; i++ {
+		m.AddWithWeight(fmt.Sprint("n", i), 1+i)
+	}
+	m.AddWithWeight("drained", 0)
+	for _, a := range m.AdviseRebalance(1.2) {
+		if a.Node == "drained" {
+			t.Fatalf("advised weight %d for an item with none", a.Weight)
+		}
+	}
+}
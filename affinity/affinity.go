@@ -0,0 +1,249 @@
+// Package affinity provides net/http middleware that sends each session to
+// the backend a consistent hash assigns its key to, for backends holding
+// session state such as WebSocket or server-sent event servers.
+package affinity
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// KeyFunc extracts the affinity key of a request.
+type KeyFunc func(r *http.Request) string
+
+// Use the value of a cookie as the affinity key.
+func Cookie(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if c, err := r.Cookie(name); err == nil {
+			return c.Value
+		}
+		return ""
+	}
+}
+
+// Use the value of a request header as the affinity key.
+func Header(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Affinity resolves requests to backends. Backends are the members of the
+// hash.
+type Affinity struct {
+	ring     *consistent.Consistent
+	key      KeyFunc
+	self     string
+	header   string
+	cookie   string
+	target   func(node string) (*url.URL, error)
+	reassign func(key, from, to string)
+
+	mu       sync.Mutex
+	sessions map[*session]struct{} // Requests in flight, checked on every change to the hash
+	stop     func()
+}
+
+// A request in flight, and the owner it was sent to.
+type session struct {
+	key, owner string
+	done       chan struct{} // Closed when the key is reassigned
+	cancel     context.CancelFunc
+}
+
+type sessionKey struct{}
+
+type Option func(*Affinity)
+
+// Name the backend running this process, for ShouldHandle.
+func WithSelf(node string) Option {
+	return func(a *Affinity) {
+		a.self = node
+	}
+}
+
+// Name the response header Assign sets to the owner. The default is
+// X-Backend; an empty name sets no header.
+func WithHeader(name string) Option {
+	return func(a *Affinity) {
+		a.header = name
+	}
+}
+
+// Make Assign also set a cookie with the given name to the owner.
+func WithCookie(name string) Option {
+	return func(a *Affinity) {
+		a.cookie = name
+	}
+}
+
+// Map a backend to the URL Proxy sends its requests to. By default the
+// backend's name is parsed as the URL.
+func WithTarget(fn func(node string) (*url.URL, error)) Option {
+	return func(a *Affinity) {
+		a.target = fn
+	}
+}
+
+// Call fn when the owner of a request in flight changes because of a change
+// to the hash.
+func OnReassign(fn func(key, from, to string)) Option {
+	return func(a *Affinity) {
+		a.reassign = fn
+	}
+}
+
+// Create an affinity over ring using key to extract each request's key. A
+// request without a key uses its remote address instead. Close stops
+// watching the hash.
+func New(ring *consistent.Consistent, key KeyFunc, opts ...Option) *Affinity {
+	a := &Affinity{
+		ring:     ring,
+		key:      key,
+		header:   "X-Backend",
+		target:   url.Parse,
+		sessions: make(map[*session]struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.stop = ring.Watch(func(consistent.Event) { a.check() })
+	return a
+}
+
+// Stop watching the hash for reassignments.
+func (a *Affinity) Close() {
+	a.stop()
+}
+
+// Get the affinity key of a request.
+func (a *Affinity) Key(r *http.Request) string {
+	if key := a.key(r); key != "" {
+		return key
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// Get the owner of a request and whether it is this backend, as named by
+// WithSelf, so a backend can decide whether to serve it or redirect it.
+func (a *Affinity) ShouldHandle(r *http.Request) (owner string, mine bool) {
+	owner = a.ring.Get(a.Key(r))
+	return owner, owner != "" && owner == a.self
+}
+
+// Returns a channel closed when the owner of a request passed through Assign
+// or Proxy changes, so a long-lived handler can tell its client to
+// reconnect. Other requests get a nil channel.
+func Reassigned(r *http.Request) <-chan struct{} {
+	if s, ok := r.Context().Value(sessionKey{}).(*session); ok {
+		return s.done
+	}
+	return nil
+}
+
+// Identify the owner of each request to the client with the response header
+// and cookie, then call next. Responds 503 if the hash is empty.
+func (a *Affinity) Assign(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := a.Key(r)
+		s := a.open(key, nil)
+		if s == nil {
+			http.Error(w, "no backend available", http.StatusServiceUnavailable)
+			return
+		}
+		defer a.close(s)
+
+		if a.header != "" {
+			w.Header().Set(a.header, s.owner)
+		}
+		if a.cookie != "" {
+			http.SetCookie(w, &http.Cookie{Name: a.cookie, Value: s.owner, Path: "/"})
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+	})
+}
+
+// Proxy each request to its owner. A request in flight is cancelled when its
+// key is reassigned, so the client reconnects to the new owner. Responds
+// 503 if the hash is empty and 502 if the owner has no valid URL.
+func (a *Affinity) Proxy() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		s := a.open(a.Key(r), cancel)
+		if s == nil {
+			http.Error(w, "no backend available", http.StatusServiceUnavailable)
+			return
+		}
+		defer a.close(s)
+
+		target, err := a.target(s.owner)
+		if err != nil {
+			http.Error(w, "invalid backend", http.StatusBadGateway)
+			return
+		}
+		if a.header != "" {
+			w.Header().Set(a.header, s.owner)
+		}
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ServeHTTP(w, r.WithContext(context.WithValue(ctx, sessionKey{}, s)))
+	})
+}
+
+// Start tracking a request in flight, to be cancelled on reassignment if
+// cancel is set. Returns nil if the key has no owner. The owner is looked up
+// under the lock, so a change to the hash after the lookup finds the session
+// registered when its watcher checks.
+func (a *Affinity) open(key string, cancel context.CancelFunc) *session {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	owner := a.ring.Get(key)
+	if owner == "" {
+		return nil
+	}
+	s := &session{key: key, owner: owner, done: make(chan struct{}), cancel: cancel}
+	a.sessions[s] = struct{}{}
+	return s
+}
+
+func (a *Affinity) close(s *session) {
+	a.mu.Lock()
+	delete(a.sessions, s)
+	a.mu.Unlock()
+}
+
+// Signal every request in flight whose key has moved.
+func (a *Affinity) check() {
+	type move struct{ key, from, to string }
+	var moves []move
+
+	a.mu.Lock()
+	for s := range a.sessions {
+		to := a.ring.Get(s.key)
+		if to == s.owner {
+			continue
+		}
+		delete(a.sessions, s)
+		close(s.done)
+		if s.cancel != nil {
+			s.cancel()
+		}
+		moves = append(moves, move{s.key, s.owner, to})
+	}
+	a.mu.Unlock()
+
+	if a.reassign != nil {
+		for _, mv := range moves {
+			a.reassign(mv.key, mv.from, mv.to)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package affinity
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// Start three backends answering with their name, or holding /stream open
+// until the request is cancelled, and add them to a ring by URL.
+func backends(t *testing.T) (*consistent.Consistent, map[string]string) {
+	t.Helper()
+	ring := consistent.New(nil, consistent.WithReplicas(20))
+	names := make(map[string]string)
+	for _, name := range []string{"a", "b", "c"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/stream" {
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+				return
+			}
+			io.WriteString(w, name)
+		}))
+		t.Cleanup(srv.Close)
+		ring.Add(srv.URL)
+		names[srv.URL] = name
+	}
+	return ring, names
+}
+
+func TestProxy(t *testing.T) {
+	ring, names := backends(t)
+	var mu sync.Mutex
+	var moves []string
+	a := New(ring, Header("X-Session"), OnReassign(func(key, from, to string) {
+		mu.Lock()
+		defer mu.Unlock()
+		moves = append(moves, fmt.Sprintf("%s %s->%s", key, names[from], names[to]))
+	}))
+	defer a.Close()
+	front := httptest.NewServer(a.Proxy())
+	defer front.Close()
+
+	request := func(key, path string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, front.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Session", key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	get := func(key string) (body, owner string) {
+		t.Helper()
+		resp := request(key, "/")
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b), resp.Header.Get("X-Backend")
+	}
+
+	first, owner := get("user1")
+	if names[owner] != first || ring.Get("user1") != owner {
+		t.Fatalf("served by %s, header %s, ring owner %s", first, owner, ring.Get("user1"))
+	}
+	for i := 0; i < 5; i++ {
+		if got, _ := get("user1"); got != first {
+			t.Fatalf("request %d served by %s, want %s", i, got, first)
+		}
+	}
+
+	// Removing the owner cuts the stream, and the next request goes elsewhere
+	resp := request("user1", "/stream")
+	cut := make(chan struct{})
+	go func() {
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+		close(cut)
+	}()
+	for {
+		// Wait for the stream to be registered
+		a.mu.Lock()
+		n := len(a.sessions)
+		a.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ring.Remove(owner)
+	select {
+	case <-cut:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the stream to the removed owner was not cut")
+	}
+	mu.Lock()
+	if len(moves) != 1 {
+		t.Fatalf("reassigned %q", moves)
+	}
+	mu.Unlock()
+	if got, _ := get("user1"); got == first {
+		t.Fatal("still served by the removed owner")
+	}
+
+	for _, url := range ring.Members() {
+		ring.Remove(url)
+	}
+	if resp := request("user1", "/"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("empty ring: status %d", resp.StatusCode)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	ring, _ := backends(t)
+	self := ring.Members()[0]
+	a := New(ring, Cookie("session"), WithSelf(self), WithCookie("backend"))
+	defer a.Close()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "user1"})
+	owner, mine := a.ShouldHandle(r)
+	if owner != ring.Get("user1") || mine != (owner == self) {
+		t.Fatalf("ShouldHandle = %s, %v", owner, mine)
+	}
+
+	var reassigned <-chan struct{}
+	rec := httptest.NewRecorder()
+	a.Assign(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reassigned = Reassigned(r)
+	})).ServeHTTP(rec, r)
+	if reassigned == nil || rec.Header().Get("X-Backend") != owner {
+		t.Fatalf("header %q, channel %v", rec.Header(), reassigned)
+	}
+	if c := rec.Result().Cookies(); len(c) != 1 || c[0].Name != "backend" || c[0].Value != owner {
+		t.Fatalf("cookies %v", c)
+	}
+	if Reassigned(r) != nil {
+		t.Fatal("a request outside Assign has a channel")
+	}
+
+	// Without a key the remote address is used
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	if got := a.Key(r); got != "10.0.0.1" {
+		t.Fatalf("key %q", got)
+	}
+}
+
+// A change to the ring right after open looks the owner up is seen by the
+// session: the owner is removed from inside the lookup, and the session
+// must end up reassigned rather than left with the removed owner.
+func TestOpenRacingChange(t *testing.T) {
+	var ring *consistent.Consistent
+	var a *Affinity
+	removed := make(chan struct{})
+	var once sync.Once
+	observe := func(key, node string) {
+		if key != "race" {
+			return
+		}
+		first := false
+		once.Do(func() { first = true })
+		if !first {
+			return // The watcher's own lookup
+		}
+		go func() {
+			ring.Remove(node)
+			close(removed)
+		}()
+		// Give the removal and its watcher time to run; with open holding
+		// the lock the watcher waits for it instead
+		select {
+		case <-removed:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	ring = consistent.New(nil, consistent.WithReplicas(20), consistent.WithLookupObserver(observe))
+	for _, name := range []string{"a", "b", "c"} {
+		ring.Add(name)
+	}
+	a = New(ring, Header("X-Session"))
+	defer a.Close()
+
+	s := a.open("race", nil)
+	<-removed
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("session still sent to the removed %s", s.owner)
+	}
+}
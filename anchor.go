@@ -0,0 +1,174 @@
+package consistent
+
+import (
+	"errors"
+	"hash/crc32"
+	"sync"
+)
+
+var (
+	ErrAnchorFull   = errors.New("consistent: every bucket is working")
+	ErrInvalidSize  = errors.New("consistent: working buckets must be between 1 and the capacity")
+	ErrNoSuchBucket = errors.New("consistent: bucket is not working")
+)
+
+// Anchor is an AnchorHash (Mendelson et al., 2020) over a fixed capacity of
+// buckets, each of which may be named by an item. Lookups take constant
+// expected time when most buckets are working. Removing a bucket only
+// remaps the keys on it, and adding a bucket back restores the last removed
+// one, so its keys return.
+type Anchor struct {
+	sync.RWMutex
+	hash Hash
+
+	// As in the paper: A is zero for working buckets and the size of the
+	// working set when each other bucket was removed, K the successor of
+	// each removed bucket, W the working buckets with L the index of each in
+	// W, R the removed buckets, N the number working.
+	a, k, w, l []int32
+	r          []int32
+	n          int32
+
+	names   []string // By bucket
+	buckets map[string]int32
+}
+
+// Create an AnchorHash of capacity buckets, of which the first
+// initialWorking are working and unnamed.
+func NewAnchor(capacity, initialWorking int) (*Anchor, error) {
+	if initialWorking < 1 || initialWorking > capacity {
+		return nil, ErrInvalidSize
+	}
+
+	h := &Anchor{
+		hash:    crc32.ChecksumIEEE,
+		a:       make([]int32, capacity),
+		k:       make([]int32, capacity),
+		w:       make([]int32, capacity),
+		l:       make([]int32, capacity),
+		names:   make([]string, capacity),
+		buckets: make(map[string]int32),
+		n:       int32(initialWorking),
+	}
+	for b := range h.a {
+		h.k[b], h.w[b], h.l[b] = int32(b), int32(b), int32(b)
+	}
+	for b := capacity - 1; b >= initialWorking; b-- {
+		h.r = append(h.r, int32(b))
+		h.a[b] = int32(b)
+	}
+	return h, nil
+}
+
+// Returns the number of working buckets.
+func (h *Anchor) Working() int {
+	h.RLock()
+	defer h.RUnlock()
+	return int(h.n)
+}
+
+// Name a working bucket.
+func (h *Anchor) SetNode(bucket int, node string) error {
+	h.Lock()
+	defer h.Unlock()
+	if bucket < 0 || bucket >= len(h.a) || h.a[bucket] != 0 {
+		return ErrNoSuchBucket
+	}
+	if _, ok := h.buckets[node]; ok {
+		return ErrNodeExists
+	}
+	delete(h.buckets, h.names[bucket])
+	h.names[bucket] = node
+	h.buckets[node] = int32(bucket)
+	return nil
+}
+
+// Returns the bucket an item names.
+func (h *Anchor) Bucket(node string) (int, bool) {
+	h.RLock()
+	defer h.RUnlock()
+	b, ok := h.buckets[node]
+	return int(b), ok
+}
+
+// Bring back the last removed bucket, named node. Returns the bucket.
+func (h *Anchor) AddBucket(node string) (int, error) {
+	h.Lock()
+	defer h.Unlock()
+	if len(h.r) == 0 {
+		return 0, ErrAnchorFull
+	}
+	if _, ok := h.buckets[node]; ok {
+		return 0, ErrNodeExists
+	}
+
+	b := h.r[len(h.r)-1]
+	h.r = h.r[:len(h.r)-1]
+	h.a[b] = 0
+	h.l[h.w[h.n]] = h.n
+	h.w[h.l[b]] = b
+	h.k[b] = b
+	h.n++
+
+	h.names[b] = node
+	h.buckets[node] = b
+	return int(b), nil
+}
+
+// Remove the bucket an item names. Only the keys on it move.
+func (h *Anchor) RemoveBucket(node string) error {
+	h.Lock()
+	defer h.Unlock()
+	b, ok := h.buckets[node]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if h.n == 1 {
+		return ErrInvalidSize
+	}
+	delete(h.buckets, node)
+	h.names[b] = ""
+	h.removeBucket(b)
+	return nil
+}
+
+func (h *Anchor) removeBucket(b int32) {
+	h.r = append(h.r, b)
+	h.n--
+	h.a[b] = h.n
+	h.w[h.l[b]] = h.w[h.n]
+	h.l[h.w[h.n]] = h.l[b]
+	h.k[b] = h.w[h.n]
+}
+
+// Get the working bucket for the provided key.
+func (h *Anchor) GetBucket(key string) int {
+	hash := uint64(h.hash([]byte(key)))
+
+	h.RLock()
+	defer h.RUnlock()
+	return int(h.bucket(hash))
+}
+
+// Get the item naming the working bucket for the provided key, or "" if
+// the bucket is unnamed.
+func (h *Anchor) Get(key string) string {
+	hash := uint64(h.hash([]byte(key)))
+
+	h.RLock()
+	defer h.RUnlock()
+	return h.names[h.bucket(hash)]
+}
+
+func (h *Anchor) bucket(hash uint64) int32 {
+	b := int32(mix64(hash) % uint64(len(h.a)))
+	for h.a[b] > 0 {
+		// Rehash among the buckets working when b was removed
+		c := int32(mix64(hash<<32|uint64(b)) % uint64(h.a[b]))
+		for h.a[c] >= h.a[b] {
+			c = h.k[c]
+		}
+		b = c
+	}
+	return b
+}
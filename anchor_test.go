@@ -0,0 +1,174 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// The AnchorHash of the paper spelled out: each removed bucket keeps a copy
+// of the working buckets, in slot order, from when it was removed, and a key
+// on a removed bucket is rehashed into that copy.
+type anchorRef struct {
+	capacity int
+	working  []int32
+	removed  []int32
+	snapshot map[int32][]int32
+	slot     map[int32]int // The slot each removed bucket left
+}
+
+func newAnchorRef(capacity, initialWorking int) *anchorRef {
+	r := &anchorRef{capacity: capacity, snapshot: make(map[int32][]int32), slot: make(map[int32]int)}
+	for b := 0; b < capacity; b++ {
+		r.working = append(r.working, int32(b))
+	}
+	for b := capacity - 1; b >= initialWorking; b-- {
+		r.remove(int32(b))
+	}
+	return r
+}
+
+func (r *anchorRef) remove(b int32) {
+	l := 0
+	for r.working[l] != b {
+		l++
+	}
+	n := len(r.working) - 1
+	r.working[l] = r.working[n]
+	r.working = r.working[:n]
+	r.snapshot[b] = append([]int32(nil), r.working...)
+	r.slot[b] = l
+	r.removed = append(r.removed, b)
+}
+
+func (r *anchorRef) add() int32 {
+	b := r.removed[len(r.removed)-1]
+	r.removed = r.removed[:len(r.removed)-1]
+	if l := r.slot[b]; l < len(r.working) {
+		r.working = append(r.working, r.working[l])
+		r.working[l] = b
+	} else {
+		r.working = append(r.working, b)
+	}
+	delete(r.snapshot, b)
+	return b
+}
+
+func (r *anchorRef) bucket(hash uint64) int32 {
+	b := int32(mix64(hash) % uint64(r.capacity))
+	for s, ok := r.snapshot[b]; ok; s, ok = r.snapshot[b] {
+		b = s[mix64(hash<<32|uint64(b))%uint64(len(s))]
+	}
+	return b
+}
+
+// Over random interleavings of adds and removes the anchor maps every key
+// like the reference, removals only move the removed bucket's keys and adds
+// only move keys to the added bucket.
+func TestAnchorMatchesReference(t *testing.T) {
+	const capacity, initial = 64, 40
+	h, err := NewAnchor(capacity, initial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := newAnchorRef(capacity, initial)
+	for b := 0; b < initial; b++ {
+		if err := h.SetNode(b, fmt.Sprint("n", b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys := testKeys(2000)
+	routing := func(step int) []string {
+		t.Helper()
+		out := make([]string, len(keys))
+		for i, k := range keys {
+			got, want := h.GetBucket(k), ref.bucket(uint64(h.hash([]byte(k))))
+			if got != int(want) {
+				t.Fatalf("step %d: %s on bucket %d, reference %d", step, k, got, want)
+			}
+			if out[i] = h.Get(k); out[i] == "" {
+				t.Fatalf("step %d: %s on unnamed bucket %d", step, k, got)
+			}
+		}
+		return out
+	}
+
+	rnd := rand.New(rand.NewSource(3))
+	working := make([]string, 0, capacity)
+	for b := 0; b < initial; b++ {
+		working = append(working, fmt.Sprint("n", b))
+	}
+	before := routing(0)
+	for step := 1; step <= 1000; step++ {
+		if rnd.Intn(2) == 0 && len(working) > 1 {
+			i := rnd.Intn(len(working))
+			victim := working[i]
+			b, _ := h.Bucket(victim)
+			working = append(working[:i], working[i+1:]...)
+			if err := h.RemoveBucket(victim); err != nil {
+				t.Fatal(err)
+			}
+			ref.remove(int32(b))
+			after := routing(step)
+			for i := range keys {
+				if before[i] != victim && after[i] != before[i] {
+					t.Fatalf("step %d: removing %s moved %s from %s", step, victim, keys[i], before[i])
+				}
+			}
+			before = after
+			continue
+		}
+
+		name := fmt.Sprint("m", step)
+		b, err := h.AddBucket(name)
+		if err == ErrAnchorFull {
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if want := ref.add(); b != int(want) {
+			t.Fatalf("step %d: added bucket %d, reference %d", step, b, want)
+		}
+		working = append(working, name)
+		after := routing(step)
+		for i := range keys {
+			if after[i] != before[i] && after[i] != name {
+				t.Fatalf("step %d: adding %s moved %s to %s", step, name, keys[i], after[i])
+			}
+		}
+		before = after
+	}
+	if h.Working() != len(working) {
+		t.Fatal(h.Working(), len(working))
+	}
+}
+
+func TestAnchorErrors(t *testing.T) {
+	if _, err := NewAnchor(4, 0); err != ErrInvalidSize {
+		t.Fatal(err)
+	}
+	if _, err := NewAnchor(4, 5); err != ErrInvalidSize {
+		t.Fatal(err)
+	}
+	h, _ := NewAnchor(2, 1)
+	h.SetNode(0, "a")
+	if err := h.SetNode(1, "b"); err != ErrNoSuchBucket {
+		t.Fatal(err)
+	}
+	if err := h.RemoveBucket("a"); err != ErrInvalidSize {
+		t.Fatal(err)
+	}
+	if _, err := h.AddBucket("a"); err != ErrNodeExists {
+		t.Fatal(err)
+	}
+	if _, err := h.AddBucket("b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.AddBucket("c"); err != ErrAnchorFull {
+		t.Fatal(err)
+	}
+	if err := h.RemoveBucket("x"); err != ErrNodeNotFound {
+		t.Fatal(err)
+	}
+}
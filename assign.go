@@ -0,0 +1,116 @@
+package consistent
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Group keys by the item Get returns for them, pins included, against a
+// single view of the membership. Keys within each group are sorted.
+func (m *Consistent) AssignAll(keys []string) map[string][]string {
+	fn := m.hash.Load()
+	hashes := make([]int, len(keys))
+	for i, key := range keys {
+		hashes[i] = m.Hash(key)
+	}
+	return m.assign(keys, hashes, fn)
+}
+
+// AssignAll, hashing the keys across GOMAXPROCS goroutines.
+func (m *Consistent) AssignAllParallel(keys []string) map[string][]string {
+	fn := m.hash.Load()
+	hashes := make([]int, len(keys))
+	workers := runtime.GOMAXPROCS(0)
+	chunk := (len(keys) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+	for from := 0; from < len(keys); from += chunk {
+		to := min(from+chunk, len(keys))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				hashes[i] = m.Hash(keys[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	return m.assign(keys, hashes, fn)
+}
+
+// Group keys hashed outside the lock, rehashing them if SetHash replaced
+// fn, the hash function current when hashing started.
+func (m *Consistent) assign(keys []string, hashes []int, fn *Hash) map[string][]string {
+	groups := make(map[string][]string)
+
+	m.RLock()
+	if m.hash.Load() != fn {
+		for i, key := range keys {
+			hashes[i] = m.Hash(key)
+		}
+	}
+	for i, key := range keys {
+		if node := m.ownerOf(key, hashes[i]); node != "" {
+			groups[node] = append(groups[node], key)
+		}
+	}
+	m.RUnlock()
+
+	for _, group := range groups {
+		sort.Strings(group)
+	}
+	return groups
+}
+
+// Get the keys that belong to the provided item, in their original order,
+// against a single view of the membership. Returns false if the item is not
+// in the hash.
+func (m *Consistent) KeysOwnedBy(key string, keys []string) ([]string, bool) {
+	return m.KeysOwnedByN(key, keys, 1)
+}
+
+// Get the keys for which the provided item is among the first rf items of
+// NextN, pins included, such as the keys it holds a replica of.
+func (m *Consistent) KeysOwnedByN(key string, keys []string, rf int) ([]string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	if _, ok := m.ring.nodes[key]; !ok {
+		return nil, false
+	}
+	hashes := make([]int, len(keys))
+	for i, k := range keys {
+		hashes[i] = m.Hash(k)
+	}
+
+	var owned []string
+	for i, k := range keys {
+		pinned, ok := m.pinnedHash(k, hashes[i])
+		if ok && (pinned == "" || pinned == key) {
+			if pinned == key {
+				owned = append(owned, k)
+			}
+			continue
+		}
+		n := 0
+		if ok {
+			n++
+		}
+		if n >= rf {
+			continue
+		}
+		m.candidates(hashes[i], func(j int) bool {
+			node := m.ring.nodeAt(j)
+			if node == key {
+				owned = append(owned, k)
+				return false
+			}
+			if node != pinned {
+				n++
+			}
+			return n < rf
+		})
+	}
+	return owned, true
+}
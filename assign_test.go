@@ -0,0 +1,181 @@
+package consistent
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestAssignAll(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 7; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	m.AddStandby("s")
+	m.SetHealthy("n3", false)
+	m.Pin("k7", "n3") // Unhealthy, so Get passes it by
+	m.Pin("k8", "n5")
+	keys := testKeys(10000)
+	keys = append(keys, "k7", "k8", "k8") // Duplicates are kept
+
+	groups := m.AssignAll(keys)
+	total := 0
+	for node, group := range groups {
+		if !slices.IsSorted(group) {
+			t.Fatalf("the keys of %s are not sorted", node)
+		}
+		for _, key := range group {
+			if got := m.Get(key); got != node {
+				t.Fatalf("%s assigned to %s, Get returns %s", key, node, got)
+			}
+		}
+		total += len(group)
+	}
+	if total != len(keys) {
+		t.Fatalf("assigned %d of %d keys", total, len(keys))
+	}
+	if _, ok := groups["s"]; ok {
+		t.Fatal("keys assigned to a standby")
+	}
+	if _, ok := groups["n3"]; ok {
+		t.Fatal("keys assigned to an unhealthy item")
+	}
+
+	if again := m.AssignAll(slices.Clone(keys)); !reflect.DeepEqual(again, groups) {
+		t.Fatal("assignments of the same keys differ")
+	}
+	if parallel := m.AssignAllParallel(keys); !reflect.DeepEqual(parallel, groups) {
+		t.Fatal("the parallel assignment differs")
+	}
+	for _, assign := range []func([]string) map[string][]string{New(nil).AssignAll, New(nil).AssignAllParallel} {
+		if got := assign(keys); len(got) != 0 {
+			t.Fatalf("an empty hash assigned %v", got)
+		}
+		if got := assign(nil); len(got) != 0 {
+			t.Fatalf("no keys assigned %v", got)
+		}
+	}
+}
+
+// An assignment racing changes matches the hash before or after one.
+func TestAssignAllConcurrentChanges(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	keys := testKeys(2000)
+	without := m.AssignAll(keys)
+	m.Add("flap")
+	with := m.AssignAll(keys)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Remove("flap")
+				m.Add("flap")
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		assign := m.AssignAll
+		if i%2 == 1 {
+			assign = m.AssignAllParallel
+		}
+		if got := assign(keys); !reflect.DeepEqual(got, with) && !reflect.DeepEqual(got, without) {
+			t.Errorf("assignment %d mixes two memberships", i)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestKeysOwnedBy(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	m.AddStandby("s")
+	m.Pin("k1", "n1")
+	m.Pin("k2", "n2")
+	keys := testKeys(2000)
+	keys = append(keys, "k1", "k2")
+	groups := m.AssignAll(keys)
+
+	for _, node := range m.Members() {
+		owned, ok := m.KeysOwnedBy(node, keys)
+		if !ok {
+			t.Fatalf("%s is not in the hash", node)
+		}
+		want := slices.Sorted(slices.Values(owned))
+		if !slices.Equal(want, groups[node]) {
+			t.Fatalf("%s owns %d keys, AssignAll gives it %d", node, len(owned), len(groups[node]))
+		}
+
+		for rf := 1; rf <= 3; rf++ {
+			var want []string
+			for _, key := range keys {
+				if slices.Contains(m.NextN(key, rf), node) {
+					want = append(want, key)
+				}
+			}
+			if got, _ := m.KeysOwnedByN(node, keys, rf); !slices.Equal(got, want) {
+				t.Fatalf("%s holds %d keys with rf %d, NextN gives it %d", node, len(got), rf, len(want))
+			}
+		}
+	}
+	if owned, ok := m.KeysOwnedBy("s", keys); !ok || len(owned) != 0 {
+		t.Fatalf("a standby owns %d keys, %v", len(owned), ok)
+	}
+	if _, ok := m.KeysOwnedBy("missing", keys); ok {
+		t.Fatal("an item not in the hash owns keys")
+	}
+	if _, ok := m.KeysOwnedByN("missing", keys, 2); ok {
+		t.Fatal("an item not in the hash holds replicas")
+	}
+}
+
+// A lookup racing changes matches the hash before or after one.
+func TestKeysOwnedByConcurrentChanges(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	keys := testKeys(2000)
+	without, _ := m.KeysOwnedByN("n0", keys, 2)
+	m.Add("flap")
+	with, _ := m.KeysOwnedByN("n0", keys, 2)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				m.Remove("flap")
+				m.Add("flap")
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		if got, _ := m.KeysOwnedByN("n0", keys, 2); !slices.Equal(got, with) && !slices.Equal(got, without) {
+			t.Errorf("lookup %d mixes two memberships", i)
+			break
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
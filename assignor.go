@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Assignor assigns partitions to consumers, as for a consumer group, keeping
+// every consumer within one partition of the others and moving as few
+// partitions as possible when consumers join or leave.
+type Assignor struct {
+	hash Hash
+	opts []Option
+}
+
+// Create an assignor placing new partitions with a hash built by
+// New(fn, opts...). More replicas spread new partitions more evenly.
+func NewAssignor(fn Hash, opts ...Option) *Assignor {
+	return &Assignor{hash: fn, opts: opts}
+}
+
+// Assign partitions to consumers. Every consumer gets len(partitions)/n or
+// one more, where n is the number of distinct consumers. A partition keeps
+// its owner in previous wherever that balance allows; the rest go to the
+// first consumer clockwise of the partition on the ring with room for it.
+// Partitions within each assignment are sorted.
+func (a *Assignor) Assign(partitions []string, consumers []string, previous map[string]string) map[string][]string {
+	m := New(a.hash, a.opts...)
+	for _, c := range consumers {
+		m.Add(c)
+	}
+	assigned := make(map[string][]string, len(m.ring.nodes))
+	for c := range m.ring.nodes {
+		assigned[c] = nil
+	}
+	if len(assigned) == 0 {
+		return assigned
+	}
+
+	parts := append([]string(nil), partitions...)
+	sort.Strings(parts)
+	parts = dedupSorted(parts)
+	base, extra := len(parts)/len(assigned), len(parts)%len(assigned)
+
+	// Keep previous owners up to base partitions each.
+	var pending []string
+	for _, p := range parts {
+		c, ok := previous[p]
+		if _, present := assigned[c]; !ok || !present || len(assigned[c]) >= base {
+			pending = append(pending, p)
+			continue
+		}
+		assigned[c] = append(assigned[c], p)
+	}
+	// Give the remaining previous owners back their partitions up to base+1,
+	// so they serve as the consumers allowed an extra one.
+	var rest []string
+	for _, p := range pending {
+		c, ok := previous[p]
+		if _, present := assigned[c]; ok && present && len(assigned[c]) == base && extra > 0 {
+			assigned[c] = append(assigned[c], p)
+			extra--
+			continue
+		}
+		rest = append(rest, p)
+	}
+
+	for _, p := range rest {
+		var owner string
+		m.ring.candidates(m.Hash(p), func(j int) bool {
+			c := m.ring.nodeAt(j)
+			switch n := len(assigned[c]); {
+			case n < base:
+			case n == base && extra > 0:
+				extra--
+			default:
+				return true
+			}
+			owner = c
+			return false
+		})
+		assigned[owner] = append(assigned[owner], p)
+	}
+
+	for _, ps := range assigned {
+		sort.Strings(ps)
+	}
+	return assigned
+}
+
+func dedupSorted(keys []string) []string {
+	out := keys[:0]
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			out = append(out, key)
+		}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Check an assignment covers every partition once and keeps the consumers
+// within one partition of each other, returning the owner of each.
+func checkAssignment(t *testing.T, got map[string][]string, parts, consumers []string) map[string]string {
+	t.Helper()
+	if len(got) != len(consumers) {
+		t.Fatalf("%d consumers assigned, want %d", len(got), len(consumers))
+	}
+	owner := make(map[string]string, len(parts))
+	lo, hi := len(parts), 0
+	for _, c := range consumers {
+		ps, ok := got[c]
+		if !ok {
+			t.Fatalf("%s missing from %v", c, got)
+		}
+		lo, hi = min(lo, len(ps)), max(hi, len(ps))
+		for _, p := range ps {
+			if o, ok := owner[p]; ok {
+				t.Fatalf("%s assigned to %s and %s", p, o, c)
+			}
+			owner[p] = c
+		}
+	}
+	if hi-lo > 1 {
+		t.Fatalf("assignment sizes from %d to %d", lo, hi)
+	}
+	if len(owner) != len(parts) {
+		t.Fatalf("%d partitions assigned, want %d", len(owner), len(parts))
+	}
+	return owner
+}
+
+// The fewest partitions that can change owner from previous in a balanced
+// assignment of parts to consumers.
+func minMoves(parts, consumers []string, previous map[string]string) int {
+	held := make(map[string]int, len(consumers))
+	for _, c := range consumers {
+		held[c] = 0
+	}
+	for _, p := range parts {
+		if c, ok := previous[p]; ok {
+			if _, present := held[c]; present {
+				held[c]++
+			}
+		}
+	}
+	base, extra := len(parts)/len(consumers), len(parts)%len(consumers)
+	kept, over := 0, 0
+	for _, n := range held {
+		kept += min(n, base)
+		if n > base {
+			over++
+		}
+	}
+	return len(parts) - kept - min(over, extra)
+}
+
+func TestAssignorBalanceAndChurn(t *testing.T) {
+	a := NewAssignor(nil, WithReplicas(50))
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 300; iter++ {
+		parts := make([]string, rng.Intn(200))
+		for i := range parts {
+			parts[i] = fmt.Sprint("p", i)
+		}
+		consumers := make([]string, 1+rng.Intn(16))
+		for i := range consumers {
+			consumers[i] = fmt.Sprint("c", i)
+		}
+
+		prev := checkAssignment(t, a.Assign(parts, consumers, nil), parts, consumers)
+		again := checkAssignment(t, a.Assign(parts, consumers, prev), parts, consumers)
+		for p, c := range prev {
+			if again[p] != c {
+				t.Fatalf("%s moved from %s to %s with no change", p, c, again[p])
+			}
+		}
+
+		// A consumer joins, one leaves, or both, and partitions come and go.
+		next := append([]string(nil), consumers...)
+		if rng.Intn(2) == 0 {
+			next = append(next, "joined")
+		}
+		if len(next) > 1 && rng.Intn(2) == 0 {
+			i := rng.Intn(len(next))
+			next = append(next[:i], next[i+1:]...)
+		}
+		nextParts := parts
+		if rng.Intn(4) == 0 {
+			nextParts = append(parts[rng.Intn(len(parts)+1):], "q1", "q2")
+		}
+
+		got := checkAssignment(t, a.Assign(nextParts, next, prev), nextParts, next)
+		moved := 0
+		for _, p := range nextParts {
+			if got[p] != prev[p] {
+				moved++
+			}
+		}
+		if want := minMoves(nextParts, next, prev); moved != want {
+			t.Fatalf("%d partitions over %d consumers to %d over %d: %d moved, at least %d needed",
+				len(parts), len(consumers), len(nextParts), len(next), moved, want)
+		}
+	}
+}
+
+func TestAssignorEmpty(t *testing.T) {
+	a := NewAssignor(nil)
+	if got := a.Assign([]string{"p0"}, nil, nil); len(got) != 0 {
+		t.Fatal(got)
+	}
+	got := a.Assign(nil, []string{"c0", "c1", "c1"}, nil)
+	if len(got) != 2 || got["c0"] != nil || got["c1"] != nil {
+		t.Fatal(got)
+	}
+	got = a.Assign([]string{"p1", "p0", "p1"}, []string{"c0"}, nil)
+	if len(got["c0"]) != 2 || got["c0"][0] != "p0" || got["c0"][1] != "p1" {
+		t.Fatal(got)
+	}
+}
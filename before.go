@@ -0,0 +1,65 @@
+package consistent
+
+import (
+	"iter"
+)
+
+// Iterate over the points of the ring counter-clockwise from the provided
+// key, yielding each position and its item, starting with the point the key
+// is in the range of and wrapping from the lowest position to the highest.
+// Each point is yielded once, standbys and unhealthy items included. The
+// points are copied when iteration starts, so changes made meanwhile are
+// not seen.
+func (m *Consistent) Before(key string) iter.Seq2[uint32, string] {
+	return func(yield func(uint32, string) bool) {
+		m.before(m.Hash(key), false, yield)
+	}
+}
+
+// Like Before, but starting from a position of the ring.
+func (m *Consistent) BeforePosition(pos uint32) iter.Seq2[uint32, string] {
+	return func(yield func(uint32, string) bool) {
+		m.before(int(pos), false, yield)
+	}
+}
+
+// Like Before, but yielding only the first point of each item, as PrevN
+// does.
+func (m *Consistent) BeforeDistinct(key string) iter.Seq2[uint32, string] {
+	return func(yield func(uint32, string) bool) {
+		m.before(m.Hash(key), true, yield)
+	}
+}
+
+func (m *Consistent) before(hash int, distinct bool, yield func(uint32, string) bool) {
+	m.RLock()
+	if m.ring.size() == 0 {
+		m.RUnlock()
+		return
+	}
+	positions := make([]uint32, 0, m.ring.size())
+	nodes := make([]string, 0, m.ring.size())
+	var seen map[string]bool
+	if distinct {
+		seen = make(map[string]bool)
+	}
+	m.ring.walkBack(m.ring.prevIndex(hash), func(i int) bool {
+		node := m.ring.nodeAt(i)
+		if distinct {
+			if seen[node] {
+				return true
+			}
+			seen[node] = true
+		}
+		positions = append(positions, uint32(m.ring.key(i)))
+		nodes = append(nodes, node)
+		return true
+	})
+	m.RUnlock()
+
+	for i, pos := range positions {
+		if !yield(pos, nodes[i]) {
+			return
+		}
+	}
+}
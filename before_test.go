@@ -0,0 +1,108 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Iteration starts at the point a hash is in the range of, wrapping below
+// the lowest point to the highest, and visits every point once.
+func TestBeforeWraparound(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		replicas int
+		points   map[string]uint32
+		start    int
+		want     []uint32
+		distinct []string
+	}{
+		{"one point, below it", 1, map[string]uint32{"a": 100}, 50, []uint32{100}, []string{"a"}},
+		{"one point, on it", 1, map[string]uint32{"a": 100}, 100, []uint32{100}, []string{"a"}},
+		{"one point, at the top", 1, map[string]uint32{"a": 100}, MaxPosition, []uint32{100}, []string{"a"}},
+		{"two points, below the first", 1, map[string]uint32{"a": 100, "b": MaxPosition}, 0, []uint32{MaxPosition, 100}, []string{"b", "a"}},
+		{"two points, on the first", 1, map[string]uint32{"a": 100, "b": MaxPosition}, 100, []uint32{100, MaxPosition}, []string{"a", "b"}},
+		{"two points, on the last", 1, map[string]uint32{"a": 100, "b": MaxPosition}, MaxPosition, []uint32{MaxPosition, 100}, []string{"b", "a"}},
+		{"two points, between", 1, map[string]uint32{"a": 100, "b": MaxPosition}, 101, []uint32{100, MaxPosition}, []string{"a", "b"}},
+		{"many points, on zero", 2, manyPoints, 0, []uint32{0, MaxPosition, 400, 300, 200, 100}, []string{"c", "b", "a"}},
+		{"many points, above zero", 2, manyPoints, 50, []uint32{0, MaxPosition, 400, 300, 200, 100}, []string{"c", "b", "a"}},
+		{"many points, on one", 2, manyPoints, 200, []uint32{200, 100, 0, MaxPosition, 400, 300}, []string{"b", "a", "c"}},
+		{"many points, below one", 2, manyPoints, 299, []uint32{200, 100, 0, MaxPosition, 400, 300}, []string{"b", "a", "c"}},
+		{"many points, at the top", 2, manyPoints, MaxPosition, []uint32{MaxPosition, 400, 300, 200, 100, 0}, []string{"b", "c", "a"}},
+	} {
+		c := New(fixedHash(tc.points), WithReplicas(tc.replicas))
+		owner := make(map[uint32]string)
+		for point, pos := range tc.points {
+			node, _, _ := strings.Cut(point, "#")
+			c.Add(node)
+			owner[pos] = node
+		}
+		key := fmt.Sprint(tc.start)
+
+		var got []uint32
+		for pos, node := range c.Before(key) {
+			if node != owner[pos] {
+				t.Errorf("%s: %d yielded with %s, owned by %s", tc.name, pos, node, owner[pos])
+			}
+			got = append(got, pos)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: Before %v, want %v", tc.name, got, tc.want)
+		}
+		if first := got[0]; c.Get(key) != owner[first] {
+			t.Errorf("%s: starts at %s, Get %s", tc.name, owner[first], c.Get(key))
+		}
+		got = got[:0]
+		for pos := range c.BeforePosition(uint32(tc.start)) {
+			got = append(got, pos)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: BeforePosition %v, want %v", tc.name, got, tc.want)
+		}
+
+		var distinct []string
+		for pos, node := range c.BeforeDistinct(key) {
+			if node != owner[pos] {
+				t.Errorf("%s: %d yielded with %s, owned by %s", tc.name, pos, node, owner[pos])
+			}
+			distinct = append(distinct, node)
+		}
+		if !slices.Equal(distinct, tc.distinct) || !slices.Equal(distinct, c.PrevN(key, len(tc.distinct))) {
+			t.Errorf("%s: BeforeDistinct %v, PrevN %v, want %v", tc.name, distinct, c.PrevN(key, 3), tc.distinct)
+		}
+	}
+}
+
+// Three items with two points each, one of them at zero.
+var manyPoints = map[string]uint32{"a": 100, "a#1": 300, "b": 200, "b#1": MaxPosition, "c": 0, "c#1": 400}
+
+func TestBeforeCopiesPoints(t *testing.T) {
+	for pos := range New(nil).Before("k") {
+		t.Fatalf("point %d on an empty hash", pos)
+	}
+
+	c := New(nil, WithReplicas(3))
+	c.Add("a")
+	c.Add("b")
+	n := 0
+	for _, node := range c.Before("k") {
+		// Changes while iterating are not seen
+		c.Add(fmt.Sprint("x", n))
+		if node != "a" && node != "b" {
+			t.Fatalf("yielded %s, added while iterating", node)
+		}
+		n++
+	}
+	if n != 6 {
+		t.Fatalf("%d points of 6", n)
+	}
+	n = 0
+	for range c.BeforePosition(0) {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Fatal("iteration went on after break")
+	}
+}
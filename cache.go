@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Memoize lookups for up to size recently used keys. Cached answers are
+// dropped wholesale whenever the membership or health changes.
+func WithLookupCache(size int) Option {
+	return func(m *Consistent) {
+		if size > 0 {
+			m.cache = &lookupCache{
+				size:    size,
+				entries: make(map[string]*cacheEntry, size),
+				clock:   make([]*cacheEntry, 0, size),
+			}
+		}
+	}
+}
+
+// A bounded cache of key to owner, valid for a single epoch. Hits only take
+// the read lock and mark their entry used; eviction sweeps the entries in
+// insertion order like a clock, sparing each used entry once, so recently
+// used keys stay without reordering a list on every hit.
+type lookupCache struct {
+	sync.RWMutex
+	size    int
+	epoch   uint64
+	entries map[string]*cacheEntry
+	clock   []*cacheEntry
+	hand    int // The next entry to consider for eviction
+}
+
+type cacheEntry struct {
+	key  string
+	node string
+	used atomic.Bool
+}
+
+func (c *lookupCache) get(key string, epoch uint64) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	if epoch != c.epoch {
+		return "", false
+	}
+	e, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !e.used.Load() {
+		e.used.Store(true)
+	}
+	return e.node, true
+}
+
+func (c *lookupCache) put(key, node string, epoch uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if epoch < c.epoch {
+		return
+	}
+	c.sync(epoch)
+	if e, ok := c.entries[key]; ok {
+		e.node = node
+		return
+	}
+
+	e := &cacheEntry{key: key, node: node}
+	c.entries[key] = e
+	if len(c.clock) < c.size {
+		c.clock = append(c.clock, e)
+		return
+	}
+	for {
+		old := c.clock[c.hand]
+		if old.used.Load() {
+			old.used.Store(false)
+			c.hand = (c.hand + 1) % c.size
+			continue
+		}
+		delete(c.entries, old.key)
+		c.clock[c.hand] = e
+		c.hand = (c.hand + 1) % c.size
+		return
+	}
+}
+
+// Drop everything cached for an older epoch.
+func (c *lookupCache) sync(epoch uint64) {
+	if epoch == c.epoch {
+		return
+	}
+	c.epoch = epoch
+	clear(c.entries)
+	clear(c.clock)
+	c.clock, c.hand = c.clock[:0], 0
+}
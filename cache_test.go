@@ -0,0 +1,85 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Lookups race with membership churn; after every change the cached hash
+// must answer like one without a cache.
+func TestLookupCacheChurn(t *testing.T) {
+	c := New(nil, WithLookupCache(100), WithReplicas(10))
+	ref := New(nil, WithReplicas(10))
+	for i := 0; i < 5; i++ {
+		c.Add(fmt.Sprint("n", i))
+		ref.Add(fmt.Sprint("n", i))
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					c.Get(fmt.Sprint("k", rnd.Intn(200)))
+				}
+			}
+		}(int64(g))
+	}
+
+	rnd := rand.New(rand.NewSource(42))
+	for i := 0; i < 300; i++ {
+		node, weight := fmt.Sprint("n", rnd.Intn(7)), 1+rnd.Intn(20)
+		for _, m := range []*Consistent{c, ref} {
+			switch i % 4 {
+			case 0:
+				m.Add(node)
+			case 1:
+				m.Remove(node)
+			case 2:
+				m.SetWeight(node, weight)
+			case 3:
+				m.SetHealthy(node, i%8 != 3)
+			}
+		}
+		for k := 0; k < 200; k++ {
+			key := fmt.Sprint("k", k)
+			if got, want := c.Get(key), ref.Get(key); got != want {
+				close(stop)
+				t.Fatalf("change %d: %s cached as %q, owned by %q", i, key, got, want)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func BenchmarkLookupCacheZipf(b *testing.B) {
+	for _, size := range []int{0, 10000} {
+		b.Run(fmt.Sprint("size=", size), func(b *testing.B) {
+			c := New(nil, WithLookupCache(size))
+			for i := 0; i < 500; i++ {
+				c.AddWithWeight(fmt.Sprint("n", i), 100)
+			}
+			// A few thousand hot keys take most lookups
+			z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.1, 1, 1000000)
+			keys := make([]string, 1<<16)
+			for i := range keys {
+				keys[i] = fmt.Sprint("key", z.Uint64())
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.Get(keys[i%len(keys)])
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package consistent
+
+import (
+	"errors"
+)
+
+var ErrGenerationMismatch = errors.New("consistent: generation has moved")
+
+// Apply a batch of changes like Update, only if the hash is still at the
+// expected generation. Otherwise nothing changes and the error wraps
+// ErrGenerationMismatch, so the caller can read the membership again and
+// retry. The generation moves with every change that is made and never
+// with a refused one.
+func (m *Consistent) UpdateIf(expected uint64, fn func(tx *Tx) error) error {
+	return m.update(&expected, fn)
+}
+
+// Add a key to the hash only if it is still at the expected generation, see
+// UpdateIf.
+func (m *Consistent) AddIf(expected uint64, key string) error {
+	return m.UpdateIf(expected, func(tx *Tx) error {
+		tx.Add(key)
+		return nil
+	})
+}
+
+// Remove a key from the hash only if it is still at the expected
+// generation, see UpdateIf.
+func (m *Consistent) RemoveIf(expected uint64, key string) error {
+	return m.UpdateIf(expected, func(tx *Tx) error {
+		tx.Remove(key)
+		return nil
+	})
+}
@@ -0,0 +1,86 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestUpdateIf(t *testing.T) {
+	m := New(nil)
+	gen := m.Generation()
+	if err := m.AddIf(gen, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddIf(gen, "b"); !errors.Is(err, ErrGenerationMismatch) {
+		t.Fatalf("stale AddIf: %v", err)
+	}
+	if m.Generation() != gen+1 || len(m.Members()) != 1 {
+		t.Fatalf("generation %d, members %q", m.Generation(), m.Members())
+	}
+	if err := m.RemoveIf(gen, "a"); !errors.Is(err, ErrGenerationMismatch) {
+		t.Fatalf("stale RemoveIf: %v", err)
+	}
+	if err := m.RemoveIf(gen+1, "a"); err != nil || len(m.Members()) != 0 {
+		t.Fatalf("RemoveIf: %v, members %q", err, m.Members())
+	}
+
+	// A refused or empty update leaves the generation
+	gen = m.Generation()
+	m.UpdateIf(gen, func(tx *Tx) error { return errors.New("no") })
+	m.UpdateIf(gen, func(tx *Tx) error { return nil })
+	if m.Generation() != gen {
+		t.Fatalf("generation moved to %d without a change", m.Generation())
+	}
+}
+
+// Two writers each read the hash, compute a change from what they read and
+// apply it only if the hash has not moved, retrying otherwise. No change is
+// lost: the names derived from the member count never collide, and a
+// counter kept in a weight counts every increment.
+func TestUpdateIfConcurrentLoops(t *testing.T) {
+	const each = 200
+	m := New(nil)
+	m.AddWithWeight("counter", 0)
+
+	var wg sync.WaitGroup
+	retries := make([]int, 2)
+	for w := range retries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				for {
+					gen := m.Generation()
+					n := len(m.Members())
+					count, _ := m.Weight("counter")
+					err := m.UpdateIf(gen, func(tx *Tx) error {
+						tx.Add(fmt.Sprint("k", n))
+						return tx.SetWeight("counter", count+1)
+					})
+					if err == nil {
+						break
+					}
+					if !errors.Is(err, ErrGenerationMismatch) {
+						t.Error(err)
+						return
+					}
+					retries[w]++
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(m.Members()); n != 2*each+1 {
+		t.Fatalf("%d members, want %d", n, 2*each+1)
+	}
+	if count, _ := m.Weight("counter"); count != 2*each {
+		t.Fatalf("counter at %d, want %d", count, 2*each)
+	}
+	if m.Generation() != 2*each+1 {
+		t.Fatalf("generation %d, want %d", m.Generation(), 2*each+1)
+	}
+	t.Logf("retries %v", retries)
+}
@@ -0,0 +1,132 @@
+package consistent
+
+import (
+	"time"
+)
+
+// Keep the last capacity membership changes with the time they were made,
+// for RecentChanges and Churn. The log is a ring buffer: once full, each
+// change evicts the oldest.
+func WithChangeLog(capacity int) Option {
+	return func(m *Consistent) {
+		if capacity > 0 {
+			m.changeLog = &changeLog{entries: make([]LogEntry, capacity)}
+		}
+	}
+}
+
+// LogEntry is a change in the change log.
+type LogEntry struct {
+	Change
+	Time       time.Time `json:"time"`
+	Generation uint64    `json:"generation"` // The generation the change produced
+}
+
+// ChurnWindow counts the changes in a window of time.
+type ChurnWindow struct {
+	Adds    int `json:"adds"`
+	Removes int `json:"removes"`
+	Other   int `json:"other"` // Weight changes, renames, promotions and pins
+}
+
+// Churn counts the changes in the change log over the last 1, 5 and 15
+// minutes.
+type Churn struct {
+	Last1m  ChurnWindow `json:"last_1m"`
+	Last5m  ChurnWindow `json:"last_5m"`
+	Last15m ChurnWindow `json:"last_15m"`
+}
+
+// A ring buffer of changes, guarded by the hash's lock.
+type changeLog struct {
+	entries []LogEntry
+	next    int // Where the next change goes
+	full    bool
+}
+
+func (l *changeLog) record(now time.Time, generation uint64, changes []Change) {
+	for _, c := range changes {
+		l.entries[l.next] = LogEntry{Change: c, Time: now, Generation: generation}
+		l.next++
+		if l.next == len(l.entries) {
+			l.next, l.full = 0, true
+		}
+	}
+}
+
+// Call fn for each entry from the newest back, until it returns false.
+func (l *changeLog) walkBack(fn func(e LogEntry) bool) {
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	for i := 1; i <= n; i++ {
+		if !fn(l.entries[(l.next-i+len(l.entries))%len(l.entries)]) {
+			return
+		}
+	}
+}
+
+// Get the changes in the change log made after since, oldest first. Returns
+// nil without WithChangeLog. Changes already evicted from the log are not
+// reported.
+func (m *Consistent) RecentChanges(since time.Time) []LogEntry {
+	m.RLock()
+	defer m.RUnlock()
+	if m.changeLog == nil {
+		return nil
+	}
+
+	var recent []LogEntry
+	m.changeLog.walkBack(func(e LogEntry) bool {
+		if !e.Time.After(since) {
+			return false
+		}
+		recent = append(recent, e)
+		return true
+	})
+	for i, j := 0, len(recent)-1; i < j; i, j = i+1, j-1 {
+		recent[i], recent[j] = recent[j], recent[i]
+	}
+	return recent
+}
+
+// Count the changes in the change log over the last 1, 5 and 15 minutes,
+// for metrics. A log too small for the rate of change undercounts the
+// longer windows.
+func (m *Consistent) Churn() Churn {
+	m.RLock()
+	defer m.RUnlock()
+	var churn Churn
+	if m.changeLog == nil {
+		return churn
+	}
+
+	now := m.clock.Now()
+	m.changeLog.walkBack(func(e LogEntry) bool {
+		age := now.Sub(e.Time)
+		if age > 15*time.Minute {
+			return false
+		}
+		if age <= time.Minute {
+			churn.Last1m.count(e.Type)
+		}
+		if age <= 5*time.Minute {
+			churn.Last5m.count(e.Type)
+		}
+		churn.Last15m.count(e.Type)
+		return true
+	})
+	return churn
+}
+
+func (w *ChurnWindow) count(t ChangeType) {
+	switch t {
+	case ChangeAdd:
+		w.Adds++
+	case ChangeRemove:
+		w.Removes++
+	default:
+		w.Other++
+	}
+}
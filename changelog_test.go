@@ -0,0 +1,86 @@
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeLog(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithChangeLog(5), WithClock(clock))
+	start := clock.Now()
+	m.Add("a") // At 0
+	clock.advance(10 * time.Minute)
+	m.Add("b")               // At 10m
+	m.SetHealthy("b", false) // Not membership, not logged
+	clock.advance(4 * time.Minute)
+	m.AddWithWeight("a", 3) // At 14m
+	clock.advance(30 * time.Second)
+	m.Remove("a") // At 14m30s
+	clock.advance(30 * time.Second)
+
+	got := m.RecentChanges(start)
+	if len(got) != 3 || got[0].Key != "b" || got[1].Type != ChangeWeight || got[2].Type != ChangeRemove {
+		t.Fatalf("changes after the start %+v", got)
+	}
+	if got[2].Generation != m.Generation() || !got[2].Time.Equal(start.Add(14*time.Minute+30*time.Second)) {
+		t.Fatalf("last change at generation %d, time %v", got[2].Generation, got[2].Time)
+	}
+	if all := m.RecentChanges(time.Time{}); len(all) != 4 {
+		t.Fatalf("%d changes in all, want 4", len(all))
+	}
+
+	want := Churn{
+		Last1m:  ChurnWindow{Removes: 1, Other: 1},
+		Last5m:  ChurnWindow{Adds: 1, Removes: 1, Other: 1},
+		Last15m: ChurnWindow{Adds: 2, Removes: 1, Other: 1},
+	}
+	if got := m.Churn(); got != want {
+		t.Fatalf("churn %+v, want %+v", got, want)
+	}
+	// The windows slide with the clock
+	clock.advance(5 * time.Minute)
+	want = Churn{Last15m: ChurnWindow{Adds: 1, Removes: 1, Other: 1}}
+	if got := m.Churn(); got != want {
+		t.Fatalf("churn five minutes on %+v, want %+v", got, want)
+	}
+	clock.advance(15 * time.Minute)
+	if got := m.Churn(); got != (Churn{}) {
+		t.Fatalf("churn a quarter hour on %+v", got)
+	}
+}
+
+// Once full, the log drops the oldest changes; a transaction's changes
+// share a time and generation.
+func TestChangeLogWraps(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithChangeLog(5), WithClock(clock))
+	m.Add("a")
+	clock.advance(time.Second)
+	m.AddWithWeight("a", 3)
+	clock.advance(time.Second)
+	m.Update(func(tx *Tx) error {
+		tx.Add("x")
+		tx.Add("y")
+		tx.Add("z")
+		tx.Remove("a")
+		return nil
+	})
+
+	all := m.RecentChanges(time.Time{})
+	if len(all) != 5 || all[0].Key != "a" || all[0].Type != ChangeWeight || all[4].Type != ChangeRemove {
+		t.Fatalf("log %+v", all)
+	}
+	for _, e := range all[1:] {
+		if e.Generation != m.Generation() || !e.Time.Equal(clock.Now()) {
+			t.Fatalf("transaction change at generation %d, time %v", e.Generation, e.Time)
+		}
+	}
+	if c := m.Churn(); c.Last1m != (ChurnWindow{Adds: 3, Removes: 1, Other: 1}) {
+		t.Fatalf("churn %+v", c)
+	}
+
+	if New(nil).RecentChanges(time.Time{}) != nil || New(nil).Churn() != (Churn{}) {
+		t.Fatal("a hash without a change log reported changes")
+	}
+}
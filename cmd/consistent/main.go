@@ -0,0 +1,333 @@
+// Command consistent inspects and queries ring files written by Save.
+//
+// Usage:
+//
+//	consistent lookup -ring ring.json -key X [-n 3]
+//	consistent stats -ring ring.json
+//	consistent diff -a old.json -b new.json [-keys keys.txt]
+//	consistent members -ring ring.json
+//	consistent visualize -ring ring.json [-width 64] [-color]
+//
+// Every subcommand takes -json for machine-readable output. The replicas,
+// hash function and domain separation are read from the ring file; -replicas,
+// -hash and -seed override them, such as for files whose settings were not
+// recorded.
+//
+// The exit status is 0 on success, 1 when diff finds differences and 2 on
+// errors, like diff(1).
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+const (
+	exitOK      = 0
+	exitDiffers = 1
+	exitError   = 2
+)
+
+// Returned by diff when the rings differ, to exit with exitDiffers.
+var errDiffers = errors.New("rings differ")
+
+type command struct {
+	name string
+	run  func(args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{"lookup", lookup},
+	{"stats", stats},
+	{"diff", diff},
+	{"members", members},
+	{"visualize", visualize},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, out, errOut io.Writer) int {
+	if len(args) == 0 {
+		usage(errOut)
+		return exitError
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:], out)
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, errDiffers):
+			return exitDiffers
+		case errors.Is(err, flag.ErrHelp):
+			return exitError
+		default:
+			fmt.Fprintf(errOut, "consistent %s: %v\n", c.name, err)
+			return exitError
+		}
+	}
+	usage(errOut)
+	return exitError
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: consistent <lookup|stats|diff|members|visualize> [flags]")
+}
+
+// Flags shared by every subcommand.
+type common struct {
+	json     bool
+	replicas int
+	hash     string
+	seed     uint64
+}
+
+func newFlags(name string) (*flag.FlagSet, *common) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	c := &common{}
+	fs.BoolVar(&c.json, "json", false, "write JSON output")
+	fs.IntVar(&c.replicas, "replicas", 0, "replicas the ring was saved with, if not those in the file")
+	fs.StringVar(&c.hash, "hash", "", fmt.Sprintf("hash function the ring was saved with, one of %v, if not the one in the file", consistent.HashNames()))
+	fs.Uint64Var(&c.seed, "seed", 0, "seed of the -hash function")
+	return fs, c
+}
+
+func (c *common) load(path string) (*consistent.Consistent, error) {
+	if path == "" {
+		return nil, errors.New("missing ring file")
+	}
+	var opts []consistent.Option
+	if c.replicas > 0 {
+		opts = append(opts, consistent.WithReplicas(c.replicas))
+	}
+	if c.hash != "" {
+		if _, err := consistent.NamedHash(c.hash, c.seed); err != nil {
+			return nil, err
+		}
+		opts = append(opts, consistent.WithNamedHash(c.hash, c.seed))
+	}
+	return consistent.Load(path, opts...)
+}
+
+func (c *common) write(out io.Writer, v any, text func(w *tabwriter.Writer)) error {
+	if c.json {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+func lookup(args []string, out io.Writer) error {
+	fs, c := newFlags("lookup")
+	path := fs.String("ring", "", "ring file")
+	key := fs.String("key", "", "key to look up")
+	n := fs.Int("n", 1, "number of distinct owners")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+
+	m, err := c.load(*path)
+	if err != nil {
+		return err
+	}
+	if m.IsEmpty() {
+		return consistent.ErrEmpty
+	}
+
+	owners := m.NextN(*key, *n)
+	result := struct {
+		Key    string   `json:"key"`
+		Hash   int      `json:"hash"`
+		Owners []string `json:"owners"`
+	}{*key, m.Hash(*key), owners}
+
+	return c.write(out, result, func(w *tabwriter.Writer) {
+		for _, owner := range owners {
+			fmt.Fprintln(w, owner)
+		}
+	})
+}
+
+func stats(args []string, out io.Writer) error {
+	fs, c := newFlags("stats")
+	path := fs.String("ring", "", "ring file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := c.load(*path)
+	if err != nil {
+		return err
+	}
+
+	s := m.Stats()
+	return c.write(out, s, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "members\t%d\n", s.Members)
+		fmt.Fprintf(w, "points\t%d\n", s.Points)
+		fmt.Fprintf(w, "min share\t%.4f\n", s.Min)
+		fmt.Fprintf(w, "max share\t%.4f\n", s.Max)
+		fmt.Fprintf(w, "mean share\t%.4f\n", s.Mean)
+		fmt.Fprintf(w, "stddev\t%.4f\n", s.StdDev)
+		fmt.Fprintf(w, "imbalance\t%.4f\n", s.Imbalance)
+	})
+}
+
+func diff(args []string, out io.Writer) error {
+	fs, c := newFlags("diff")
+	pathA := fs.String("a", "", "old ring file")
+	pathB := fs.String("b", "", "new ring file")
+	keysPath := fs.String("keys", "", "file of keys, one per line, to report moves for")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := c.load(*pathA)
+	if err != nil {
+		return err
+	}
+	b, err := c.load(*pathB)
+	if err != nil {
+		return err
+	}
+
+	transfers := consistent.Diff(a, b)
+
+	type move struct {
+		Key  string `json:"key"`
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	var moves []move
+	if *keysPath != "" {
+		keys, err := readKeys(*keysPath)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if from, to := a.Get(key), b.Get(key); from != to {
+				moves = append(moves, move{key, from, to})
+			}
+		}
+	}
+
+	var moved float64
+	for _, t := range transfers {
+		moved += float64(uint64((t.Range.To-t.Range.From)&consistent.MaxPosition)+1) / (consistent.MaxPosition + 1)
+	}
+
+	result := struct {
+		Moved     float64               `json:"moved"` // The fraction of the hash space changing owner
+		Transfers []consistent.Transfer `json:"transfers"`
+		Keys      []move                `json:"keys,omitempty"`
+	}{moved, transfers, moves}
+
+	err = c.write(out, result, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "moved\t%.4f\n", moved)
+		if *keysPath != "" {
+			for _, m := range moves {
+				fmt.Fprintf(w, "%s\t%s\t->\t%s\n", m.Key, m.From, m.To)
+			}
+			return
+		}
+		for _, t := range transfers {
+			fmt.Fprintf(w, "%d-%d\t%s\t->\t%s\n", t.Range.From, t.Range.To, t.From, t.To)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(transfers) > 0 {
+		return errDiffers
+	}
+	return nil
+}
+
+func members(args []string, out io.Writer) error {
+	fs, c := newFlags("members")
+	path := fs.String("ring", "", "ring file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := c.load(*path)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		Name    string  `json:"name"`
+		Weight  int     `json:"weight"`
+		Standby bool    `json:"standby"`
+		Share   float64 `json:"share"`
+	}
+	shares := m.Stats().Shares
+	var entries []entry
+	for _, key := range m.Members() {
+		weight, _ := m.Weight(key)
+		entries = append(entries, entry{key, weight, m.IsStandby(key), shares[key]})
+	}
+
+	return c.write(out, entries, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "NAME\tWEIGHT\tSTANDBY\tSHARE")
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%d\t%t\t%.4f\n", e.Name, e.Weight, e.Standby, e.Share)
+		}
+	})
+}
+
+func visualize(args []string, out io.Writer) error {
+	fs, c := newFlags("visualize")
+	path := fs.String("ring", "", "ring file")
+	width := fs.Int("width", 64, "width of the bar in cells")
+	color := fs.Bool("color", false, "use ANSI colors even when not writing to a terminal")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := c.load(*path)
+	if err != nil {
+		return err
+	}
+
+	var opts []consistent.VisualizeOption
+	if *color {
+		opts = append(opts, consistent.VisualizeColor(true))
+	}
+	return m.Visualize(out, *width, opts...)
+}
+
+func readKeys(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := s.Text(); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys, s.Err()
+}
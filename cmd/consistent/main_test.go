@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// Save a ring with settings other than the defaults.
+func saveRing(t *testing.T, name string, nodes int) (string, *consistent.Consistent) {
+	t.Helper()
+	c := consistent.New(nil, consistent.WithReplicas(20), consistent.WithNamedHash("crc32c", 9), consistent.WithDomainSeparation())
+	for i := 0; i < nodes; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	c.AddStandby("standby")
+	path := filepath.Join(t.TempDir(), name)
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	return path, c
+}
+
+func runCLI(args ...string) (int, string, string) {
+	var out, errOut bytes.Buffer
+	code := run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestLookupReadsSettingsFromFile(t *testing.T) {
+	path, c := saveRing(t, "ring.json", 5)
+	for _, key := range []string{"a", "user:42", "x"} {
+		code, out, errOut := runCLI("lookup", "-ring", path, "-key", key, "-n", "3", "-json")
+		if code != exitOK {
+			t.Fatalf("%s: exit %d: %s", key, code, errOut)
+		}
+		var result struct {
+			Hash   int      `json:"hash"`
+			Owners []string `json:"owners"`
+		}
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatal(err)
+		}
+		if want := c.NextN(key, 3); fmt.Sprint(result.Owners) != fmt.Sprint(want) || result.Hash != c.Hash(key) {
+			t.Fatalf("%s: %+v, want %v at %d", key, result, want, c.Hash(key))
+		}
+		if code, out, _ := runCLI("lookup", "-ring", path, "-key", key); code != exitOK || out != c.Get(key)+"\n" {
+			t.Fatalf("%s: text lookup %q, exit %d", key, out, code)
+		}
+	}
+
+	// Overrides that disagree with the file
+	for _, args := range [][]string{{"-replicas", "3"}, {"-hash", "fnv1a32"}, {"-hash", "crc32c"}} {
+		code, _, errOut := runCLI(append([]string{"lookup", "-ring", path, "-key", "a"}, args...)...)
+		if code != exitError || !strings.Contains(errOut, "does not match") {
+			t.Fatalf("%v: exit %d: %s", args, code, errOut)
+		}
+	}
+	if code, _, errOut := runCLI("lookup", "-ring", path, "-key", "a", "-hash", "crc32c", "-seed", "9", "-replicas", "20"); code != exitOK {
+		t.Fatalf("matching overrides: exit %d: %s", code, errOut)
+	}
+	if code, _, errOut := runCLI("lookup", "-ring", path, "-key", "a", "-hash", "md5"); code != exitError || !strings.Contains(errOut, "unknown hash") {
+		t.Fatalf("unknown hash: exit %d: %s", code, errOut)
+	}
+}
+
+func TestStatsAndMembers(t *testing.T) {
+	path, c := saveRing(t, "ring.json", 4)
+	code, out, errOut := runCLI("stats", "-ring", path, "-json")
+	if code != exitOK {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	var s consistent.Stats
+	if err := json.Unmarshal([]byte(out), &s); err != nil || s.Members != c.Stats().Members || s.Points != c.Stats().Points {
+		t.Fatalf("stats %+v, %v", s, err)
+	}
+	if code, out, _ := runCLI("stats", "-ring", path); code != exitOK || !strings.Contains(out, "imbalance") {
+		t.Fatalf("text stats exit %d: %s", code, out)
+	}
+
+	code, out, _ = runCLI("members", "-ring", path, "-json")
+	var entries []struct {
+		Name    string  `json:"name"`
+		Standby bool    `json:"standby"`
+		Share   float64 `json:"share"`
+	}
+	if err := json.Unmarshal([]byte(out), &entries); err != nil || code != exitOK || len(entries) != 5 {
+		t.Fatalf("members %v, exit %d, %v", entries, code, err)
+	}
+	for _, e := range entries {
+		if e.Standby != (e.Name == "standby") || e.Standby && e.Share != 0 {
+			t.Fatalf("member %+v", e)
+		}
+	}
+}
+
+// The subcommand draws what Visualize draws for the saved ring.
+func TestVisualize(t *testing.T) {
+	path, c := saveRing(t, "ring.json", 5)
+	for _, tc := range []struct {
+		args  []string
+		width int
+		opts  []consistent.VisualizeOption
+	}{
+		{nil, 64, nil},
+		{[]string{"-width", "32"}, 32, nil},
+		{[]string{"-width", "16", "-color"}, 16, []consistent.VisualizeOption{consistent.VisualizeColor(true)}},
+	} {
+		var want bytes.Buffer
+		if err := c.Visualize(&want, tc.width, tc.opts...); err != nil {
+			t.Fatal(err)
+		}
+		code, out, errOut := runCLI(append([]string{"visualize", "-ring", path}, tc.args...)...)
+		if code != exitOK || out != want.String() {
+			t.Errorf("%v: exit %d: %s\ngot:\n%s\nwant:\n%s", tc.args, code, errOut, out, want.String())
+		}
+	}
+	if code, out, _ := runCLI("visualize", "-ring", path, "-color"); !strings.Contains(out, "\x1b[") || code != exitOK {
+		t.Errorf("-color: exit %d without ANSI colors", code)
+	}
+}
+
+func TestDiffExitStatus(t *testing.T) {
+	a, ring := saveRing(t, "a.json", 5)
+	if code, out, _ := runCLI("diff", "-a", a, "-b", a); code != exitOK || !strings.HasPrefix(out, "moved") {
+		t.Fatalf("same ring: exit %d: %s", code, out)
+	}
+
+	before := make(map[string]string)
+	var keys []string
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprint("key", i)
+		keys = append(keys, key)
+		before[key] = ring.Get(key)
+	}
+	ring.Remove("n2")
+	b := filepath.Join(t.TempDir(), "b.json")
+	if err := ring.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	keysPath := filepath.Join(t.TempDir(), "keys.txt")
+	os.WriteFile(keysPath, []byte(strings.Join(keys, "\n")+"\n"), 0o644)
+
+	code, out, _ := runCLI("diff", "-a", a, "-b", b, "-keys", keysPath, "-json")
+	if code != exitDiffers {
+		t.Fatalf("exit %d, want %d", code, exitDiffers)
+	}
+	var result struct {
+		Moved float64 `json:"moved"`
+		Keys  []struct {
+			Key, From, To string
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for _, key := range keys {
+		if before[key] != ring.Get(key) {
+			moved++
+		}
+	}
+	if len(result.Keys) != moved || result.Moved <= 0 || result.Moved >= 1 {
+		t.Fatalf("%d keys moved, want %d; moved %v", len(result.Keys), moved, result.Moved)
+	}
+	for _, m := range result.Keys {
+		if m.From != "n2" || m.To != ring.Get(m.Key) {
+			t.Fatalf("move %+v", m)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	path, _ := saveRing(t, "ring.json", 1)
+	for _, args := range [][]string{
+		nil,
+		{"nope"},
+		{"lookup", "-key", "a"},
+		{"lookup", "-ring", path, "-n", "0"},
+		{"stats", "-ring", filepath.Join(t.TempDir(), "missing.json")},
+		{"members", "-bogus"},
+	} {
+		if code, _, errOut := runCLI(args...); code != exitError || errOut == "" {
+			t.Fatalf("%v: exit %d: %q", args, code, errOut)
+		}
+	}
+
+	empty := consistent.New(nil)
+	emptyPath := filepath.Join(t.TempDir(), "empty.json")
+	empty.Save(emptyPath)
+	if code, _, errOut := runCLI("lookup", "-ring", emptyPath, "-key", "a"); code != exitError || !strings.Contains(errOut, "no items") {
+		t.Fatalf("empty ring: exit %d: %s", code, errOut)
+	}
+}
@@ -0,0 +1,120 @@
+package consistent
+
+import (
+	"fmt"
+)
+
+// Config describes a hash declaratively, so services can build identical
+// hashes from a shared file.
+type Config struct {
+	Hash     string         `json:"hash,omitempty" yaml:"hash,omitempty"` // A name from HashNames, crc32 if empty
+	Seed     uint64         `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Replicas int            `json:"replicas,omitempty" yaml:"replicas,omitempty"` // Points per member without a weight, 1 if zero
+	Members  []MemberConfig `json:"members" yaml:"members"`
+
+	DomainSeparation bool `json:"domain_separation,omitempty" yaml:"domain_separation,omitempty"` // See WithDomainSeparation
+}
+
+// MemberConfig describes one member of a Config.
+type MemberConfig struct {
+	Name    string `json:"name" yaml:"name"`
+	Weight  int    `json:"weight,omitempty" yaml:"weight,omitempty"` // Replicas if zero
+	Zone    string `json:"zone,omitempty" yaml:"zone,omitempty"`
+	Standby bool   `json:"standby,omitempty" yaml:"standby,omitempty"`
+	Tier    int    `json:"tier,omitempty" yaml:"tier,omitempty"` // See AddTiered
+}
+
+// Build a hash from a configuration. Members are added in order, so the
+// same configuration always gives the same fingerprint. Options are applied
+// after the configuration.
+func NewFromConfig(cfg Config, opts ...Option) (*Consistent, error) {
+	name := cfg.Hash
+	if name == "" {
+		name = "crc32"
+	}
+	if _, err := NamedHash(name, cfg.Seed); err != nil {
+		return nil, err
+	}
+	if cfg.Replicas < 0 {
+		return nil, fmt.Errorf("consistent: replicas must not be negative, got %d", cfg.Replicas)
+	}
+
+	if err := validateMembers(cfg.Members); err != nil {
+		return nil, err
+	}
+
+	// Before opts, so a WithHash there is the one Config reports
+	base := []Option{WithNamedHash(name, cfg.Seed), WithReplicas(cfg.Replicas)}
+	if cfg.DomainSeparation {
+		base = append(base, WithDomainSeparation())
+	}
+	m := New(nil, append(base, opts...)...)
+	for _, mc := range cfg.Members {
+		weight := mc.Weight
+		if weight == 0 {
+			weight = m.replicas
+		}
+		m.ring.add(mc.Name, weight)
+		mem := m.ring.nodes[mc.Name]
+		mem.zone, mem.standby, mem.explicit = mc.Zone, mc.Standby, mc.Weight != 0
+		m.ring.setTier(mem, mc.Tier)
+	}
+	m.rebuildTable()
+
+	return m, nil
+}
+
+// Check that every member has a name and a weight that is not negative, and
+// that no name is listed twice.
+func validateMembers(members []MemberConfig) error {
+	seen := make(map[string]bool, len(members))
+	for _, mc := range members {
+		if mc.Name == "" {
+			return fmt.Errorf("consistent: member without a name")
+		}
+		if seen[mc.Name] {
+			return fmt.Errorf("%w: %q is listed twice", ErrNodeExists, mc.Name)
+		}
+		seen[mc.Name] = true
+		if mc.Weight < 0 {
+			return fmt.Errorf("%w: member %q has weight %d", ErrInvalidWeight, mc.Name, mc.Weight)
+		}
+	}
+	return nil
+}
+
+// Get the configuration of the hash, with members sorted by name. A hash
+// function not chosen by name is reported as "custom", which NewFromConfig
+// rejects. A member whose weight follows the replicas is reported with
+// weight 0, as is a member without points, which NewFromConfig reads as the
+// default.
+func (m *Consistent) Config() Config {
+	m.RLock()
+	defer m.RUnlock()
+
+	cfg := Config{
+		Hash:     m.hashName,
+		Seed:     m.seed,
+		Replicas: m.replicas,
+		Members:  make([]MemberConfig, 0, len(m.ring.nodes)),
+
+		DomainSeparation: m.domains,
+	}
+	if cfg.Hash == "" {
+		cfg.Hash = "custom"
+	}
+	for _, key := range sortedKeys(m.ring.nodes) {
+		mem := m.ring.nodes[key]
+		mc := MemberConfig{
+			Name:    key,
+			Zone:    mem.zone,
+			Standby: mem.standby,
+			Tier:    mem.tier,
+		}
+		if mem.explicit {
+			mc.Weight = mem.weight
+		}
+		cfg.Members = append(cfg.Members, mc)
+	}
+	return cfg
+}
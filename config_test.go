@@ -0,0 +1,96 @@
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	cfg := Config{Hash: "fnv1a32", Seed: 42, Replicas: 10, Members: []MemberConfig{
+		{Name: "a", Zone: "z1"},
+		{Name: "b", Weight: 20, Zone: "z2"},
+		{Name: "c", Standby: true, Weight: 10},
+		{Name: "d", Tier: 1},
+	}}
+	m1, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m1.Fingerprint() != m2.Fingerprint() {
+		t.Fatal("the same configuration gave different fingerprints")
+	}
+	if tier, _ := m1.Tier("d"); tier != 1 {
+		t.Fatalf("d is in tier %d, want 1", tier)
+	}
+
+	out := m1.Config()
+	if !reflect.DeepEqual(out.Members, cfg.Members) || out.Hash != cfg.Hash || out.Seed != cfg.Seed {
+		t.Fatalf("Config() = %+v, want %+v", out, cfg)
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Config
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	m3, err := NewFromConfig(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m3.Fingerprint() != m1.Fingerprint() || !reflect.DeepEqual(m3.Config(), out) {
+		t.Fatal("the reported configuration did not rebuild the hash")
+	}
+	for _, key := range testKeys(200) {
+		if m3.Get(key) != m1.Get(key) {
+			t.Fatalf("%s: rebuilt hash routes to %s, want %s", key, m3.Get(key), m1.Get(key))
+		}
+	}
+}
+
+func TestConfigReportsHashOption(t *testing.T) {
+	cfg := Config{Hash: "fnv1a32", Members: []MemberConfig{{Name: "a"}}}
+	m, err := NewFromConfig(cfg, WithHash(fnv32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Config().Hash; got != "custom" {
+		t.Fatalf("hash overridden by WithHash reported as %q", got)
+	}
+	m, err = NewFromConfig(cfg, WithNamedHash("crc32", 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Config(); got.Hash != "crc32" || got.Seed != 7 {
+		t.Fatalf("hash overridden by WithNamedHash reported as %q seed %d", got.Hash, got.Seed)
+	}
+}
+
+func TestConfigRejects(t *testing.T) {
+	for _, tc := range []struct {
+		cfg  Config
+		want error
+	}{
+		{Config{Members: []MemberConfig{{Name: "a"}, {Name: "a"}}}, ErrNodeExists},
+		{Config{Members: []MemberConfig{{Name: "a", Weight: -1}}}, ErrInvalidWeight},
+	} {
+		if _, err := NewFromConfig(tc.cfg); !errors.Is(err, tc.want) {
+			t.Errorf("%+v: got %v, want %v", tc.cfg, err, tc.want)
+		}
+	}
+	for _, cfg := range []Config{{Hash: "md5"}, {Replicas: -1}, {Members: []MemberConfig{{}}}} {
+		if _, err := NewFromConfig(cfg); err == nil {
+			t.Errorf("%+v: accepted", cfg)
+		}
+	}
+	if New(func([]byte) uint32 { return 1 }).Config().Hash != "custom" || New(nil).Config().Hash != "crc32" {
+		t.Fatal("wrong hash names for the default and a custom hash")
+	}
+}
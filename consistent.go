@@ -1,150 +1,603 @@
 package consistent
 
 import (
+	"errors"
 	"hash/crc32"
 	"sort"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Inspired by:
 // https://github.com/golang/groupcache/blob/master/consistenthash/consistenthash.go
 // https://github.com/stathat/consistent/blob/master/consistent.go
 
+var (
+	ErrNodeNotFound  = errors.New("consistent: node not found")
+	ErrNodeExists    = errors.New("consistent: node already exists")
+	ErrInvalidWeight = errors.New("consistent: weight must not be negative")
+	ErrEmpty         = errors.New("consistent: no items in the hash")
+	ErrInvalidCount  = errors.New("consistent: count must not be negative")
+)
+
 type Hash func(data []byte) uint32
 
+// The largest position on the ring.
+const MaxPosition = 1<<32 - 1
+
+// HashRange is an inclusive range of positions. It wraps past MaxPosition
+// to zero when From > To.
+type HashRange struct {
+	From int
+	To   int
+}
+
+// Option configures a Consistent.
+type Option func(*Consistent)
+
 type Consistent struct {
 	sync.RWMutex
-	hash    Hash
-	keys    []int // Sorted
-	hashMap map[int]string
+	hash      atomic.Pointer[Hash] // Swapped by SetHash under the write lock
+	hashName  string               // Empty for a hash function not chosen by name
+	stream    *streamHash          // Set by WithHasher
+	seed      uint64
+	domains   bool // Whether item names and keys are hashed apart, see WithDomainSeparation
+	replicas  int  // Points per key added without a weight
+	transform func(string) string
+	hashTags  bool // Only the tag of a key is hashed, see WithHashTags
+	formatter func(node string, replica int) []byte
+	clock     Clock
+	loadModel LoadModel
+	halfLife  time.Duration
+	smoothing float64 // The weight of each latency report, see WithLatencySmoothing
+
+	adviseIterations int
+	hot              *hotKeys
+	onLookup         func(key, node string)
+	traffic          *traffic
+	ejection         *ejection  // Nil without WithEjection
+	lastKnown        *lastKnown // Nil without WithLastKnownFallback
+	grace            *grace     // Nil without WithGraceWindow
+	strictPins       bool
+	treeIndex        bool
+	arcSplit         bool   // See WithArcSplittingPlacement
+	minQuorum        int    // Fewest owners GetQuorum accepts, 1 if zero
+	ipBits           [2]int // IPv4 and IPv6 prefix lengths for GetIP
+
+	maxNameLength int // Checked by AddStrict, unlimited if zero
+	deniedChars   string
+	validator     func(string) error
+
+	ring       *ring
+	frozen     bool
+	thaw       *ThawToken // Thaws the hash while it is frozen
+	generation uint64
+	epoch      uint64 // Counts every change to lookups, including health
+
+	watchMu     sync.Mutex
+	watchers    map[int]func(Event)
+	nextWatcher int
+
+	fingerprint fingerprint
+	history     history
+	changeLog   *changeLog // Nil without WithChangeLog
+
+	cache     *lookupCache
+	table     atomic.Pointer[lookupTable]
+	tableBits int
 }
 
-func New(fn Hash) *Consistent {
+func New(fn Hash, opts ...Option) *Consistent {
 	m := &Consistent{
-		hash:    fn,
-		hashMap: make(map[int]string),
+		replicas: 1,
+		history:  history{limit: defaultHistory},
+		clock:    systemClock{},
+		halfLife: defaultHalfLife,
+
+		adviseIterations: defaultAdviseIterations,
+		ipBits:           [2]int{defaultIPv4Bits, defaultIPv6Bits},
+		smoothing:        defaultSmoothing,
+
+		maxNameLength: defaultMaxNameLength,
+		deniedChars:   "#",
 	}
 
-	if m.hash == nil {
-		m.hash = crc32.ChecksumIEEE
+	if fn != nil {
+		m.hash.Store(&fn)
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.hash.Load() == nil {
+		fn := Hash(crc32.ChecksumIEEE)
+		m.hash.Store(&fn)
+		m.hashName = "crc32"
+	}
+
+	m.ring = m.newRing()
 
 	return m
 }
 
+// Use fn as the hash function, as an alternative to passing it to New.
+func WithHash(fn Hash) Option {
+	return func(m *Consistent) {
+		if fn != nil {
+			m.hash.Store(&fn)
+			m.hashName, m.seed = "", 0
+		}
+	}
+}
+
+// Give keys added without a weight n points on the ring instead of one.
+func WithReplicas(n int) Option {
+	return func(m *Consistent) {
+		if n > 0 {
+			m.replicas = n
+		}
+	}
+}
+
+// Normalize lookup keys with fn before hashing them, in Hash and every
+// method that looks a key up. The names of items are never transformed, so
+// Add("User:42") and Get("User:42") hash different strings when fn changes
+// the name.
+func WithKeyTransform(fn func(string) string) Option {
+	return func(m *Consistent) {
+		m.transform = fn
+	}
+}
+
 // Returns true if there are no items available.
 func (m *Consistent) IsEmpty() bool {
 	m.RLock()
 	defer m.RUnlock()
-	return len(m.keys) == 0
+	return m.ring.size() == 0
+}
+
+// Returns the keys in the hash, including standbys, sorted by name.
+func (m *Consistent) Members() []string {
+	m.RLock()
+	defer m.RUnlock()
+	members := make([]string, 0, len(m.ring.nodes))
+	for key := range m.ring.nodes {
+		members = append(members, key)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Returns the number of points a key has on the ring.
+func (m *Consistent) Weight(key string) (int, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return 0, false
+	}
+	return mem.weight, true
 }
 
-// Hash a key.
+// Returns the number of changes made to the membership so far.
+func (m *Consistent) Generation() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.generation
+}
+
+// Hash a key, after applying the key transform and taking its hash tag.
 func (m *Consistent) Hash(key string) int {
-	return int(m.hash([]byte(key)))
+	key = m.hashedKey(key)
+	if m.domains {
+		return m.hashIn(keyDomain, []byte(key))
+	}
+	return int(m.sum([]byte(key)))
 }
 
-// Add a key to the hash.
-func (m *Consistent) Add(key string) int {
-	hash := m.Hash(key)
+// The part of a key that Hash hashes.
+func (m *Consistent) hashedKey(key string) string {
+	if m.transform != nil {
+		key = m.transform(key)
+	}
+	if m.hashTags {
+		key = hashTag(key)
+	}
+	return key
+}
 
-	m.Lock()
-	defer m.Unlock()
-	if _, ok := m.hashMap[hash]; !ok {
-		// Do not add another key to the sorted index if it already exists
-		m.keys = append(m.keys, hash)
-		sort.Ints(m.keys)
+// Hash the name of an item, which is never transformed.
+func (m *Consistent) nodeHash(key string) int {
+	if m.domains {
+		return m.hashIn(nodeDomain, []byte(key))
 	}
+	return int(m.sum([]byte(key)))
+}
 
-	m.hashMap[hash] = key
+// Hash data with the current hash function.
+func (m *Consistent) sum(data []byte) uint32 {
+	return (*m.hash.Load())(data)
+}
 
+// The position of a replica of a key. See DefaultReplicaFormatter.
+func (m *Consistent) position(key string, replica int) int {
+	if m.formatter != nil {
+		if m.domains {
+			return m.hashIn(nodeDomain, m.formatter(key, replica))
+		}
+		return int(m.sum(m.formatter(key, replica)))
+	}
+	if replica == 0 {
+		return m.nodeHash(key)
+	}
+	// Built in a pooled buffer so replica names are never kept
+	bp := partsPool.Get().(*[]byte)
+	b := (*bp)[:0]
+	if m.domains {
+		b = append(b, nodeDomain...)
+	}
+	b = append(b, key...)
+	b = append(b, '#')
+	b = strconv.AppendInt(b, int64(replica), 10)
+	hash := int(m.sum(b))
+	*bp = b
+	partsPool.Put(bp)
 	return hash
 }
 
-// Remove a key from the hash.
-func (m *Consistent) Remove(key string) {
-	hash := m.Hash(key)
+// Add a key to the hash. Returns the position of its first point, or -1 if
+// the hash is frozen.
+func (m *Consistent) Add(key string) int {
+	hash := m.position(key, 0)
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if pos, ok := m.ring.origin(key); ok {
+			// Already present, possibly at the position of the key it was renamed from
+			hash = pos
+			return nil
+		}
+		if !m.ring.add(key, m.replicas) {
+			return nil
+		}
+		if pos, ok := m.ring.origin(key); ok {
+			// Placed elsewhere by WithArcSplittingPlacement
+			hash = pos
+		}
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: m.replicas})
+		return &Event{Type: EventAdd, Added: []string{key}}
+	})
+
+	if err != nil {
+		return -1
+	}
+	return hash
+}
 
-	m.Lock()
-	defer m.Unlock()
-	// Remove hash from m.keys
-	i := sort.SearchInts(m.keys, hash)
-	if i < len(m.keys) && m.keys[i] == hash {
-		m.keys = append(m.keys[:i], m.keys[i+1:]...)
+// Add a key to the hash with the given number of points, or change the
+// weight of a key already present.
+func (m *Consistent) AddWithWeight(key string, weight int) error {
+	if weight < 0 {
+		return ErrInvalidWeight
 	}
 
-	// Remove hash from hashMap
-	delete(m.hashMap, hash)
+	var err error
+	m.mutate(&err, func() *Event {
+		if m.ring.add(key, weight) {
+			m.ring.nodes[key].explicit = true
+			m.history.record(Change{Type: ChangeAdd, Key: key, Weight: weight})
+			return &Event{Type: EventAdd, Added: []string{key}}
+		}
+		m.ring.nodes[key].explicit, m.ring.nodes[key].ramp = true, nil
+		if m.ring.setWeight(key, weight) {
+			m.history.record(Change{Type: ChangeWeight, Key: key, Weight: weight})
+			return &Event{Type: EventWeight, Changed: []string{key}}
+		}
+		return nil
+	})
+
+	return err
+}
 
-	sort.Ints(m.keys)
+// Change the number of points a key has on the ring. Only the points above
+// the smaller of the old and new weights move.
+func (m *Consistent) SetWeight(key string, weight int) error {
+	if weight < 0 {
+		return ErrInvalidWeight
+	}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		mem.explicit, mem.ramp = true, nil
+		if !m.ring.setWeight(key, weight) {
+			return nil
+		}
+		m.history.record(Change{Type: ChangeWeight, Key: key, Weight: weight})
+		return &Event{Type: EventWeight, Changed: []string{key}}
+	})
+
+	return err
+}
+
+// Add a standby to the hash. A standby has points like any other key and
+// appears after the primary in NextN, but Get never returns it: keys in its
+// arcs stay where they would be without it until it is promoted.
+func (m *Consistent) AddStandby(key string) {
+	m.TryAddStandby(key)
+}
+
+// Like AddStandby, but returns ErrFrozen if the hash is frozen instead of
+// doing nothing.
+func (m *Consistent) TryAddStandby(key string) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if !m.ring.add(key, m.replicas) {
+			return nil
+		}
+		m.ring.nodes[key].standby = true
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: m.replicas, Standby: true})
+		return &Event{Type: EventStandby, Added: []string{key}}
+	})
+
+	return err
+}
+
+// Make a standby a full member, keeping its points so only the arcs they
+// define change owner.
+func (m *Consistent) Promote(key string) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if !mem.standby {
+			return nil
+		}
+		mem.standby = false
+		m.history.record(Change{Type: ChangePromote, Key: key})
+		return &Event{Type: EventPromote, Changed: []string{key}}
+	})
+
+	return err
+}
+
+// Returns true if the key is a standby.
+func (m *Consistent) IsStandby(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	return ok && mem.standby
+}
+
+// Remove a key from the hash.
+func (m *Consistent) Remove(key string) {
+	m.TryRemove(key)
+}
+
+// Like Remove, but returns ErrFrozen if the hash is frozen instead of doing
+// nothing.
+func (m *Consistent) TryRemove(key string) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if !m.ring.remove(key) {
+			return nil
+		}
+		m.history.record(Change{Type: ChangeRemove, Key: key})
+		return &Event{Type: EventRemove, Removed: []string{key}}
+	})
+
+	return err
+}
+
+// Rename a key in the hash, keeping its positions so no items move.
+func (m *Consistent) Rename(from, to string) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[from]; !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if _, ok := m.ring.nodes[to]; ok {
+			err = ErrNodeExists
+			return nil
+		}
+		m.ring.rename(from, to)
+		m.history.record(Change{Type: ChangeRename, Key: from, To: to})
+		return &Event{Type: EventRename, Added: []string{to}, Removed: []string{from}}
+	})
+
+	return err
 }
 
 // Get the item in the hash the provided key is in the range of.
 func (m *Consistent) Get(key string) string {
-	if m.IsEmpty() {
-		return ""
+	node := m.get(key)
+	if node == "" && m.lastKnown != nil {
+		node, _ = m.stale(key)
 	}
+	m.observe(key, node)
+	return node
+}
 
-	hash := m.Hash(key)
-	index := m.prev(hash)
+func (m *Consistent) get(key string) string {
+	m.coolDue()
+	m.sample(key)
+	if t := m.table.Load(); t != nil {
+		if len(t.pins) > 0 {
+			if node, ok := t.pins[m.pinKey(key)]; ok {
+				return node
+			}
+		}
+		if node, ok := t.get(m.Hash(key)); ok && t.current(m) {
+			return node
+		}
+	}
 
 	m.RLock()
 	defer m.RUnlock()
-	return m.hashMap[index]
-}
+	if node, ok := m.pinned(key); ok {
+		return node
+	}
+	if m.ring.size() == 0 {
+		return ""
+	}
 
-// Get the next item in the hash to the provided key.
-func (m *Consistent) Next(key string) string {
-	if m.IsEmpty() {
+	if m.ring.tiered() {
+		// Not cached, since the tier can depend on load
+		if i := m.lookupTiered(m.Hash(key), nil); i >= 0 {
+			return m.ring.nodeAt(i)
+		}
 		return ""
 	}
 
-	hash := m.Hash(key)
-	candidate := m.next(hash)
+	if m.cache != nil {
+		if node, ok := m.cache.get(key, m.epoch); ok {
+			return node
+		}
+	}
+
+	var node string
+	if i := m.ring.lookupTrickle(m.Hash(key), m.trickle()); i >= 0 {
+		node = m.ring.nodeAt(i)
+	}
+
+	if m.cache != nil {
+		m.cache.put(key, node, m.epoch)
+	}
+
+	return node
+}
 
+// Get the next item in the hash to the provided key.
+func (m *Consistent) Next(key string) string {
 	m.RLock()
 	defer m.RUnlock()
-	return m.hashMap[candidate]
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return ""
+	}
+
+	return m.ring.nodeAt(m.ring.nextIndex(hash))
 }
 
-// Get the range of hash keys to the provided item.
+// Get the range of hash keys to the provided item, from its first point up
+// to the next point whose item Get can return. An item Get skips, or one
+// not in the hash, gets the range of its first point up to the next point.
 func (m *Consistent) Range(host string) (int, int) {
-	if m.IsEmpty() {
+	m.RLock()
+	defer m.RUnlock()
+	if m.ring.size() == 0 {
 		return 0, 0
 	}
 
-	from := m.Hash(host)
-	to := m.next(from) - 1
+	from, ok := m.ring.origin(host)
+	if ok && m.ring.nodes[host].eligible() {
+		arc := m.ring.servedArc(m.ring.index.search(from))
+		return arc.From, arc.To
+	}
+	if !ok {
+		from = m.position(host, 0)
+	}
+	to := m.ring.next(from) - 1
+	if to < 0 {
+		to = MaxPosition
+	}
 
 	return from, to
 }
 
-func (m *Consistent) prev(hash int) int {
+// Get the ranges of hash keys Get maps to the provided item, merging
+// neighbouring points, in order of position. The item's points serve the
+// arcs of the standbys and unhealthy items after them, and an item Get
+// skips has no ranges. With several tiers the item has the arcs the tiered
+// lookup gives it, see AddTiered. Ranges pinned to another item are left out and
+// ranges pinned to this one added, see PinRange.
+func (m *Consistent) Ranges(key string) []HashRange {
 	m.RLock()
 	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok || !mem.eligible() || len(mem.positions) == 0 && len(m.ring.rangePins) == 0 {
+		return nil
+	}
 
-	rev := make([]int, len(m.keys))
-	copy(rev, m.keys)
-	sort.Sort(sort.Reverse(sort.IntSlice(rev)))
-
-	i := sort.Search(len(rev), func(i int) bool { return rev[i] <= hash })
+	var ranges []HashRange
+	if m.ring.tiered() {
+		ranges = m.ring.tieredRanges(key, m.atCapacity)
+	} else {
+		for _, pos := range mem.positions {
+			arc := m.ring.servedArc(m.ring.index.search(pos))
+			if n := len(ranges); n > 0 && ranges[n-1].To+1 == arc.From {
+				ranges[n-1].To = arc.To
+				continue
+			}
+			ranges = append(ranges, arc)
+		}
+	}
+	if len(m.ring.rangePins) > 0 {
+		ranges = m.ring.pinnedRanges(key, ranges, m.strictPins)
+	}
 
-	if i == len(rev) {
-		i -= 1
+	// The arc wrapping past the top joins the one starting at zero
+	if n := len(ranges); n > 1 && (ranges[n-1].To+1)&MaxPosition == ranges[0].From {
+		ranges[0].From = ranges[n-1].From
+		ranges = ranges[:n-1]
 	}
 
-	return rev[i]
+	return ranges
 }
 
-func (m *Consistent) next(hash int) int {
-	m.RLock()
-	defer m.RUnlock()
-
-	i := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] > hash })
+// Apply a change under the write lock. The change returns the event
+// describing it, or nil if nothing changed. Once the hash is frozen the
+// change is not made, and ErrFrozen is stored in err unless it is nil.
+func (m *Consistent) mutate(err *error, fn func() *Event) {
+	if e := m.apply(fn, err, true); e != nil {
+		m.forgetBreakers(*e)
+		m.notify(*e)
+	}
+}
 
-	if i == len(m.keys) {
-		i = 0
+// Apply a change to routing only, such as health, which Freeze allows.
+func (m *Consistent) reroute(fn func() *Event) {
+	if e := m.apply(fn, nil, false); e != nil {
+		m.notify(*e)
 	}
+}
 
-	return m.keys[i]
+func (m *Consistent) apply(fn func() *Event, err *error, guarded bool) *Event {
+	m.Lock()
+	defer m.Unlock()
+	if guarded && m.frozen {
+		if err != nil {
+			*err = ErrFrozen
+		}
+		return nil
+	}
+	e := fn()
+	defer m.history.discard()
+	if e != nil {
+		if !e.Type.routingOnly() {
+			m.generation++
+			if m.changeLog != nil {
+				m.changeLog.record(m.clock.Now(), m.generation, m.history.pending)
+			}
+			if e.Type.replayable() {
+				m.history.commit(m.generation)
+			} else {
+				m.history.reset()
+			}
+		}
+		m.epoch++
+		e.Generation = m.generation
+		m.rebuildTable()
+	}
+	return e
 }
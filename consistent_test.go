@@ -0,0 +1,209 @@
+package consistent
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRenameThenRemove(t *testing.T) {
+	c := New(nil)
+	c.AddWithWeight("a", 10)
+	c.AddWithWeight("b", 5)
+	before := c.Get("somekey")
+	if err := c.Rename("a", "z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Rename("a", "y"); err != ErrNodeNotFound {
+		t.Fatal(err)
+	}
+	if err := c.Rename("z", "b"); err != ErrNodeExists {
+		t.Fatal(err)
+	}
+	if after := c.Get("somekey"); before == "a" && after != "z" || before == "b" && after != "b" {
+		t.Fatal(before, after)
+	}
+	if got := c.Members(); len(got) != 2 || got[0] != "b" || got[1] != "z" {
+		t.Fatal(got)
+	}
+
+	c.Remove("z")
+	r := c.ring
+	if r.size() != 5 || r.index.len() != 5 || len(r.nodes) != 1 {
+		t.Fatal(r.names, r.nodes)
+	}
+	for i := 0; i < r.size(); i++ {
+		if node := r.nodeAt(i); node != "b" {
+			t.Fatalf("point %d still held by %q", i, node)
+		}
+	}
+	if len(r.free) != 1 || r.names[r.free[0]] != "" {
+		t.Fatal(r.names, r.free)
+	}
+	if _, ok := c.Weight("z"); ok {
+		t.Fatal("z still present")
+	}
+}
+
+func standbyRing(opts ...Option) *Consistent {
+	c := New(nil, append([]Option{WithReplicas(10)}, opts...)...)
+	for i := 0; i < 5; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	c.AddStandby("s")
+	return c
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	return keys
+}
+
+func rangeLength(r HashRange) uint64 {
+	return uint64((r.To-r.From)&MaxPosition) + 1
+}
+
+// A clock that moves only when told to.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Unix(1000, 0)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestStandbyNeverReturned(t *testing.T) {
+	for _, bits := range []int{0, 12} {
+		c := standbyRing(WithLookupTable(bits))
+		for _, k := range testKeys(5000) {
+			node := c.Get(k)
+			if node == "s" || node == "" {
+				t.Fatalf("%s maps to %q", k, node)
+			}
+			if o, _ := c.GetOwner(k); o.Node != node {
+				t.Fatalf("%s: GetOwner %q, Get %q", k, o.Node, node)
+			}
+			if n := c.NextN(k, 6); len(n) != 6 || n[0] != node {
+				t.Fatalf("%s: NextN %v, Get %q", k, n, node)
+			}
+			if p := c.PrevN(k, 6); len(p) != 6 || p[0] != node {
+				t.Fatalf("%s: PrevN %v, Get %q", k, p, node)
+			}
+			if p := c.PrevNDistinct(k, 1, false); len(p) != 1 || p[0] == node {
+				t.Fatalf("%s: PrevNDistinct %v, Get %q", k, p, node)
+			}
+			h, in := c.Hash(k), false
+			for _, r := range c.Ranges(node) {
+				in = in || contains(r, h)
+			}
+			if !in {
+				t.Fatalf("%s: Ranges(%q) leaves out %d", k, node, h)
+			}
+		}
+
+		if r := c.Ranges("s"); r != nil {
+			t.Fatal("standby has ranges", r)
+		}
+		if n, ok := c.ArcLength("s"); !ok || n != 0 {
+			t.Fatal("standby has an arc length", n)
+		}
+		var total uint64
+		for _, node := range c.Members() {
+			n, _ := c.ArcLength(node)
+			var sum uint64
+			for _, r := range c.Ranges(node) {
+				sum += rangeLength(r)
+			}
+			if sum != n {
+				t.Fatalf("%s: Ranges cover %d, ArcLength %d", node, sum, n)
+			}
+			total += n
+		}
+		if total != MaxPosition+1 {
+			t.Fatal("arc lengths sum to", total)
+		}
+		for _, o := range c.RangeOwners(0, MaxPosition) {
+			if o.Node == "s" {
+				t.Fatal("standby owns", o.Range)
+			}
+		}
+	}
+
+	only := New(nil)
+	only.AddStandby("x")
+	if only.Get("k") != "" || only.Ranges("x") != nil {
+		t.Fatal("a lone standby serves keys")
+	}
+	if n := only.NextN("k", 2); len(n) != 1 {
+		t.Fatal(n)
+	}
+}
+
+// Promotion moves exactly the keys in the arcs the standby's own points
+// define, all of them to the standby.
+func TestPromoteMovesOnlyStandbyArcs(t *testing.T) {
+	c := standbyRing()
+	var arcs []HashRange
+	for i := 0; i < c.ring.size(); i++ {
+		if c.ring.nodeAt(i) == "s" {
+			arcs = append(arcs, c.ring.arc(i))
+		}
+	}
+	keys := testKeys(20000)
+	before := make(map[string]string, len(keys))
+	for _, k := range keys {
+		before[k] = c.Get(k)
+	}
+
+	var events []Event
+	c.Watch(func(e Event) { events = append(events, e) })
+	if err := c.Promote("s"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventPromote {
+		t.Fatal(events)
+	}
+
+	moved := 0
+	for _, k := range keys {
+		h, inArc := c.Hash(k), false
+		for _, r := range arcs {
+			inArc = inArc || contains(r, h)
+		}
+		switch got := c.Get(k); {
+		case inArc && got != "s":
+			t.Fatalf("%s in a standby arc maps to %q", k, got)
+		case !inArc && got != before[k]:
+			t.Fatalf("%s moved from %q to %q", k, before[k], got)
+		case inArc:
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("nothing moved")
+	}
+	var want uint64
+	for _, r := range arcs {
+		want += rangeLength(r)
+	}
+	if n, _ := c.ArcLength("s"); n != want {
+		t.Fatalf("promoted standby has %d, its arcs %d", n, want)
+	}
+}
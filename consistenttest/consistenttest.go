@@ -0,0 +1,91 @@
+// Package consistenttest provides helpers for testing code that uses
+// consistent hashes. It only uses the public API of the consistent package.
+package consistenttest
+
+import (
+	"hash/crc32"
+	"testing"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// Hash returns a hash function mapping each key in positions to its value,
+// so tests can put items and keys at exact positions. Other keys, including
+// the replica names of weighted items, fall back to crc32.
+func Hash(positions map[string]uint32) consistent.Hash {
+	return func(data []byte) uint32 {
+		if pos, ok := positions[string(data)]; ok {
+			return pos
+		}
+		return crc32.ChecksumIEEE(data)
+	}
+}
+
+// AssertOwner fails the test unless key belongs to want.
+func AssertOwner(t testing.TB, ring consistent.Reader, key, want string) {
+	t.Helper()
+	if got := ring.Get(key); got != want {
+		t.Errorf("Get(%q) = %q, want %q", key, got, want)
+	}
+}
+
+// AssertBalanced fails the test if the busiest item gets more than
+// maxPeakToMean times the mean number of sample keys over the members
+// lookups can return.
+func AssertBalanced(t testing.TB, ring consistent.Reader, maxPeakToMean float64, sampleKeys []string) {
+	t.Helper()
+	counts := make(map[string]int)
+	for _, key := range ring.Members() {
+		if !ring.IsStandby(key) && ring.IsHealthy(key) {
+			counts[key] = 0
+		}
+	}
+	if len(counts) == 0 || len(sampleKeys) == 0 {
+		t.Errorf("nothing to balance: %d members, %d keys", len(counts), len(sampleKeys))
+		return
+	}
+
+	peak, busiest := 0, ""
+	for _, key := range sampleKeys {
+		node := ring.Get(key)
+		counts[node]++
+		if counts[node] > peak {
+			peak, busiest = counts[node], node
+		}
+	}
+	mean := float64(len(sampleKeys)) / float64(len(counts))
+	if ratio := float64(peak) / mean; ratio > maxPeakToMean {
+		t.Errorf("%q has %d of %d keys, %.2f times the mean, want at most %.2f", busiest, peak, len(sampleKeys), ratio, maxPeakToMean)
+	}
+}
+
+// MeasureChurn returns the fraction of sample keys whose item differs
+// between before and after.
+func MeasureChurn(t testing.TB, before, after consistent.Reader, sampleKeys []string) float64 {
+	t.Helper()
+	if len(sampleKeys) == 0 {
+		t.Errorf("no sample keys")
+		return 0
+	}
+	moved := 0
+	for _, key := range sampleKeys {
+		if before.Get(key) != after.Get(key) {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(sampleKeys))
+}
+
+// AssertMinimalChurn fails the test if more than 1/n + tolerance of the
+// sample keys moved between before and after, where n is the larger number
+// of members: the share one added or removed item should move.
+func AssertMinimalChurn(t testing.TB, before, after consistent.Reader, sampleKeys []string, tolerance float64) {
+	t.Helper()
+	n := max(len(before.Members()), len(after.Members()))
+	if n == 0 {
+		return
+	}
+	if churn, limit := MeasureChurn(t, before, after, sampleKeys), 1/float64(n)+tolerance; churn > limit {
+		t.Errorf("%.4f of keys moved, want at most %.4f", churn, limit)
+	}
+}
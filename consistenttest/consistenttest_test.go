@@ -0,0 +1,124 @@
+package consistenttest
+
+import (
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// A test recording the failures of the helpers instead of failing.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) check(t *testing.T, name string, fail bool) {
+	t.Helper()
+	if failed := len(r.errors) > 0; failed != fail {
+		t.Errorf("%s: failed %v, want %v: %q", name, failed, fail, r.errors)
+	}
+	r.errors = nil
+}
+
+func sampleKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	return keys
+}
+
+func TestHash(t *testing.T) {
+	hash := Hash(map[string]uint32{"a": 100, "k": 150})
+	if got := hash([]byte("a")); got != 100 {
+		t.Errorf("hash of a = %d, want 100", got)
+	}
+	if got, want := hash([]byte("other")), crc32.ChecksumIEEE([]byte("other")); got != want {
+		t.Errorf("hash of other = %d, want the crc32 %d", got, want)
+	}
+}
+
+func TestAssertOwner(t *testing.T) {
+	m := consistent.New(Hash(map[string]uint32{"a": 100, "b": 200, "k": 150, "wrap": 50}))
+	m.Add("a")
+	m.Add("b")
+	// Keys belong to the point at or before them, wrapping below the first.
+	r := &recorder{TB: t}
+	AssertOwner(r, m, "k", "a")
+	r.check(t, "k on a", false)
+	AssertOwner(r, m, "wrap", "b")
+	r.check(t, "wrap on b", false)
+	AssertOwner(r, m, "k", "b")
+	r.check(t, "k on b", true)
+}
+
+func TestAssertBalanced(t *testing.T) {
+	keys := sampleKeys(5000)
+	even := consistent.New(nil, consistent.WithReplicas(200))
+	skewed := consistent.New(nil, consistent.WithReplicas(200))
+	for i := 0; i < 5; i++ {
+		even.Add(fmt.Sprint("n", i))
+		skewed.Add(fmt.Sprint("n", i))
+	}
+	skewed.SetWeight("n0", 2000)
+	even.AddStandby("standby") // Standbys are not counted in the mean
+
+	r := &recorder{TB: t}
+	AssertBalanced(r, even, 1.3, keys)
+	r.check(t, "even", false)
+	AssertBalanced(r, skewed, 1.3, keys)
+	r.check(t, "skewed", true)
+	AssertBalanced(r, consistent.New(nil), 1.3, keys)
+	r.check(t, "empty", true)
+	AssertBalanced(r, even, 1.3, nil)
+	r.check(t, "no keys", true)
+}
+
+func TestMeasureChurn(t *testing.T) {
+	keys := sampleKeys(5000)
+	before := consistent.New(nil, consistent.WithReplicas(100))
+	for i := 0; i < 5; i++ {
+		before.Add(fmt.Sprint("n", i))
+	}
+	added := consistent.New(nil, consistent.WithReplicas(100))
+	rehashed := consistent.New(func(b []byte) uint32 { return crc32.Checksum(b, crc32.MakeTable(crc32.Koopman)) }, consistent.WithReplicas(100))
+	for i := 0; i < 6; i++ {
+		added.Add(fmt.Sprint("n", i))
+		rehashed.Add(fmt.Sprint("n", i))
+	}
+
+	r := &recorder{TB: t}
+	if churn := MeasureChurn(r, before, before, keys); churn != 0 {
+		t.Errorf("%v of keys moved between a hash and itself", churn)
+	}
+	moved := 0
+	for _, key := range keys {
+		if before.Get(key) != added.Get(key) {
+			moved++
+			if added.Get(key) != "n5" {
+				t.Fatalf("%s moved to %s, not to the added item", key, added.Get(key))
+			}
+		}
+	}
+	if churn := MeasureChurn(r, before, added, keys); churn != float64(moved)/float64(len(keys)) {
+		t.Errorf("churn %v, want %d of %d", churn, moved, len(keys))
+	}
+	r.check(t, "measured", false)
+
+	AssertMinimalChurn(r, before, added, keys, 0.03)
+	r.check(t, "one added item", false)
+	AssertMinimalChurn(r, before, rehashed, keys, 0.03)
+	r.check(t, "another hash", true)
+	MeasureChurn(r, before, added, nil)
+	r.check(t, "no keys", true)
+	AssertMinimalChurn(r, consistent.New(nil), consistent.New(nil), keys, 0)
+	r.check(t, "empty hashes", false)
+}
@@ -0,0 +1,140 @@
+package consistent
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The half-life of hit rates when WithLoadHalfLife is not given.
+const defaultHalfLife = time.Minute
+
+// Clock tells the time. Tests can provide one they control.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Use c for every time-based decision of the hash. The default is the system
+// clock, whose readings are monotonic.
+func WithClock(c Clock) Option {
+	return func(m *Consistent) {
+		if c != nil {
+			m.clock = c
+		}
+	}
+}
+
+// LoadModel selects the measure of load that capacity-based lookups use.
+type LoadModel int
+
+const (
+	LoadCount LoadModel = iota // The units counted by Inc and Done
+	LoadRate                   // The decayed rate of RecordHit, in hits per second
+)
+
+// Choose the measure of load that GetWithinCapacity and GetLeastLoaded
+// compare.
+func WithLoadModel(model LoadModel) Option {
+	return func(m *Consistent) {
+		m.loadModel = model
+	}
+}
+
+// Let recorded hits count half as much after d.
+func WithLoadHalfLife(d time.Duration) Option {
+	return func(m *Consistent) {
+		if d > 0 {
+			m.halfLife = d
+		}
+	}
+}
+
+// An exponentially decayed count of hits, decayed when read or written
+// rather than in the background.
+type decayed struct {
+	sync.Mutex
+	count float64
+	at    time.Time
+}
+
+func (d *decayed) add(now time.Time, halfLife time.Duration, n float64) float64 {
+	d.Lock()
+	defer d.Unlock()
+	if elapsed := now.Sub(d.at); elapsed > 0 {
+		d.count *= math.Exp2(-float64(elapsed) / float64(halfLife))
+		d.at = now
+	} else if d.at.IsZero() {
+		d.at = now
+	}
+	d.count += n
+	return d.count
+}
+
+// Record a hit on an item for the decayed load model.
+func (m *Consistent) RecordHit(key string) {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[key]; ok {
+		mem.hits.add(m.clock.Now(), m.halfLife, 1)
+	}
+}
+
+// Returns the decayed hit rate of an item in hits per second. A steady rate
+// of r hits per second converges to r.
+func (m *Consistent) LoadRate(key string) float64 {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[key]; ok {
+		return m.rate(mem)
+	}
+	return 0
+}
+
+func (m *Consistent) rate(mem *member) float64 {
+	return mem.hits.add(m.clock.Now(), m.halfLife, 0) * math.Ln2 / m.halfLife.Seconds()
+}
+
+// The load of an item under the configured model.
+func (m *Consistent) loadOf(mem *member) float64 {
+	if m.loadModel == LoadRate {
+		return m.rate(mem)
+	}
+	return float64(atomic.LoadInt64(&mem.load))
+}
+
+// Get the least loaded of the first n items in NextN order for the provided
+// key, starting with the item Get returns, pins included, and preferring the
+// earlier item on ties. Standbys and unhealthy items are never chosen.
+func (m *Consistent) GetLeastLoaded(key string, n int) (string, error) {
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return "", ErrEmpty
+	}
+
+	node, least := "", math.Inf(1)
+	m.ownerCandidates(key, hash, func(key string, first bool) bool {
+		mem := m.ring.nodes[key]
+		if !first && !mem.eligible() {
+			return true
+		}
+		if load := m.loadOf(mem); load < least {
+			node, least = key, load
+		}
+		n--
+		return n > 0
+	})
+	if node == "" {
+		return "", ErrEmpty
+	}
+	return node, nil
+}
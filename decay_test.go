@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// Find a key Get maps to node.
+func keyOwnedBy(t *testing.T, m *Consistent, node string) string {
+	t.Helper()
+	for _, key := range testKeys(1000) {
+		if m.Get(key) == node {
+			return key
+		}
+	}
+	t.Fatalf("no key maps to %s", node)
+	return ""
+}
+
+func TestLoadRateDecays(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithClock(clock), WithLoadHalfLife(10*time.Second))
+	m.Add("a")
+	for i := 0; i < 100; i++ {
+		m.RecordHit("a")
+	}
+	start := m.LoadRate("a")
+	if want := 100 * math.Ln2 / 10; math.Abs(start-want) > 1e-9 {
+		t.Fatalf("rate %v, want %v", start, want)
+	}
+	clock.advance(10 * time.Second)
+	if r := m.LoadRate("a"); math.Abs(r-start/2) > 1e-9 {
+		t.Fatalf("rate %v after a half-life, want %v", r, start/2)
+	}
+	clock.advance(20 * time.Second)
+	if r := m.LoadRate("a"); math.Abs(r-start/8) > 1e-9 {
+		t.Fatalf("rate %v after three half-lives, want %v", r, start/8)
+	}
+	if m.LoadRate("missing") != 0 {
+		t.Fatal("rate of a missing item")
+	}
+}
+
+// A steady 20 hits per second converges to a rate of 20.
+func TestLoadRateSteady(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithClock(clock), WithLoadHalfLife(10*time.Second))
+	m.Add("a")
+	for i := 0; i < 600; i++ {
+		m.RecordHit("a")
+		m.RecordHit("a")
+		clock.advance(100 * time.Millisecond)
+	}
+	if r := m.LoadRate("a"); math.Abs(r-20) > 1 {
+		t.Fatalf("rate %v, want about 20", r)
+	}
+}
+
+func TestLoadRateModel(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithClock(clock), WithLoadModel(LoadRate), WithLoadHalfLife(10*time.Second))
+	m.Add("a")
+	m.Add("b")
+	key := keyOwnedBy(t, m, "a")
+
+	for i := 0; i < 100; i++ {
+		m.RecordHit("a")
+	}
+	if got, _ := m.GetLeastLoaded(key, 2); got != "b" {
+		t.Fatalf("least loaded %s, want b", got)
+	}
+	// Counted load does not matter under the rate model
+	m.Inc("b")
+	m.Inc("b")
+	if got, _ := m.GetLeastLoaded(key, 2); got != "b" {
+		t.Fatalf("least loaded %s with counted load on b, want b", got)
+	}
+
+	// Five minutes on, the hits have decayed below a single new one
+	clock.advance(5 * time.Minute)
+	m.RecordHit("b")
+	if got, _ := m.GetLeastLoaded(key, 2); got != "a" {
+		t.Fatalf("least loaded %s after the decay, want a", got)
+	}
+
+	m.SetCapacity("a", 5)
+	for i := 0; i < 100; i++ {
+		m.RecordHit("a")
+	}
+	if got, _ := m.GetWithinCapacity(key); got != "b" {
+		t.Fatalf("within capacity %s with a over its rate, want b", got)
+	}
+	clock.advance(time.Minute)
+	if got, _ := m.GetWithinCapacity(key); got != "a" {
+		t.Fatalf("within capacity %s once a cooled down, want a", got)
+	}
+}
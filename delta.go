@@ -0,0 +1,271 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrHistoryTooOld = errors.New("consistent: generation is no longer in the history, take a snapshot")
+	ErrDeltaOrder    = errors.New("consistent: delta does not start at the current generation")
+	ErrDeltaMismatch = errors.New("consistent: delta does not produce the fingerprint it claims")
+)
+
+// The number of generations kept for ChangesSince by default.
+const defaultHistory = 1024
+
+type ChangeType int
+
+const (
+	ChangeAdd ChangeType = iota
+	ChangeRemove
+	ChangeWeight
+	ChangeRename
+	ChangePromote
+	ChangePin // Key is a lookup key, To the item
+	ChangeUnpin
+	ChangePinRange // Range is the range, To the item
+	ChangeUnpinRange
+	ChangeZone // To is the new zone
+)
+
+// Change is a single membership change, in the form it is replayed by
+// ApplyDelta.
+type Change struct {
+	Type    ChangeType `json:"type"`
+	Key     string     `json:"key"`
+	To      string     `json:"to,omitempty"`      // The new name of a renamed key, or the item a key is pinned to
+	Weight  int        `json:"weight,omitempty"`  // The weight of an added or reweighted key
+	Standby bool       `json:"standby,omitempty"` // Whether an added key is a standby
+	Tier    int        `json:"tier,omitempty"`    // The tier of an added key, see AddTiered
+	Range   *HashRange `json:"range,omitempty"`   // The range of a range pin
+}
+
+// Step is the changes that produced one generation, in the order they were
+// made. A transaction is a single step.
+type Step struct {
+	Generation uint64   `json:"generation"`
+	Changes    []Change `json:"changes"`
+}
+
+// Delta is the changes taking a hash from generation From to generation To,
+// and the fingerprint of the hash at To.
+type Delta struct {
+	From        uint64 `json:"from"`
+	To          uint64 `json:"to"`
+	Steps       []Step `json:"steps"`
+	Fingerprint uint64 `json:"fingerprint"`
+}
+
+// Keep the changes of the last n generations for ChangesSince.
+func WithHistory(n int) Option {
+	return func(m *Consistent) {
+		if n >= 0 {
+			m.history.limit = n
+		}
+	}
+}
+
+// The recent steps of a hash, guarded by its lock.
+type history struct {
+	limit   int
+	steps   []Step   // Consecutive generations, oldest first
+	pending []Change // Changes made by the mutation in progress
+}
+
+func (h *history) record(changes ...Change) {
+	h.pending = append(h.pending, changes...)
+}
+
+// Store the pending changes as the step producing generation.
+func (h *history) commit(generation uint64) {
+	h.push(Step{Generation: generation, Changes: h.pending})
+	h.pending = nil
+}
+
+func (h *history) discard() {
+	h.pending = nil
+}
+
+func (h *history) push(s Step) {
+	if h.limit == 0 {
+		return
+	}
+	if len(h.steps) >= h.limit {
+		h.steps = append(h.steps[:0], h.steps[len(h.steps)-h.limit+1:]...)
+	}
+	h.steps = append(h.steps, s)
+}
+
+// Forget every step, as when the membership is replaced wholesale.
+func (h *history) reset() {
+	h.steps = nil
+	h.pending = nil
+}
+
+// Get the changes made since the given generation. Returns
+// ErrHistoryTooOld if some of them are no longer kept, in which case a
+// follower has to start over from a snapshot.
+func (m *Consistent) ChangesSince(generation uint64) (Delta, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	if generation > m.generation {
+		return Delta{}, fmt.Errorf("consistent: generation %d is ahead of the hash at %d", generation, m.generation)
+	}
+
+	d := Delta{From: generation, To: m.generation, Fingerprint: m.fingerprintLocked()}
+	if generation == m.generation {
+		return d, nil
+	}
+
+	steps := m.history.steps
+	if len(steps) == 0 || steps[0].Generation > generation+1 {
+		return Delta{}, ErrHistoryTooOld
+	}
+	for _, s := range steps[generation+1-steps[0].Generation:] {
+		d.Steps = append(d.Steps, Step{
+			Generation: s.Generation,
+			Changes:    append([]Change(nil), s.Changes...),
+		})
+	}
+	return d, nil
+}
+
+// Apply a delta from another hash. It must start at the current
+// generation, so a delta applied twice or out of order is rejected with
+// ErrDeltaOrder, and the result must match the delta's fingerprint, or
+// ErrDeltaMismatch is returned. Either way the hash is left unchanged on
+// error. Otherwise the hash moves to the delta's generation at once and
+// watchers receive a single EventUpdate.
+func (m *Consistent) ApplyDelta(d Delta) error {
+	e, err := m.applyDelta(d)
+	if e != nil {
+		m.notify(*e)
+	}
+	return err
+}
+
+func (m *Consistent) applyDelta(d Delta) (*Event, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.frozen {
+		return nil, ErrFrozen
+	}
+	if d.From != m.generation {
+		if d.To <= m.generation {
+			return nil, fmt.Errorf("%w: already at generation %d, delta is %d to %d", ErrDeltaOrder, m.generation, d.From, d.To)
+		}
+		return nil, fmt.Errorf("%w: at generation %d, delta is %d to %d", ErrDeltaOrder, m.generation, d.From, d.To)
+	}
+	if uint64(len(d.Steps)) != d.To-d.From {
+		return nil, fmt.Errorf("%w: %d steps from %d to %d", ErrDeltaOrder, len(d.Steps), d.From, d.To)
+	}
+
+	r := m.ring.clone()
+	for i, s := range d.Steps {
+		if s.Generation != d.From+uint64(i)+1 {
+			return nil, fmt.Errorf("%w: step %d is for generation %d", ErrDeltaOrder, i, s.Generation)
+		}
+		for _, c := range s.Changes {
+			if err := r.apply(c); err != nil {
+				return nil, fmt.Errorf("consistent: generation %d: %w", s.Generation, err)
+			}
+		}
+	}
+	r.sortKeys()
+
+	if sum := r.fingerprint(); sum != d.Fingerprint {
+		return nil, fmt.Errorf("%w: got %016x, want %016x", ErrDeltaMismatch, sum, d.Fingerprint)
+	}
+	if d.From == d.To {
+		return nil, nil
+	}
+
+	e := diff(m.ring, r)
+	m.ring = r
+	m.generation = d.To
+	m.epoch++
+	now := m.clock.Now()
+	for _, s := range d.Steps {
+		m.history.push(s)
+		if m.changeLog != nil {
+			m.changeLog.record(now, s.Generation, s.Changes)
+		}
+	}
+	m.rebuildTable()
+
+	if e == nil {
+		e = &Event{Type: EventUpdate}
+	}
+	e.Generation = m.generation
+	return e, nil
+}
+
+// Replay a change made to another ring.
+func (r *ring) apply(c Change) error {
+	switch c.Type {
+	case ChangePin:
+		if _, ok := r.nodes[c.To]; !ok {
+			return fmt.Errorf("%w: %q", ErrNodeNotFound, c.To)
+		}
+		r.pin(c.Key, c.To)
+		return nil
+	case ChangeUnpin:
+		r.unpin(c.Key)
+		return nil
+	case ChangePinRange:
+		if _, ok := r.nodes[c.To]; !ok {
+			return fmt.Errorf("%w: %q", ErrNodeNotFound, c.To)
+		}
+		if c.Range == nil {
+			return fmt.Errorf("%w: range pin without a range", ErrCorrupt)
+		}
+		if _, ok := r.pinRange(rangePin{HashRange: *c.Range, node: c.To}); !ok {
+			return fmt.Errorf("%w: %d-%d", ErrRangeOverlap, c.Range.From, c.Range.To)
+		}
+		return nil
+	case ChangeUnpinRange:
+		if c.Range != nil {
+			r.unpinRange(*c.Range)
+		}
+		return nil
+	case ChangeAdd:
+		if c.Weight < 0 {
+			return ErrInvalidWeight
+		}
+		if !r.add(c.Key, c.Weight) {
+			return fmt.Errorf("%w: %q", ErrNodeExists, c.Key)
+		}
+		r.nodes[c.Key].standby = c.Standby
+		r.setTier(r.nodes[c.Key], c.Tier)
+		return nil
+	}
+
+	mem, ok := r.nodes[c.Key]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNodeNotFound, c.Key)
+	}
+	switch c.Type {
+	case ChangeRemove:
+		r.remove(c.Key)
+	case ChangeWeight:
+		if c.Weight < 0 {
+			return ErrInvalidWeight
+		}
+		r.setWeight(c.Key, c.Weight)
+	case ChangeRename:
+		if _, ok := r.nodes[c.To]; ok {
+			return fmt.Errorf("%w: %q", ErrNodeExists, c.To)
+		}
+		r.rename(c.Key, c.To)
+	case ChangePromote:
+		mem.standby = false
+	case ChangeZone:
+		mem.zone = c.To
+	default:
+		return fmt.Errorf("consistent: unknown change type %d", c.Type)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+// A follower replaying the leader's deltas after 1000 random mutations
+// ends up with the same fingerprint and routing.
+func TestDeltaFollower(t *testing.T) {
+	leader := New(nil, WithReplicas(4), WithHistory(2000))
+	leader.Add("seed")
+	path := filepath.Join(t.TempDir(), "ring.json")
+	if err := leader.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	follower, err := Load(path, WithReplicas(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	name := func() string { return fmt.Sprint("n", rnd.Intn(60)) }
+	keys := testKeys(500)
+	sync := func(i int) {
+		t.Helper()
+		d, err := leader.ChangesSince(follower.Generation())
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if err := follower.ApplyDelta(d); err != nil {
+			t.Fatal(i, err)
+		}
+		if err := follower.ApplyDelta(d); d.From != d.To && !errors.Is(err, ErrDeltaOrder) {
+			t.Fatal(i, "a delta applied twice:", err)
+		}
+		if follower.Fingerprint() != leader.Fingerprint() || follower.Generation() != leader.Generation() {
+			t.Fatalf("mutation %d: follower at %d, leader at %d", i, follower.Generation(), leader.Generation())
+		}
+		for _, k := range keys {
+			if follower.Get(k) != leader.Get(k) {
+				t.Fatalf("mutation %d: %s routes apart", i, k)
+			}
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		switch rnd.Intn(9) {
+		case 0:
+			leader.Add(name())
+		case 1:
+			leader.Remove(name())
+		case 2:
+			leader.AddWithWeight(name(), rnd.Intn(8))
+		case 3:
+			leader.SetWeight(name(), rnd.Intn(8))
+		case 4:
+			leader.AddStandby(name())
+			leader.Promote(name())
+		case 5:
+			leader.Rename(name(), name())
+		case 6:
+			leader.Pin(fmt.Sprint("key", rnd.Intn(500)), name())
+		case 7:
+			leader.AddTiered(name(), rnd.Intn(3))
+		case 8:
+			leader.Update(func(tx *Tx) error {
+				tx.Add(name())
+				tx.Remove(name())
+				return tx.AddWithWeight(name(), 3)
+			})
+		}
+		if rnd.Intn(5) == 0 {
+			sync(i)
+		}
+	}
+	sync(1000)
+}
+
+func TestDeltaErrors(t *testing.T) {
+	small := New(nil, WithHistory(2))
+	for i := 0; i < 5; i++ {
+		small.Add(fmt.Sprint(i))
+	}
+	if _, err := small.ChangesSince(2); !errors.Is(err, ErrHistoryTooOld) {
+		t.Fatal(err)
+	}
+	d, err := small.ChangesSince(3)
+	if err != nil || len(d.Steps) != 2 {
+		t.Fatal(err, d)
+	}
+
+	f := New(nil)
+	for i := 0; i < 3; i++ {
+		f.Add(fmt.Sprint(i))
+	}
+	d.Fingerprint++
+	if err := f.ApplyDelta(d); !errors.Is(err, ErrDeltaMismatch) || f.Generation() != 3 || len(f.Members()) != 3 {
+		t.Fatal(err, f.Members())
+	}
+}
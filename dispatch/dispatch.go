@@ -0,0 +1,208 @@
+// Package dispatch runs tasks on a pool of workers chosen by a consistent
+// hash of their keys, so the tasks of a key run in order on one goroutine
+// and resizing the pool moves only some keys.
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+var (
+	ErrClosed    = errors.New("dispatch: dispatcher is closed")
+	ErrNoWorkers = errors.New("dispatch: no workers")
+)
+
+// The replicas each worker has on the ring unless the options say otherwise,
+// enough to spread keys evenly over a handful of workers.
+const defaultReplicas = 100
+
+// Dispatcher runs tasks on workers, one goroutine each, keeping the tasks of
+// a key in the order they were submitted.
+type Dispatcher struct {
+	submitMu sync.Mutex // Held while routing and queueing, so queues are in submission order
+	ring     *consistent.Consistent
+	workers  []*worker
+	byName   map[string]*worker
+	closed   bool
+
+	mu     sync.Mutex // Guards keys and the counts of queued tasks
+	keys   map[string]*keyState
+	handle func(key string, task any)
+	buffer int
+	wg     sync.WaitGroup
+}
+
+type worker struct {
+	name    string
+	tasks   chan job
+	queued  int // Tasks queued or running
+	retired bool
+}
+
+// The tasks of a key on one worker. When the key moves, its first task on
+// the new worker waits for drained, so the tasks left behind run first.
+type keyState struct {
+	w       *worker
+	queued  int
+	drained chan struct{} // Made when the key moves, closed once queued is zero
+}
+
+type job struct {
+	key   string
+	task  any
+	state *keyState
+	after chan struct{} // Closed once the key's tasks on its previous worker ran
+}
+
+// Create a dispatcher with the given number of workers, each queueing up to
+// buffer tasks, calling handle for every task. The options configure the
+// ring of workers, which has 100 replicas per worker by default.
+func New(workers, buffer int, handle func(key string, task any), opts ...consistent.Option) *Dispatcher {
+	opts = append([]consistent.Option{consistent.WithReplicas(defaultReplicas)}, opts...)
+	d := &Dispatcher{
+		ring:   consistent.New(nil, opts...),
+		byName: make(map[string]*worker),
+		keys:   make(map[string]*keyState),
+		handle: handle,
+		buffer: max(buffer, 0),
+	}
+	d.resize(max(workers, 0))
+	return d
+}
+
+// Queue a task for the worker of key, blocking while its queue is full. The
+// tasks of a key run in the order they were submitted, even when a resize
+// moves the key to another worker. Returns ErrClosed once Close was called.
+func (d *Dispatcher) Submit(key string, task any) error {
+	d.submitMu.Lock()
+	defer d.submitMu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	node := d.ring.Get(key)
+	if node == "" {
+		return ErrNoWorkers
+	}
+	target := d.byName[node]
+
+	d.mu.Lock()
+	j := job{key: key, task: task}
+	st := d.keys[key]
+	if st != nil && st.w != target {
+		if st.drained == nil {
+			st.drained = make(chan struct{})
+		}
+		j.after = st.drained
+		st = nil
+	}
+	if st == nil {
+		st = &keyState{w: target}
+		d.keys[key] = st
+	}
+	st.queued++
+	target.queued++
+	j.state = st
+	d.mu.Unlock()
+
+	target.tasks <- j
+	return nil
+}
+
+// Change the number of workers. Added workers take over some keys at once;
+// the tasks already queued for a moved key run first, on its old worker.
+// Removed workers stop once the tasks queued on them have run.
+func (d *Dispatcher) Resize(n int) error {
+	if n < 0 {
+		return fmt.Errorf("dispatch: negative number of workers %d", n)
+	}
+	d.submitMu.Lock()
+	defer d.submitMu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.resize(n)
+	return nil
+}
+
+// Returns the number of workers.
+func (d *Dispatcher) Workers() int {
+	d.submitMu.Lock()
+	defer d.submitMu.Unlock()
+	return len(d.workers)
+}
+
+// Stop accepting tasks and wait for every queued task to run.
+func (d *Dispatcher) Close() {
+	d.submitMu.Lock()
+	if !d.closed {
+		d.closed = true
+		d.mu.Lock()
+		for _, w := range d.workers {
+			d.retire(w)
+		}
+		d.mu.Unlock()
+		d.workers, d.byName = nil, nil
+	}
+	d.submitMu.Unlock()
+	d.wg.Wait()
+}
+
+// Add or remove workers from the end. Called under submitMu.
+func (d *Dispatcher) resize(n int) {
+	d.ring.Update(func(tx *consistent.Tx) error {
+		for i := len(d.workers); i < n; i++ {
+			w := &worker{name: fmt.Sprintf("worker-%d", i), tasks: make(chan job, d.buffer)}
+			d.workers = append(d.workers, w)
+			d.byName[w.name] = w
+			d.wg.Add(1)
+			go d.run(w)
+			tx.Add(w.name)
+		}
+		d.mu.Lock()
+		for len(d.workers) > n {
+			w := d.workers[len(d.workers)-1]
+			d.workers = d.workers[:len(d.workers)-1]
+			delete(d.byName, w.name)
+			d.retire(w)
+			tx.Remove(w.name)
+		}
+		d.mu.Unlock()
+		return nil
+	})
+}
+
+// Stop a worker once its queue is empty. Called under mu.
+func (d *Dispatcher) retire(w *worker) {
+	w.retired = true
+	if w.queued == 0 {
+		close(w.tasks)
+	}
+}
+
+func (d *Dispatcher) run(w *worker) {
+	defer d.wg.Done()
+	for j := range w.tasks {
+		if j.after != nil {
+			<-j.after
+		}
+		d.handle(j.key, j.task)
+
+		d.mu.Lock()
+		if j.state.queued--; j.state.queued == 0 {
+			if j.state.drained != nil {
+				close(j.state.drained)
+			}
+			if d.keys[j.key] == j.state {
+				delete(d.keys, j.key)
+			}
+		}
+		if w.queued--; w.queued == 0 && w.retired {
+			close(w.tasks)
+		}
+		d.mu.Unlock()
+	}
+}
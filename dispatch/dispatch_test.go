@@ -0,0 +1,132 @@
+package dispatch
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// Tasks numbered per key run in order, once each and never two of a key at
+// a time, while concurrent submitters race repeated resizes that move keys
+// between workers with tasks still queued.
+func TestOrderAcrossResize(t *testing.T) {
+	const keys, per = 50, 300
+	var mu sync.Mutex
+	last := make(map[string]int)
+	running := make(map[string]bool)
+	var errs []string
+	d := New(2, 4, func(key string, task any) {
+		mu.Lock()
+		if running[key] {
+			errs = append(errs, key+" ran twice at once")
+		}
+		running[key] = true
+		if n := task.(int); n != last[key]+1 {
+			errs = append(errs, fmt.Sprintf("%s: task %d after %d", key, n, last[key]))
+		}
+		last[key] = task.(int)
+		mu.Unlock()
+
+		runtime.Gosched() // Let queues fill so moved keys leave tasks behind
+		mu.Lock()
+		running[key] = false
+		mu.Unlock()
+	})
+
+	owners := func() map[string]string {
+		m := make(map[string]string, keys)
+		for k := 0; k < keys; k++ {
+			key := fmt.Sprint("k", k)
+			m[key] = d.ring.Get(key)
+		}
+		return m
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 5; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= per; i++ {
+				for k := g; k < keys; k += 5 {
+					if err := d.Submit(fmt.Sprint("k", k), i); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	moved := 0
+	for round := 0; round < 20; round++ {
+		for _, n := range []int{5, 1, 8, 3, 4} {
+			before := owners()
+			if err := d.Resize(n); err != nil {
+				t.Fatal(err)
+			}
+			for key, owner := range owners() {
+				if owner != before[key] {
+					moved++
+				}
+			}
+		}
+	}
+	wg.Wait()
+	d.Close()
+
+	if len(errs) > 0 {
+		t.Fatal(errs[:min(5, len(errs))])
+	}
+	if moved == 0 {
+		t.Fatal("no resize moved a key")
+	}
+	for k := 0; k < keys; k++ {
+		if got := last[fmt.Sprint("k", k)]; got != per {
+			t.Fatalf("k%d ran up to task %d, want %d", k, got, per)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	d := New(0, 1, func(string, any) {})
+	if err := d.Submit("a", 1); !errors.Is(err, ErrNoWorkers) {
+		t.Fatalf("no workers: %v", err)
+	}
+	if err := d.Resize(-1); err == nil {
+		t.Fatal("negative size accepted")
+	}
+	if err := d.Resize(3); err != nil || d.Workers() != 3 {
+		t.Fatalf("resize: %v, %d workers", err, d.Workers())
+	}
+	d.Close()
+	d.Close()
+	if err := d.Submit("a", 1); !errors.Is(err, ErrClosed) {
+		t.Fatalf("submit after close: %v", err)
+	}
+	if err := d.Resize(2); !errors.Is(err, ErrClosed) {
+		t.Fatalf("resize after close: %v", err)
+	}
+}
+
+// Close waits for every queued task, including those on removed workers.
+func TestCloseDrains(t *testing.T) {
+	var mu sync.Mutex
+	ran := 0
+	release := make(chan struct{})
+	d := New(4, 100, func(string, any) {
+		<-release
+		mu.Lock()
+		ran++
+		mu.Unlock()
+	})
+	for i := 0; i < 200; i++ {
+		d.Submit(fmt.Sprint(i), i)
+	}
+	d.Resize(1)
+	close(release)
+	d.Close()
+	if ran != 200 {
+		t.Fatalf("%d tasks ran before Close returned, want 200", ran)
+	}
+}
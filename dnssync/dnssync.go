@@ -0,0 +1,213 @@
+// Package dnssync keeps the membership of a consistent hash in line with the
+// records of a DNS name, such as a headless Kubernetes service.
+package dnssync
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// Resolver is the part of *net.Resolver a Syncer uses, so tests can provide
+// their own.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// Reconciliation reports one poll of a Syncer.
+type Reconciliation struct {
+	Added   int
+	Removed int
+	Members int   // The number of members after the poll
+	Err     error // Set if the name could not be resolved or the ring refused the change, which is then not made
+}
+
+// Syncer polls a DNS name and makes the members of a hash match its records.
+type Syncer struct {
+	resolver Resolver
+	name     string
+	interval time.Duration
+	ring     *consistent.Consistent
+
+	service, proto string // Look up SRV records instead of addresses if set
+	jitter         float64
+	emptyThreshold int
+	observer       func(Reconciliation)
+
+	empties int // Consecutive empty results so far
+}
+
+type Option func(*Syncer)
+
+// Look up the SRV records of _service._proto.name instead of the addresses
+// of name. Members are then named target:port.
+func WithSRV(service, proto string) Option {
+	return func(s *Syncer) {
+		s.service, s.proto = service, proto
+	}
+}
+
+// Vary each poll interval randomly by up to the given fraction of it, so
+// many syncers don't poll in step. The default is 0.1.
+func WithJitter(fraction float64) Option {
+	return func(s *Syncer) {
+		if fraction >= 0 && fraction < 1 {
+			s.jitter = fraction
+		}
+	}
+}
+
+// Only remove every member after n consecutive polls found no records, so a
+// flaky resolver can't empty the ring. The default is 3.
+func WithEmptyThreshold(n int) Option {
+	return func(s *Syncer) {
+		if n > 0 {
+			s.emptyThreshold = n
+		}
+	}
+}
+
+// Call fn after every poll.
+func WithObserver(fn func(Reconciliation)) Option {
+	return func(s *Syncer) {
+		s.observer = fn
+	}
+}
+
+// Create a syncer resolving name every interval into ring. A nil resolver
+// uses net.DefaultResolver.
+func New(resolver Resolver, name string, interval time.Duration, ring *consistent.Consistent, opts ...Option) *Syncer {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	s := &Syncer{
+		resolver:       resolver,
+		name:           name,
+		interval:       interval,
+		ring:           ring,
+		jitter:         0.1,
+		emptyThreshold: 3,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Poll at once and then every interval until ctx is done, returning its
+// error. Run must not be called concurrently with itself or Sync.
+func (s *Syncer) Run(ctx context.Context) error {
+	for {
+		s.Sync(ctx)
+
+		t := time.NewTimer(s.next())
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Syncer) next() time.Duration {
+	if s.jitter == 0 {
+		return s.interval
+	}
+	return time.Duration(float64(s.interval) * (1 + s.jitter*(2*rand.Float64()-1)))
+}
+
+// Resolve the name once and apply the difference to the ring.
+func (s *Syncer) Sync(ctx context.Context) Reconciliation {
+	rec := s.sync(ctx)
+	if s.observer != nil {
+		s.observer(rec)
+	}
+	return rec
+}
+
+func (s *Syncer) sync(ctx context.Context) Reconciliation {
+	want, err := s.resolve(ctx)
+	if err != nil {
+		return Reconciliation{Members: len(s.ring.Members()), Err: err}
+	}
+
+	if len(want) == 0 {
+		s.empties++
+		if s.empties < s.emptyThreshold {
+			return Reconciliation{Members: len(s.ring.Members())}
+		}
+	} else {
+		s.empties = 0
+	}
+
+	var rec Reconciliation
+	err = s.ring.Update(func(tx *consistent.Tx) error {
+		rec = Reconciliation{}
+		have := make(map[string]bool)
+		for _, key := range tx.Members() {
+			have[key] = true
+		}
+		for _, key := range want {
+			if !have[key] {
+				tx.Add(key)
+				rec.Added++
+			}
+			delete(have, key)
+		}
+		for key := range have {
+			tx.Remove(key)
+			rec.Removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return Reconciliation{Members: len(s.ring.Members()), Err: err}
+	}
+	rec.Members = len(s.ring.Members())
+	return rec
+}
+
+// Get the members the records name, sorted and without duplicates. A name
+// that doesn't exist has no members rather than an error.
+func (s *Syncer) resolve(ctx context.Context) ([]string, error) {
+	var members []string
+	if s.service != "" || s.proto != "" {
+		_, srvs, err := s.resolver.LookupSRV(ctx, s.service, s.proto, s.name)
+		if err != nil && !notFound(err) {
+			return nil, err
+		}
+		for _, srv := range srvs {
+			target := strings.TrimSuffix(srv.Target, ".")
+			members = append(members, net.JoinHostPort(target, strconv.Itoa(int(srv.Port))))
+		}
+	} else {
+		addrs, err := s.resolver.LookupHost(ctx, s.name)
+		if err != nil && !notFound(err) {
+			return nil, err
+		}
+		members = addrs
+	}
+
+	sort.Strings(members)
+	out := members[:0]
+	for i, key := range members {
+		if i == 0 || key != members[i-1] {
+			out = append(out, key)
+		}
+	}
+	return out, nil
+}
+
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
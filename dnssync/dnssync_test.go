@@ -0,0 +1,103 @@
+package dnssync
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// A resolver answering from its fields.
+type fakeResolver struct {
+	hosts []string
+	err   error
+}
+
+func (f *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return f.hosts, f.err
+}
+
+func (f *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	var srvs []*net.SRV
+	for _, h := range f.hosts {
+		srvs = append(srvs, &net.SRV{Target: h + ".", Port: 80})
+	}
+	return "", srvs, f.err
+}
+
+func TestSyncAppliesRecords(t *testing.T) {
+	f := &fakeResolver{hosts: []string{"10.0.0.1", "10.0.0.2", "10.0.0.1"}}
+	ring := consistent.New(nil)
+	var recs []Reconciliation
+	s := New(f, "svc", 0, ring, WithEmptyThreshold(2), WithObserver(func(r Reconciliation) { recs = append(recs, r) }))
+	ctx := context.Background()
+
+	if r := s.Sync(ctx); r != (Reconciliation{Added: 2, Members: 2}) {
+		t.Fatalf("first poll %+v", r)
+	}
+	f.hosts = []string{"10.0.0.2", "10.0.0.3"}
+	if r := s.Sync(ctx); r != (Reconciliation{Added: 1, Removed: 1, Members: 2}) {
+		t.Fatalf("change %+v", r)
+	}
+	if got := ring.Members(); !slices.Equal(got, f.hosts) {
+		t.Fatalf("members %v, records %v", got, f.hosts)
+	}
+
+	// Failures leave the ring alone
+	f.err = errors.New("boom")
+	if r := s.Sync(ctx); r.Err != f.err || r.Added+r.Removed != 0 || r.Members != 2 {
+		t.Fatalf("failed poll %+v", r)
+	}
+
+	// A missing name empties the ring only after the threshold
+	f.err, f.hosts = &net.DNSError{IsNotFound: true}, nil
+	if r := s.Sync(ctx); r != (Reconciliation{Members: 2}) {
+		t.Fatalf("first empty poll %+v", r)
+	}
+	if r := s.Sync(ctx); r != (Reconciliation{Removed: 2}) {
+		t.Fatalf("second empty poll %+v", r)
+	}
+	if len(recs) != 5 {
+		t.Fatalf("%d polls observed of 5", len(recs))
+	}
+
+	f.err, f.hosts = nil, []string{"a", "b"}
+	New(f, "svc", 0, ring, WithSRV("http", "tcp")).Sync(ctx)
+	if got := ring.Members(); !slices.Equal(got, []string{"a:80", "b:80"}) {
+		t.Fatalf("SRV members %v", got)
+	}
+}
+
+func TestSyncReportsRefusedChange(t *testing.T) {
+	f := &fakeResolver{hosts: []string{"a"}}
+	ring := consistent.New(nil)
+	s := New(f, "svc", 0, ring)
+	s.Sync(context.Background())
+
+	token := ring.Freeze()
+	f.hosts = []string{"b", "c"}
+	r := s.Sync(context.Background())
+	if !errors.Is(r.Err, consistent.ErrFrozen) || r.Added != 0 || r.Removed != 0 || r.Members != 1 {
+		t.Fatalf("frozen poll %+v", r)
+	}
+	ring.Thaw(token)
+	if r := s.Sync(context.Background()); r != (Reconciliation{Added: 2, Removed: 1, Members: 2}) {
+		t.Fatalf("poll after thawing %+v", r)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	polls := 0
+	s := New(&fakeResolver{hosts: []string{"a"}}, "svc", 0, consistent.New(nil), WithObserver(func(Reconciliation) {
+		if polls++; polls == 3 {
+			cancel()
+		}
+	}))
+	if err := s.Run(ctx); err != context.Canceled || polls < 3 {
+		t.Fatalf("Run returned %v after %d polls", err, polls)
+	}
+}
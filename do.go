@@ -0,0 +1,66 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DoOption configures Do.
+type DoOption func(*doing)
+
+// Try unhealthy items and standbys too, each using an attempt. By default
+// they are skipped without using one.
+func DoIncludeUnhealthy() DoOption {
+	return func(d *doing) {
+		d.unhealthy = true
+	}
+}
+
+// Skip the provided items without using an attempt.
+func DoExcluding(nodes ...string) DoOption {
+	return func(d *doing) {
+		for _, node := range nodes {
+			d.excluded[node] = true
+		}
+	}
+}
+
+type doing struct {
+	unhealthy bool
+	excluded  map[string]bool
+}
+
+// Call fn with the items for a key in NextN order, one attempt each, until
+// it returns nil, trying at most attempts items. The items are chosen before
+// the first call. If every attempt fails, the error joins each item's error,
+// prefixed by its name; if ctx is done between attempts, it also wraps the
+// context's error. Returns ErrEmpty if there is no item to try. An attempts
+// below 1 is taken as 1.
+func (m *Consistent) Do(ctx context.Context, key string, attempts int, fn func(ctx context.Context, node string) error, opts ...DoOption) error {
+	d := doing{excluded: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	// nextNExcluding holds the read lock while calling this
+	nodes := m.nextNExcluding(key, max(attempts, 1), func(node string) bool {
+		return d.excluded[node] || !d.unhealthy && !m.ring.nodes[node].eligible()
+	})
+	if len(nodes) == 0 {
+		return ErrEmpty
+	}
+
+	var errs []error
+	for _, node := range nodes {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		err := fn(ctx, node)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", node, err))
+	}
+	return errors.Join(errs...)
+}
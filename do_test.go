@@ -0,0 +1,100 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Call Do for k and return the items it tried; fn succeeds on attempt okAt.
+func doTried(t *testing.T, m *Consistent, attempts, okAt int, opts ...DoOption) ([]string, error) {
+	t.Helper()
+	var tried []string
+	err := m.Do(context.Background(), "k", attempts, func(ctx context.Context, node string) error {
+		tried = append(tried, node)
+		if len(tried) == okAt {
+			return nil
+		}
+		return fmt.Errorf("attempt %d failed", len(tried))
+	}, opts...)
+	return tried, err
+}
+
+func TestDo(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	order := m.NextN("k", 5)
+
+	if tried, err := doTried(t, m, 3, 1); err != nil || !slices.Equal(tried, order[:1]) {
+		t.Errorf("first item succeeds: tried %v, %v", tried, err)
+	}
+	if tried, err := doTried(t, m, 3, 3); err != nil || !slices.Equal(tried, order[:3]) {
+		t.Errorf("third item succeeds: tried %v, %v", tried, err)
+	}
+	if tried, err := doTried(t, m, 0, 99); err == nil || !slices.Equal(tried, order[:1]) {
+		t.Errorf("zero attempts: tried %v, %v", tried, err)
+	}
+	if tried, err := doTried(t, m, 9, 99); err == nil || !slices.Equal(tried, order) {
+		t.Errorf("more attempts than items: tried %v, %v", tried, err)
+	}
+
+	tried, err := doTried(t, m, 3, 99)
+	if err == nil || !slices.Equal(tried, order[:3]) {
+		t.Fatalf("every attempt fails: tried %v, %v", tried, err)
+	}
+	for i, node := range order[:3] {
+		if want := fmt.Sprintf("%s: attempt %d failed", node, i+1); !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not have %q", err, want)
+		}
+	}
+}
+
+func TestDoSkips(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	order := m.NextN("k", 5)
+	m.SetHealthy(order[1], false)
+
+	if tried, _ := doTried(t, m, 3, 99); !slices.Equal(tried, []string{order[0], order[2], order[3]}) {
+		t.Errorf("unhealthy %s: tried %v", order[1], tried)
+	}
+	if tried, _ := doTried(t, m, 3, 99, DoIncludeUnhealthy()); !slices.Equal(tried, order[:3]) {
+		t.Errorf("including unhealthy: tried %v", tried)
+	}
+	if tried, _ := doTried(t, m, 3, 99, DoIncludeUnhealthy(), DoExcluding(order[0], order[2])); !slices.Equal(tried, []string{order[1], order[3], order[4]}) {
+		t.Errorf("excluding %s and %s: tried %v", order[0], order[2], tried)
+	}
+	if tried, err := doTried(t, m, 3, 99, DoExcluding(order...)); !errors.Is(err, ErrEmpty) || len(tried) != 0 {
+		t.Errorf("everything excluded: tried %v, %v", tried, err)
+	}
+	if _, err := doTried(t, New(nil), 3, 1); !errors.Is(err, ErrEmpty) {
+		t.Errorf("empty hash: %v", err)
+	}
+}
+
+func TestDoCanceled(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	errFailed := errors.New("failed")
+	ctx, cancel := context.WithCancel(context.Background())
+	var tried []string
+	err := m.Do(ctx, "k", 4, func(ctx context.Context, node string) error {
+		tried = append(tried, node)
+		if len(tried) == 2 {
+			cancel()
+		}
+		return errFailed
+	})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errFailed) || len(tried) != 2 {
+		t.Errorf("canceled after 2 attempts: tried %v, %v", tried, err)
+	}
+}
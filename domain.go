@@ -0,0 +1,28 @@
+package consistent
+
+// The prefixes hashed before item names and lookup keys with
+// WithDomainSeparation.
+const (
+	nodeDomain = "node\x00"
+	keyDomain  = "key\x00"
+)
+
+// Hash item names and lookup keys with different prefixes, so a key equal
+// to an item's name does not land on the item's point, and keys cannot be
+// chosen to sit next to points. This moves every point and key; Load reads
+// it from the file.
+func WithDomainSeparation() Option {
+	return func(m *Consistent) {
+		m.domains = true
+	}
+}
+
+// Hash data after a domain prefix, in a pooled buffer.
+func (m *Consistent) hashIn(domain string, data []byte) int {
+	bp := partsPool.Get().(*[]byte)
+	b := append(append((*bp)[:0], domain...), data...)
+	hash := int(m.sum(b))
+	*bp = b
+	partsPool.Put(bp)
+	return hash
+}
@@ -0,0 +1,70 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestDomainSeparationHashes(t *testing.T) {
+	var last string
+	m := New(recordingHash(&last), WithReplicas(3), WithDomainSeparation())
+	if h := m.Hash("k"); last != keyDomain+"k" || h != int(fnv32([]byte(keyDomain+"k"))) {
+		t.Errorf("Hash(k) hashed %q to %d", last, h)
+	}
+
+	m.Add("a")
+	var want []int
+	for _, name := range []string{"a", "a#1", "a#2"} {
+		want = append(want, int(fnv32([]byte(nodeDomain+name))))
+	}
+	slices.Sort(want)
+	if got, _ := m.PositionsOf("a"); !slices.Equal(got, want) {
+		t.Errorf("points of a at %v, want %v", got, want)
+	}
+
+	f := New(recordingHash(&last), WithReplicas(2), WithDomainSeparation(), WithReplicaFormatter(func(node string, replica int) []byte {
+		return fmt.Appendf(nil, "%d-%s", replica, node)
+	}))
+	f.Add("b")
+	if last != nodeDomain+"1-b" {
+		t.Errorf("formatted replica hashed as %q", last)
+	}
+}
+
+// Without separation a key equal to an item's name lands on the item's
+// first point; with it, the key and the name hash apart.
+func TestDomainSeparationKeyNamedAfterItem(t *testing.T) {
+	plain := New(nil, WithReplicas(3), WithNamedHash("fnv1a32", 0))
+	separated := New(nil, WithReplicas(3), WithNamedHash("fnv1a32", 0), WithDomainSeparation())
+	for i := 0; i < 20; i++ {
+		plain.Add(fmt.Sprint("n", i))
+		separated.Add(fmt.Sprint("n", i))
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprint("n", i)
+		if positions, _ := plain.PositionsOf(name); !slices.Contains(positions, plain.Hash(name)) {
+			t.Errorf("without separation %s misses its point", name)
+		}
+		if positions, _ := separated.PositionsOf(name); slices.Contains(positions, separated.Hash(name)) {
+			t.Errorf("with separation %s lands on its point", name)
+		}
+	}
+	if err := separated.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg := separated.Config()
+	if !cfg.DomainSeparation {
+		t.Fatal("config lost the domain separation")
+	}
+	c, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range testKeys(500) {
+		if c.Get(key) != separated.Get(key) {
+			t.Fatalf("%s routes apart after a config round trip", key)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package consistent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TimerClock is a Clock that also schedules waits, such as a fake clock in
+// tests. DrainOver waits on the clock given to WithClock if it is a
+// TimerClock, and on the system clock otherwise.
+type TimerClock interface {
+	Clock
+	After(d time.Duration) <-chan time.Time
+}
+
+// DrainOption configures DrainOver.
+type DrainOption func(*draining)
+
+// Remove the item once its weight reaches zero.
+func DrainRemove() DrainOption {
+	return func(d *draining) {
+		d.remove = true
+	}
+}
+
+type draining struct {
+	remove bool
+}
+
+// Lower the weight of an item from its current weight to zero in steps
+// equal steps, one every d/steps, each through SetWeight so only the keys
+// of the points dropped move and an EventWeight reports it. A steps below
+// 1 is taken as 1. If ctx is done first the ramp stops, leaving the weight
+// reached, and the returned error wraps the context's error and names the
+// weight.
+func (m *Consistent) DrainOver(ctx context.Context, node string, d time.Duration, steps int, opts ...DrainOption) error {
+	var o draining
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if steps < 1 {
+		steps = 1
+	}
+
+	start, ok := m.Weight(node)
+	if !ok {
+		return ErrNodeNotFound
+	}
+	weight := start
+	for i := 1; i <= steps; i++ {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("consistent: drain of %q stopped at weight %d: %w", node, weight, ctx.Err())
+		case <-m.after(d / time.Duration(steps)):
+		}
+		weight = start * (steps - i) / steps
+		if err := m.SetWeight(node, weight); err != nil {
+			return err
+		}
+	}
+
+	if o.remove {
+		_, err := m.RemoveDetailed(node)
+		return err
+	}
+	return nil
+}
+
+func (m *Consistent) after(d time.Duration) <-chan time.Time {
+	if c, ok := m.clock.(TimerClock); ok {
+		return c.After(d)
+	}
+	return time.After(d)
+}
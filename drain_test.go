@@ -0,0 +1,136 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A TimerClock whose waits fire when the test advances it. Each call to
+// After is announced on waits, so a test knows a wait has started.
+type timerClock struct {
+	*testClock
+	mu      sync.Mutex
+	waiters []timerWaiter
+	waits   chan time.Duration
+}
+
+type timerWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newTimerClock() *timerClock {
+	return &timerClock{testClock: newTestClock(), waits: make(chan time.Duration)}
+}
+
+func (c *timerClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.mu.Lock()
+	c.waiters = append(c.waiters, timerWaiter{at: c.Now().Add(d), ch: ch})
+	c.mu.Unlock()
+	c.waits <- d
+	return ch
+}
+
+// Move the clock on, firing the waits that are due.
+func (c *timerClock) advance(d time.Duration) {
+	c.testClock.advance(d)
+	now := c.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiters := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(now) {
+			waiters = append(waiters, w)
+		} else {
+			w.ch <- now
+		}
+	}
+	c.waiters = waiters
+}
+
+// Wait for the next wait to start and let it pass.
+func (c *timerClock) step(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		c.advance(d)
+		return d
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing waits on the clock")
+		return 0
+	}
+}
+
+func TestDrainOver(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock))
+	m.AddWithWeight("a", 40)
+	m.AddWithWeight("b", 40)
+	var weights []int
+	m.Watch(func(e Event) {
+		if e.Type == EventWeight {
+			w, _ := m.Weight("a")
+			weights = append(weights, w)
+		}
+	})
+
+	done := make(chan error)
+	go func() { done <- m.DrainOver(context.Background(), "a", 4*time.Minute, 4, DrainRemove()) }()
+	for i := 0; i < 4; i++ {
+		if d := clock.step(t); d != time.Minute {
+			t.Fatalf("step %d waited %v, want a minute", i, d)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{30, 20, 10, 0}; len(weights) != len(want) || weights[0] != 30 || weights[3] != 0 {
+		t.Fatalf("weights %v, want %v", weights, want)
+	}
+	if _, ok := m.Weight("a"); ok {
+		t.Fatal("the drained item was not removed")
+	}
+}
+
+func TestDrainOverCancel(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock))
+	m.AddWithWeight("a", 40)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.DrainOver(ctx, "a", 4*time.Minute, 4) }()
+
+	clock.step(t)
+	<-clock.waits // The second step is waiting
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled drain: %v", err)
+	}
+	if w, ok := m.Weight("a"); !ok || w != 30 {
+		t.Fatalf("weight %d after one step, want 30", w)
+	}
+
+	if err := m.DrainOver(context.Background(), "missing", time.Minute, 1); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("drain of a missing item: %v", err)
+	}
+}
+
+// Removed while draining, the next step reports the item gone.
+func TestDrainOverRemoved(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock))
+	m.AddWithWeight("a", 10)
+	done := make(chan error)
+	go func() { done <- m.DrainOver(context.Background(), "a", time.Minute, 2) }()
+	clock.step(t)
+	d := <-clock.waits
+	m.Remove("a")
+	clock.advance(d)
+	if err := <-done; !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("drain of a removed item: %v", err)
+	}
+}
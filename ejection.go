@@ -0,0 +1,340 @@
+package consistent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTrickle   = 0.1
+	defaultSuccesses = 3
+)
+
+// BreakerState is the state of an item's circuit breaker, see WithEjection.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // Taking its keys
+	BreakerOpen                         // Ejected, marked unhealthy until the cooldown passes
+	BreakerHalfOpen                     // Taking a trickle of keys until enough successes are reported
+)
+
+// Eject an item once threshold failures are reported for it within window:
+// it is marked unhealthy, so Get skips it. After the cooldown it is half-open
+// and Get routes a trickle of its keys to it, see WithHalfOpen; enough
+// reported successes restore it, and a failure ejects it again. The cooldown
+// is checked by lookups and reports, so an ejected item needs neither to be
+// reported on again. Removing an item or calling SetHealthy on it resets its
+// breaker. Ejections
+// and recoveries are EventHealth events, and the start of the trickle is an
+// EventHalfOpen event. A threshold below 1 is ignored.
+func WithEjection(threshold int, window, cooldown time.Duration) Option {
+	return func(m *Consistent) {
+		if threshold < 1 {
+			return
+		}
+		e := m.ejector()
+		e.threshold, e.window, e.cooldown = threshold, window, cooldown
+	}
+}
+
+// Route share of the keys of a half-open item to it, and restore it after
+// successes reported successes. The defaults are 0.1 and 3. A share outside
+// (0, 1] or successes below 1 is ignored.
+func WithHalfOpen(share float64, successes int) Option {
+	return func(m *Consistent) {
+		if share <= 0 || share > 1 || successes < 1 {
+			return
+		}
+		e := m.ejector()
+		e.share, e.successes = share, successes
+	}
+}
+
+func (m *Consistent) ejector() *ejection {
+	if m.ejection == nil {
+		m.ejection = &ejection{
+			share:     defaultTrickle,
+			successes: defaultSuccesses,
+			breakers:  make(map[string]*breaker),
+		}
+	}
+	return m.ejection
+}
+
+// Report a request to an item that succeeded, for WithEjection. Without
+// ejection it does nothing.
+func (m *Consistent) ReportSuccess(node string) error {
+	return m.report(node, true)
+}
+
+// Report a request to an item that failed, for WithEjection. Without
+// ejection it does nothing.
+func (m *Consistent) ReportFailure(node string) error {
+	return m.report(node, false)
+}
+
+// Get the state of an item's circuit breaker. Items without reports, and
+// every item without WithEjection, are closed.
+func (m *Consistent) BreakerState(node string) BreakerState {
+	e := m.ejection
+	if e == nil {
+		return BreakerClosed
+	}
+	m.coolDue()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if b, ok := e.breakers[node]; ok {
+		return b.state
+	}
+	return BreakerClosed
+}
+
+func (m *Consistent) report(node string, ok bool) error {
+	e := m.ejection
+	if e == nil || e.threshold == 0 {
+		return nil
+	}
+
+	// The breakers are changed and applied to the ring under e.mu, so
+	// concurrent reports reach the ring in order; watchers are told after.
+	var events []Event
+	err := func() error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		defer e.schedule()
+		now := m.clock.Now()
+
+		if ev := m.cool(now); ev != nil {
+			events = append(events, *ev)
+		}
+
+		m.RLock()
+		_, present := m.ring.nodes[node]
+		m.RUnlock()
+		if !present {
+			delete(e.breakers, node)
+			return ErrNodeNotFound
+		}
+
+		if state, changed := e.record(node, ok, now); changed {
+			if ev := m.apply(m.setBreakers([]string{node}, state), nil, false); ev != nil {
+				events = append(events, *ev)
+			}
+		}
+		return nil
+	}()
+
+	for _, ev := range events {
+		m.notify(ev)
+	}
+	return err
+}
+
+// Move the breakers whose cooldown has passed to half-open, under e.mu,
+// returning the event for watchers.
+func (m *Consistent) cool(now time.Time) *Event {
+	if cooled := m.ejection.cool(now); len(cooled) > 0 {
+		return m.apply(m.setBreakers(cooled, BreakerHalfOpen), nil, false)
+	}
+	return nil
+}
+
+// Move the breakers whose cooldown has passed to half-open, if any may have,
+// so ejected items come back without further reports. Called by lookups
+// before they take the lock.
+func (m *Consistent) coolDue() {
+	e := m.ejection
+	if e == nil {
+		return
+	}
+	if due := e.due.Load(); due == 0 || m.clock.Now().UnixNano() < due {
+		return
+	}
+
+	e.mu.Lock()
+	ev := m.cool(m.clock.Now())
+	e.schedule()
+	e.mu.Unlock()
+	if ev != nil {
+		m.notify(*ev)
+	}
+}
+
+// Drop the breakers of the items a membership change removed, and move the
+// breaker of a renamed item to its new name.
+func (m *Consistent) forgetBreakers(ev Event) {
+	e := m.ejection
+	if e == nil || len(ev.Removed) == 0 {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, node := range ev.Removed {
+		if b, ok := e.breakers[node]; ok && ev.Type == EventRename {
+			e.breakers[ev.Added[i]] = b
+		}
+		delete(e.breakers, node)
+	}
+	e.schedule()
+}
+
+// Apply a breaker state to the ring, for reroute.
+func (m *Consistent) setBreakers(nodes []string, state BreakerState) func() *Event {
+	return func() *Event {
+		var changed []string
+		for _, node := range nodes {
+			if mem, ok := m.ring.nodes[node]; ok && m.ring.setBreaker(mem, state) {
+				changed = append(changed, node)
+			}
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+		typ := EventHealth
+		if state == BreakerHalfOpen {
+			typ = EventHalfOpen
+		}
+		return &Event{Type: typ, Changed: changed}
+	}
+}
+
+type ejection struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	share     float64
+	successes int
+	breakers  map[string]*breaker
+	due       atomic.Int64 // When the first open breaker cools, in Unix nanoseconds, or 0
+}
+
+type breaker struct {
+	state     BreakerState
+	failures  []time.Time // Within the window, oldest first
+	opened    time.Time
+	successes int // Reported while half-open
+}
+
+// Move the open breakers whose cooldown has passed to half-open, returning
+// their items sorted.
+func (e *ejection) cool(now time.Time) []string {
+	var cooled []string
+	for _, node := range sortedKeys(e.breakers) {
+		b := e.breakers[node]
+		if b.state == BreakerOpen && now.Sub(b.opened) >= e.cooldown {
+			b.state, b.successes = BreakerHalfOpen, 0
+			cooled = append(cooled, node)
+		}
+	}
+	return cooled
+}
+
+// Note when the first open breaker cools, for coolDue.
+func (e *ejection) schedule() {
+	var due int64
+	for _, b := range e.breakers {
+		if b.state != BreakerOpen {
+			continue
+		}
+		if at := b.opened.Add(e.cooldown).UnixNano(); due == 0 || at < due {
+			due = at
+		}
+	}
+	e.due.Store(due)
+}
+
+// Record a report, returning the new state and whether it changed.
+func (e *ejection) record(node string, ok bool, now time.Time) (BreakerState, bool) {
+	b := e.breakers[node]
+	if b == nil {
+		if ok {
+			return BreakerClosed, false
+		}
+		b = &breaker{}
+		e.breakers[node] = b
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		if ok {
+			return b.state, false
+		}
+		i := 0
+		for i < len(b.failures) && now.Sub(b.failures[i]) >= e.window {
+			i++
+		}
+		b.failures = append(b.failures[i:], now)
+		if len(b.failures) < e.threshold {
+			return b.state, false
+		}
+		b.state, b.opened, b.failures = BreakerOpen, now, nil
+	case BreakerHalfOpen:
+		if ok {
+			if b.successes++; b.successes < e.successes {
+				return b.state, false
+			}
+			delete(e.breakers, node)
+			return BreakerClosed, true
+		}
+		b.state, b.opened = BreakerOpen, now
+	default:
+		return b.state, false
+	}
+	return b.state, true
+}
+
+func (m *Consistent) trickle() float64 {
+	if m.ejection == nil {
+		return 0
+	}
+	return m.ejection.share
+}
+
+// Set the health of an item for a breaker state, returning true if it
+// changed.
+func (r *ring) setBreaker(mem *member, state BreakerState) bool {
+	unhealthy, halfOpen := state != BreakerClosed, state == BreakerHalfOpen
+	if mem.unhealthy == unhealthy && mem.halfOpen == halfOpen {
+		return false
+	}
+	r.setHalfOpen(mem, halfOpen)
+	mem.unhealthy = unhealthy
+	return true
+}
+
+func (r *ring) setHalfOpen(mem *member, halfOpen bool) {
+	if mem.halfOpen == halfOpen {
+		return
+	}
+	if mem.halfOpen = halfOpen; halfOpen {
+		r.halfOpen++
+	} else {
+		r.halfOpen--
+	}
+}
+
+// Index of the point serving hash for Get: a half-open item that would
+// serve the hash if it were healthy takes it if the hash falls in the
+// trickle share, and otherwise the keys go where lookup sends them.
+func (r *ring) lookupTrickle(hash int, share float64) int {
+	if r.halfOpen > 0 {
+		trickle := -1
+		r.walkBack(r.prevIndex(hash), func(j int) bool {
+			mem := r.nodes[r.nodeAt(j)]
+			if mem.halfOpen && !mem.standby {
+				trickle = j
+				return false
+			}
+			return !mem.eligible()
+		})
+		// Spread the hash, whose high bits are shared by keys near a point
+		if trickle >= 0 && float64(uint32(hash)*0x9e3779b1) < share*(1<<32) {
+			return trickle
+		}
+	}
+	return r.lookup(hash)
+}
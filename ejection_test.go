@@ -0,0 +1,220 @@
+package consistent
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func ejectionRing(clock Clock) *Consistent {
+	c := New(nil, WithReplicas(50), WithClock(clock),
+		WithEjection(3, time.Second, 10*time.Second), WithHalfOpen(0.2, 2))
+	c.Add("a")
+	c.Add("b")
+	c.Add("c")
+	return c
+}
+
+// Get the item of every key.
+func getAll(c *Consistent, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, k := range keys {
+		owners[k] = c.Get(k)
+	}
+	return owners
+}
+
+func eject(t *testing.T, c *Consistent, node string) {
+	t.Helper()
+	for i := 0; i < 3; i++ {
+		c.ReportFailure(node)
+	}
+	if s := c.BreakerState(node); s != BreakerOpen || c.IsHealthy(node) {
+		t.Fatalf("%s not ejected: %v", node, s)
+	}
+}
+
+// Check only keys of node in base reach it, and return how many do.
+func trickled(t *testing.T, c *Consistent, base map[string]string, node string) int {
+	t.Helper()
+	n := 0
+	for k, owner := range base {
+		got := c.Get(k)
+		if got == node {
+			if owner != node {
+				t.Fatalf("%s reaches %s, owned by %s", k, node, owner)
+			}
+			n++
+		} else if owner != node && got != owner {
+			t.Fatalf("%s moved from %s to %s", k, owner, got)
+		}
+	}
+	return n
+}
+
+func TestEjectHalfOpenRecover(t *testing.T) {
+	clock := newTestClock()
+	c := ejectionRing(clock)
+	var events []EventType
+	c.Watch(func(e Event) { events = append(events, e.Type) })
+	keys := testKeys(10000)
+	base := getAll(c, keys)
+
+	// Failures outside the window do not eject
+	c.ReportFailure("b")
+	c.ReportFailure("b")
+	clock.advance(2 * time.Second)
+	c.ReportFailure("b")
+	if s := c.BreakerState("b"); s != BreakerClosed {
+		t.Fatalf("ejected by failures outside the window: %v", s)
+	}
+	c.ReportFailure("b")
+	c.ReportFailure("b")
+	if s := c.BreakerState("b"); s != BreakerOpen {
+		t.Fatalf("not ejected: %v", s)
+	}
+	if n := trickled(t, c, base, "b"); n != 0 {
+		t.Fatalf("%d keys reach an ejected item", n)
+	}
+
+	clock.advance(9 * time.Second)
+	if n := trickled(t, c, base, "b"); n != 0 || c.BreakerState("b") != BreakerOpen {
+		t.Fatalf("half-open before the cooldown, %d keys", n)
+	}
+
+	// No reports at all: the lookups notice the cooldown has passed
+	clock.advance(time.Second)
+	total := 0
+	for _, owner := range base {
+		if owner == "b" {
+			total++
+		}
+	}
+	n := trickled(t, c, base, "b")
+	if c.BreakerState("b") != BreakerHalfOpen {
+		t.Fatalf("not half-open after the cooldown: %v", c.BreakerState("b"))
+	}
+	if share := float64(n) / float64(total); share < 0.1 || share > 0.3 {
+		t.Fatalf("half-open item takes %d of its %d keys", n, total)
+	}
+
+	c.ReportSuccess("b")
+	if c.BreakerState("b") != BreakerHalfOpen {
+		t.Fatal("recovered after one success")
+	}
+	c.ReportSuccess("b")
+	if s := c.BreakerState("b"); s != BreakerClosed || !c.IsHealthy("b") {
+		t.Fatalf("not recovered: %v", s)
+	}
+	if n := trickled(t, c, base, "b"); n != total {
+		t.Fatalf("%d of %d keys back", n, total)
+	}
+	if want := []EventType{EventHealth, EventHalfOpen, EventHealth}; !slices.Equal(events, want) {
+		t.Fatalf("events %v, want %v", events, want)
+	}
+}
+
+func TestEjectHalfOpenReeject(t *testing.T) {
+	clock := newTestClock()
+	c := ejectionRing(clock)
+	var events []EventType
+	c.Watch(func(e Event) { events = append(events, e.Type) })
+	keys := testKeys(10000)
+	base := getAll(c, keys)
+
+	eject(t, c, "b")
+	clock.advance(10 * time.Second)
+	if n := trickled(t, c, base, "b"); n == 0 || c.BreakerState("b") != BreakerHalfOpen {
+		t.Fatalf("not half-open: %v, %d keys", c.BreakerState("b"), n)
+	}
+
+	c.ReportSuccess("b")
+	c.ReportFailure("b")
+	if s := c.BreakerState("b"); s != BreakerOpen || c.IsHealthy("b") {
+		t.Fatalf("not ejected again: %v", s)
+	}
+	if n := trickled(t, c, base, "b"); n != 0 {
+		t.Fatalf("%d keys reach a re-ejected item", n)
+	}
+
+	// The cooldown starts over from the second ejection
+	clock.advance(9 * time.Second)
+	if c.Get(keys[0]); c.BreakerState("b") != BreakerOpen {
+		t.Fatal("half-open before the second cooldown")
+	}
+	clock.advance(time.Second)
+	if n := trickled(t, c, base, "b"); n == 0 || c.BreakerState("b") != BreakerHalfOpen {
+		t.Fatalf("not half-open again: %v, %d keys", c.BreakerState("b"), n)
+	}
+	want := []EventType{EventHealth, EventHalfOpen, EventHealth, EventHalfOpen}
+	if !slices.Equal(events, want) {
+		t.Fatalf("events %v, want %v", events, want)
+	}
+}
+
+func TestBreakerReset(t *testing.T) {
+	clock := newTestClock()
+	c := ejectionRing(clock)
+	keys := testKeys(5000)
+	base := getAll(c, keys)
+
+	// A removed item comes back closed
+	eject(t, c, "b")
+	c.Remove("b")
+	c.Add("b")
+	if s := c.BreakerState("b"); s != BreakerClosed || !c.IsHealthy("b") {
+		t.Fatalf("re-added item is %v", s)
+	}
+	clock.advance(10 * time.Second)
+	if got := getAll(c, keys); !maps.Equal(got, base) {
+		t.Fatal("re-added item does not take its keys back")
+	}
+	c.ReportFailure("b")
+	c.ReportFailure("b")
+	if s := c.BreakerState("b"); s != BreakerClosed {
+		t.Fatalf("failures before the removal still count: %v", s)
+	}
+
+	// SetHealthy overrides the breaker, which does not reopen after the
+	// cooldown
+	eject(t, c, "c")
+	if err := c.SetHealthy("c", true); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.BreakerState("c"); s != BreakerClosed {
+		t.Fatalf("breaker %v after SetHealthy", s)
+	}
+	clock.advance(10 * time.Second)
+	if got := getAll(c, keys); !maps.Equal(got, base) || c.BreakerState("c") != BreakerClosed {
+		t.Fatalf("SetHealthy item is %v after the cooldown", c.BreakerState("c"))
+	}
+
+	eject(t, c, "c")
+	clock.advance(10 * time.Second)
+	c.Get(keys[0])
+	if err := c.SetHealthy("c", false); err != nil {
+		t.Fatal(err)
+	}
+	c.ReportSuccess("c")
+	c.ReportSuccess("c")
+	if s := c.BreakerState("c"); s != BreakerClosed || c.IsHealthy("c") {
+		t.Fatalf("successes restored an item marked unhealthy: %v", s)
+	}
+
+	// A renamed item keeps its breaker
+	eject(t, c, "a")
+	if err := c.Rename("a", "z"); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.BreakerState("z"); s != BreakerOpen || c.BreakerState("a") != BreakerClosed {
+		t.Fatalf("renamed breaker is %v", s)
+	}
+	clock.advance(10 * time.Second)
+	c.Get(keys[0])
+	c.ReportSuccess("z")
+	c.ReportSuccess("z")
+	if s := c.BreakerState("z"); s != BreakerClosed || !c.IsHealthy("z") {
+		t.Fatalf("renamed item not recovered: %v", s)
+	}
+}
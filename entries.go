@@ -0,0 +1,35 @@
+package consistent
+
+// Entry is one point of the ring.
+type Entry struct {
+	Position uint32 `json:"position"`
+	Node     string `json:"node"`
+	Replica  int    `json:"replica"`
+}
+
+// Get every point of the ring, sorted by position, so another
+// implementation can route like Get: a key goes to the entry with the
+// greatest position at or below Hash(key), or to the last entry if there is
+// none. Get also skips standbys and unhealthy items, moving on to the
+// previous entry, and applies pins first.
+func (m *Consistent) Entries() []Entry {
+	m.RLock()
+	defer m.RUnlock()
+	entries := make([]Entry, m.ring.size())
+	for i := range entries {
+		o := m.ring.owner(i)
+		entries[i] = Entry{Position: uint32(o.Position), Node: o.Node, Replica: o.Replica}
+	}
+	return entries
+}
+
+// Get the positions of every point of the ring, sorted, as Entries does.
+func (m *Consistent) Positions() []uint32 {
+	m.RLock()
+	defer m.RUnlock()
+	positions := make([]uint32, m.ring.size())
+	for i := range positions {
+		positions[i] = uint32(m.ring.key(i))
+	}
+	return positions
+}
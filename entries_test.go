@@ -0,0 +1,72 @@
+package consistent
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// A reimplementation of Get from the documented rule, as another language
+// would write it: the entry with the greatest position at or below the
+// hash, or the last entry, then back past items that serve no keys.
+func referenceGet(entries []Entry, serving map[string]bool, hash uint32) string {
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Position > hash }) - 1
+	for n := 0; n < len(entries); n++ {
+		e := entries[(i-n+2*len(entries))%len(entries)]
+		if serving[e.Node] {
+			return e.Node
+		}
+	}
+	return ""
+}
+
+func TestEntriesReproduceGet(t *testing.T) {
+	m := New(nil, WithReplicas(17))
+	for i := 0; i < 7; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	m.AddWithWeight("heavy", 60)
+	m.AddStandby("standby")
+	m.SetHealthy("n3", false)
+	serving := make(map[string]bool)
+	for _, node := range m.Members() {
+		serving[node] = node != "standby" && node != "n3"
+	}
+
+	entries := m.Entries()
+	positions := m.Positions()
+	if len(entries) != 8*17+60 || len(positions) != len(entries) {
+		t.Fatalf("%d entries and %d positions, want %d", len(entries), len(positions), 8*17+60)
+	}
+	replicas := make(map[string]map[int]bool)
+	for i, e := range entries {
+		if e.Position != positions[i] {
+			t.Fatalf("entry %d at %d, position %d", i, e.Position, positions[i])
+		}
+		if i > 0 && entries[i-1].Position >= e.Position {
+			t.Fatalf("entry %d at %d follows %d", i, e.Position, entries[i-1].Position)
+		}
+		if replicas[e.Node] == nil {
+			replicas[e.Node] = make(map[int]bool)
+		}
+		replicas[e.Node][e.Replica] = true
+	}
+	for node, seen := range replicas {
+		for r := range seen {
+			if r < 0 || r >= len(seen) {
+				t.Fatalf("%s has replica %d of %d", node, r, len(seen))
+			}
+		}
+	}
+
+	for _, key := range testKeys(20000) {
+		if got, want := referenceGet(entries, serving, uint32(m.Hash(key))), m.Get(key); got != want {
+			t.Fatalf("%s: the reference routes to %q, Get to %q", key, got, want)
+		}
+	}
+
+	entries[0].Node = "changed"
+	if m.Entries()[0].Node == "changed" {
+		t.Fatal("changing the entries changed the hash")
+	}
+}
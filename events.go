@@ -0,0 +1,77 @@
+package consistent
+
+type EventType int
+
+const (
+	EventAdd EventType = iota
+	EventRemove
+	EventRename
+	EventWeight
+	EventUpdate
+	EventStandby  // A standby was added
+	EventPromote  // A standby became a full member
+	EventHealth   // Items were marked healthy or unhealthy
+	EventPin      // A key was pinned or unpinned, Changed lists the key
+	EventReadOnly // Items were marked read-only or writable
+	EventRebuild  // Every point was placed again with a new hash function
+	EventHalfOpen // Ejected items started taking a trickle of keys, see WithEjection
+	EventRestore  // The ring was replaced by a snapshot, see Restore
+	EventRangePin // A hash range was pinned or unpinned, Changed lists its item
+	EventZone     // An item's zone label changed, see SetZone
+)
+
+// Returns true if the event changed lookups but not the membership, so the
+// generation does not move.
+func (t EventType) routingOnly() bool {
+	return t == EventHealth || t == EventReadOnly || t == EventHalfOpen
+}
+
+// Returns true if the event's changes can be replayed by ApplyDelta.
+func (t EventType) replayable() bool {
+	return t != EventRebuild && t != EventRestore
+}
+
+// Event describes a change to the membership of a hash. A rename lists the
+// old name as removed and the new name as added. Changed lists items whose
+// weight, role or zone changed.
+type Event struct {
+	Type       EventType
+	Generation uint64
+	Added      []string
+	Removed    []string
+	Changed    []string
+}
+
+// Call fn after every change to the membership. Watchers run outside the
+// hash's lock once the change is visible, so they may use the hash; events
+// from concurrent changes can arrive in any order, use Generation to order
+// them. The returned function stops the watcher.
+func (m *Consistent) Watch(fn func(Event)) (cancel func()) {
+	m.watchMu.Lock()
+	defer m.watchMu.Unlock()
+	if m.watchers == nil {
+		m.watchers = make(map[int]func(Event))
+	}
+	id := m.nextWatcher
+	m.nextWatcher++
+	m.watchers[id] = fn
+
+	return func() {
+		m.watchMu.Lock()
+		defer m.watchMu.Unlock()
+		delete(m.watchers, id)
+	}
+}
+
+func (m *Consistent) notify(e Event) {
+	m.watchMu.Lock()
+	watchers := make([]func(Event), 0, len(m.watchers))
+	for _, fn := range m.watchers {
+		watchers = append(watchers, fn)
+	}
+	m.watchMu.Unlock()
+
+	for _, fn := range watchers {
+		fn(e)
+	}
+}
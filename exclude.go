@@ -0,0 +1,72 @@
+package consistent
+
+// Get the first item in NextN order for the provided key that is not in
+// exclude, such as the next item to retry after the owner failed. Returns
+// false if every item is excluded. Exclusions apply to this call only, on
+// top of health and standby state.
+func (m *Consistent) GetExcluding(key string, exclude map[string]bool) (string, bool) {
+	nodes := m.nextNExcluding(key, 1, m.orIneligible(func(node string) bool { return exclude[node] }))
+	if len(nodes) == 0 {
+		return "", false
+	}
+	return nodes[0], true
+}
+
+// Get up to n distinct items for the provided key in NextN order, skipping
+// the excluded items and all of their points, and standby and unhealthy
+// items.
+func (m *Consistent) GetNExcluding(key string, n int, exclude ...string) []string {
+	switch len(exclude) {
+	case 0:
+		return m.nextNExcluding(key, n, m.orIneligible(func(string) bool { return false }))
+	case 1:
+		return m.nextNExcluding(key, n, m.orIneligible(func(node string) bool { return node == exclude[0] }))
+	}
+	set := make(map[string]bool, len(exclude))
+	for _, node := range exclude {
+		set[node] = true
+	}
+	return m.nextNExcluding(key, n, m.orIneligible(func(node string) bool { return set[node] }))
+}
+
+// Exclude standby and unhealthy items as well. nextNExcluding calls the
+// result under the read lock.
+func (m *Consistent) orIneligible(excluded func(node string) bool) func(node string) bool {
+	return func(node string) bool {
+		return !m.ring.nodes[node].eligible() || excluded(node)
+	}
+}
+
+func (m *Consistent) nextNExcluding(key string, n int, excluded func(node string) bool) []string {
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 || n <= 0 {
+		return nil
+	}
+
+	var nodes []string
+	pinned, ok := m.pinned(key)
+	if ok {
+		if pinned == "" {
+			return nil
+		}
+		if !excluded(pinned) {
+			nodes = append(nodes, pinned)
+			if n == 1 {
+				return nodes
+			}
+		}
+	}
+	m.candidates(hash, func(i int) bool {
+		if node := m.ring.nodeAt(i); node != pinned && !excluded(node) {
+			nodes = append(nodes, node)
+		}
+		return len(nodes) < n
+	})
+
+	return nodes
+}
@@ -0,0 +1,43 @@
+package consistent
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExcludingSkipsIneligible(t *testing.T) {
+	c := New(nil, WithReplicas(10))
+	for _, node := range []string{"a", "b", "c", "d", "n1"} {
+		c.Add(node)
+	}
+	c.AddStandby("s")
+	c.SetHealthy("n1", false)
+
+	for _, k := range testKeys(1000) {
+		all := c.NextN(k, 6)
+		var eligible []string
+		for _, node := range all {
+			if node != "s" && node != "n1" {
+				eligible = append(eligible, node)
+			}
+		}
+		if eligible[0] != c.Get(k) {
+			t.Fatalf("%s: NextN %v, Get %s", k, all, c.Get(k))
+		}
+
+		// The retry after the owner failed
+		got, ok := c.GetExcluding(k, map[string]bool{eligible[0]: true})
+		if !ok || got != eligible[1] {
+			t.Fatalf("%s: retry on %s, want %s of %v", k, got, eligible[1], all)
+		}
+		if rest := c.GetNExcluding(k, 6, eligible[0], eligible[2]); !slices.Equal(rest, []string{eligible[1], eligible[3]}) {
+			t.Fatalf("%s: %v, want %v", k, rest, []string{eligible[1], eligible[3]})
+		}
+		if rest := c.GetNExcluding(k, 6); !slices.Equal(rest, eligible) {
+			t.Fatalf("%s: nothing excluded gives %v, want %v", k, rest, eligible)
+		}
+		if node, ok := c.GetExcluding(k, map[string]bool{"a": true, "b": true, "c": true, "d": true}); ok {
+			t.Fatalf("%s: %s with every eligible item excluded", k, node)
+		}
+	}
+}
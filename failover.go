@@ -0,0 +1,40 @@
+package consistent
+
+// Failover looks keys up in an ordered list of hashes, such as a primary
+// cluster and a disaster recovery cluster, using the first that has a
+// healthy item for the key.
+type Failover struct {
+	tiers []*Consistent
+}
+
+func NewFailover(tiers ...*Consistent) *Failover {
+	return &Failover{tiers: tiers}
+}
+
+// Get the item for the provided key from the first hash able to serve it.
+// Unhealthy items are skipped within a hash before falling back to the next.
+func (f *Failover) Get(key string) string {
+	node, _ := f.get(key)
+	return node
+}
+
+// Returns the index of the hash that serves the provided key, or -1 if none
+// can.
+func (f *Failover) Source(key string) int {
+	_, tier := f.get(key)
+	return tier
+}
+
+// Returns the members of the hash at the given index.
+func (f *Failover) Members(tier int) []string {
+	return f.tiers[tier].Members()
+}
+
+func (f *Failover) get(key string) (string, int) {
+	for i, m := range f.tiers {
+		if o, ok := m.GetOwner(key); ok {
+			return o.Node, i
+		}
+	}
+	return "", -1
+}
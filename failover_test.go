@@ -0,0 +1,95 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestFailover(t *testing.T) {
+	primary, secondary := New(nil, WithReplicas(10)), New(nil, WithReplicas(10))
+	f := NewFailover(primary, secondary)
+	keys := testKeys(1000)
+	check := func(name string, want func(key string) (string, int)) {
+		t.Helper()
+		for _, key := range keys {
+			node, tier := want(key)
+			if got := f.Get(key); got != node {
+				t.Fatalf("%s: %s went to %q, want %q", name, key, got, node)
+			}
+			if got := f.Source(key); got != tier {
+				t.Fatalf("%s: %s served by hash %d, want %d", name, key, got, tier)
+			}
+		}
+	}
+
+	check("both empty", func(string) (string, int) { return "", -1 })
+
+	secondary.Add("s1")
+	secondary.Add("s2")
+	check("empty primary", func(key string) (string, int) { return secondary.Get(key), 1 })
+
+	for i := 0; i < 3; i++ {
+		primary.Add(fmt.Sprint("p", i))
+	}
+	before := make(map[string]string)
+	for _, key := range keys {
+		before[key] = primary.Get(key)
+	}
+	check("healthy primary", func(key string) (string, int) { return before[key], 0 })
+
+	// The keys of an unhealthy item move within the primary, the rest stay
+	primary.SetHealthy("p0", false)
+	moved := 0
+	check("partially unhealthy primary", func(key string) (string, int) {
+		if before[key] != "p0" {
+			return before[key], 0
+		}
+		moved++
+		next := slices.DeleteFunc(primary.NextN(key, 3), func(node string) bool { return node == "p0" })
+		return next[0], 0
+	})
+	if moved == 0 {
+		t.Fatal("no keys were on p0")
+	}
+
+	primary.SetHealthy("p1", false)
+	primary.SetHealthy("p2", false)
+	check("unhealthy primary", func(key string) (string, int) { return secondary.Get(key), 1 })
+
+	secondary.SetHealthy("s1", false)
+	secondary.SetHealthy("s2", false)
+	check("both unhealthy", func(string) (string, int) { return "", -1 })
+
+	for _, node := range []string{"p0", "p1", "p2"} {
+		primary.SetHealthy(node, true)
+	}
+	check("recovered primary", func(key string) (string, int) { return before[key], 0 })
+
+	if got := f.Members(0); !slices.Equal(got, []string{"p0", "p1", "p2"}) {
+		t.Errorf("primary members %v", got)
+	}
+	if got := f.Members(1); !slices.Equal(got, []string{"s1", "s2"}) {
+		t.Errorf("secondary members %v", got)
+	}
+}
+
+func TestFailoverOrder(t *testing.T) {
+	tiers := []*Consistent{New(nil), New(nil), New(nil)}
+	f := NewFailover(tiers...)
+	tiers[2].Add("c")
+	if got := f.Source("key"); got != 2 {
+		t.Fatalf("served by hash %d, want 2", got)
+	}
+	tiers[1].Add("b")
+	if got := f.Get("key"); got != "b" {
+		t.Fatalf("got %s, want b from the second hash", got)
+	}
+	tiers[0].AddStandby("a")
+	if got := f.Get("key"); got != "b" {
+		t.Fatalf("got %s, a standby served the key", got)
+	}
+	if got := NewFailover().Source("key"); got != -1 {
+		t.Fatalf("a failover without hashes served the key from %d", got)
+	}
+}
@@ -0,0 +1,94 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// The fingerprint of one generation, computed on first use.
+type fingerprint struct {
+	sync.Mutex
+	valid      bool
+	generation uint64
+	sum        uint64
+}
+
+// Get a checksum of the ring: every position with its item and replica, and
+// every item's weight and role. Hashes built with the same hash function and
+// membership have equal fingerprints, so processes can compare them to detect
+// drift. It is cached until the membership changes.
+func (m *Consistent) Fingerprint() uint64 {
+	m.RLock()
+	defer m.RUnlock()
+	return m.fingerprintLocked()
+}
+
+func (m *Consistent) fingerprintLocked() uint64 {
+	m.fingerprint.Lock()
+	defer m.fingerprint.Unlock()
+	if !m.fingerprint.valid || m.fingerprint.generation != m.generation {
+		m.fingerprint.sum = m.ring.fingerprint()
+		m.fingerprint.generation = m.generation
+		m.fingerprint.valid = true
+	}
+	return m.fingerprint.sum
+}
+
+func (r *ring) fingerprint() uint64 {
+	h := fnv.New64a()
+	var buf []byte
+	str := func(s string) {
+		buf = binary.AppendUvarint(buf, uint64(len(s)))
+		buf = append(buf, s...)
+	}
+
+	for i := 0; i < r.size(); i++ {
+		pos, p := r.index.at(i)
+		buf = binary.BigEndian.AppendUint32(buf[:0], uint32(pos))
+		str(r.names[p.node])
+		buf = binary.AppendUvarint(buf, uint64(p.replica))
+		h.Write(buf)
+	}
+
+	names := make([]string, 0, len(r.nodes))
+	for key := range r.nodes {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		mem := r.nodes[key]
+		buf = buf[:0]
+		str(key)
+		buf = binary.AppendUvarint(buf, uint64(mem.weight))
+		var flags byte
+		if mem.standby {
+			flags |= 1
+		}
+		if mem.tier != 0 {
+			flags |= 2
+		}
+		buf = append(buf, flags)
+		if mem.tier != 0 {
+			buf = binary.AppendVarint(buf, int64(mem.tier))
+		}
+		h.Write(buf)
+	}
+
+	for _, pin := range r.sortedPins() {
+		buf = buf[:0]
+		str(pin[0])
+		str(pin[1])
+		h.Write(buf)
+	}
+
+	for _, pin := range r.rangePins {
+		buf = binary.BigEndian.AppendUint32(buf[:0], uint32(pin.From))
+		buf = binary.BigEndian.AppendUint32(buf, uint32(pin.To))
+		str(pin.node)
+		h.Write(buf)
+	}
+
+	return h.Sum64()
+}
@@ -0,0 +1,114 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Hashes with the same items have the same fingerprint, whatever the order
+// and the way they were added.
+func TestFingerprintAgrees(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	names := make([]string, 30)
+	for i := range names {
+		names[i] = fmt.Sprint("n", i)
+	}
+	build := func(order []int, batched bool) *Consistent {
+		m := New(nil, WithReplicas(10))
+		add := func(add func(key string, weight int) error) {
+			for _, i := range order {
+				add(names[i], 1+i%4)
+			}
+		}
+		if batched {
+			m.Update(func(tx *Tx) error {
+				add(tx.AddWithWeight)
+				return nil
+			})
+		} else {
+			add(func(key string, weight int) error {
+				m.AddWithWeight(key, weight)
+				return nil
+			})
+		}
+		return m
+	}
+
+	want := build(rnd.Perm(len(names)), false).Fingerprint()
+	for i := 0; i < 20; i++ {
+		if got := build(rnd.Perm(len(names)), i%2 == 0).Fingerprint(); got != want {
+			t.Fatalf("order %d: fingerprint %x, want %x", i, got, want)
+		}
+	}
+
+	// Changes undone restore it
+	m := build(rnd.Perm(len(names)), false)
+	m.SetWeight("n3", 9)
+	m.Add("extra")
+	m.Pin("key", "n1")
+	m.Unpin("key")
+	m.Remove("extra")
+	m.SetWeight("n3", 4)
+	if got := m.Fingerprint(); got != want {
+		t.Fatalf("after undone changes %x, want %x", got, want)
+	}
+}
+
+func TestFingerprintDiffers(t *testing.T) {
+	base := func(opts ...Option) *Consistent {
+		m := New(nil, append([]Option{WithReplicas(10)}, opts...)...)
+		m.Add("ab")
+		m.Add("c")
+		m.AddWithWeight("d", 3)
+		return m
+	}
+	seen := map[uint64]string{base().Fingerprint(): "base"}
+	for name, change := range map[string]func() *Consistent{
+		"another item": func() *Consistent { m := base(); m.Add("e"); return m },
+		"fewer items":  func() *Consistent { m := base(); m.Remove("c"); return m },
+		"a weight":     func() *Consistent { m := base(); m.SetWeight("d", 4); return m },
+		"replicas":     func() *Consistent { return base(WithReplicas(11)) },
+		"a standby":    func() *Consistent { m := base(); m.AddStandby("e"); return m },
+		"a tier":       func() *Consistent { m := base(); m.AddTiered("e", 1); return m },
+		"a rename":     func() *Consistent { m := base(); m.Rename("c", "x"); return m },
+		"a pin":        func() *Consistent { m := base(); m.Pin("key", "c"); return m },
+		"the hash":     func() *Consistent { return base(WithHash(fnv32)) },
+		"split names": func() *Consistent {
+			m := New(nil, WithReplicas(10))
+			m.Add("a")
+			m.Add("bc")
+			m.AddWithWeight("d", 3)
+			return m
+		},
+		"a moved weight": func() *Consistent { m := base(); m.SetWeight("ab", 3); m.SetWeight("d", 10); return m },
+	} {
+		fp := change().Fingerprint()
+		if other, ok := seen[fp]; ok {
+			t.Errorf("%s has the fingerprint of %s", name, other)
+		}
+		seen[fp] = name
+	}
+}
+
+// The cached fingerprint follows every change, including those of a
+// transaction.
+func TestFingerprintCache(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	m.Add("a")
+	first := m.Fingerprint()
+	if m.Fingerprint() != first {
+		t.Fatal("the fingerprint changed without a change")
+	}
+	m.Update(func(tx *Tx) error { tx.Add("b"); return nil })
+	second := m.Fingerprint()
+	if second == first {
+		t.Fatal("the fingerprint missed an update")
+	}
+	fresh := New(nil, WithReplicas(10))
+	fresh.Add("a")
+	fresh.Add("b")
+	if second != fresh.Fingerprint() {
+		t.Fatal("the cached fingerprint differs from a new hash's")
+	}
+}
@@ -0,0 +1,213 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// The flat encoding written by MarshalFlat, little-endian throughout, each
+// section starting at a multiple of 4 bytes:
+//
+//	header    magic "CHRF", version, hash check, flags, points, names,
+//	          string bytes, reserved, as uint32s
+//	positions [points]uint32, strictly ascending
+//	owners    [points]uint32, the name index Get returns for each arc, or
+//	          noOwner
+//	offsets   [names+1]uint32, where each name starts in the string table
+//	strings   the names, back to back
+const (
+	flatMagic      = "CHRF"
+	flatVersion    = 1
+	flatHeaderSize = 32
+	flatDomains    = 1 << 0 // Keys are hashed after keyDomain
+	noOwner        = 1<<32 - 1
+)
+
+// Encode the routing of the hash in the flat encoding ReadOnlyRing reads
+// in place, such as from a memory-mapped file. Each arc records the item
+// Get maps it to, so health and standby state at the time of the call are
+// kept; pins, the key transform and hash tags are not.
+func (m *Consistent) MarshalFlat() []byte {
+	m.RLock()
+	defer m.RUnlock()
+	return m.marshalFlat()
+}
+
+func (m *Consistent) marshalFlat() []byte {
+	check, domains := uint32(m.nodeHash(hashProbe)), m.domains
+	keys := make([]int, m.ring.size())
+	owners := make([]string, len(keys))
+	index := make(map[string]uint32)
+	for i := range keys {
+		keys[i] = m.ring.key(i)
+		if j := m.ring.arcServer(i, m.atCapacity); j >= 0 {
+			owners[i] = m.ring.nodeAt(j)
+			index[owners[i]] = 0
+		}
+	}
+
+	names := sortedKeys(index)
+	strLen := 0
+	for i, name := range names {
+		index[name] = uint32(i)
+		strLen += len(name)
+	}
+
+	var flags uint32
+	if domains {
+		flags |= flatDomains
+	}
+	b := make([]byte, 0, flatHeaderSize+8*len(keys)+4*(len(names)+1)+strLen)
+	b = append(b, flatMagic...)
+	for _, v := range []uint32{flatVersion, check, flags, uint32(len(keys)), uint32(len(names)), uint32(strLen), 0} {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	for _, pos := range keys {
+		b = binary.LittleEndian.AppendUint32(b, uint32(pos))
+	}
+	for _, node := range owners {
+		owner := uint32(noOwner)
+		if node != "" {
+			owner = index[node]
+		}
+		b = binary.LittleEndian.AppendUint32(b, owner)
+	}
+	offset := uint32(0)
+	for _, name := range names {
+		b = binary.LittleEndian.AppendUint32(b, offset)
+		offset += uint32(len(name))
+	}
+	b = binary.LittleEndian.AppendUint32(b, offset)
+	for _, name := range names {
+		b = append(b, name...)
+	}
+	return b
+}
+
+// ReadOnlyRing looks keys up directly in the flat encoding of a hash,
+// without copying or decoding it. Names it returns point into the data,
+// which must not change while they are in use.
+type ReadOnlyRing struct {
+	fn        Hash
+	domains   bool
+	n         int
+	positions []byte
+	owners    []byte
+	offsets   []byte
+	strings   []byte
+}
+
+// Open the flat encoding written by MarshalFlat, checking that fn is the
+// hash function it was written with. The whole encoding is validated once,
+// so lookups need no bounds checks of their own; a truncated or corrupt
+// encoding returns an error wrapping ErrCorrupt.
+func OpenFlat(data []byte, fn Hash) (*ReadOnlyRing, error) {
+	if len(data) < flatHeaderSize || string(data[:4]) != flatMagic {
+		return nil, fmt.Errorf("%w: not a flat ring", ErrCorrupt)
+	}
+	word := func(i int) uint32 { return binary.LittleEndian.Uint32(data[4*i:]) }
+	if v := word(1); v != flatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCorrupt, v)
+	}
+	flags, points, names, strLen := word(3), uint64(word(4)), uint64(word(5)), uint64(word(6))
+	if size := flatHeaderSize + 8*points + 4*(names+1) + strLen; size != uint64(len(data)) {
+		return nil, fmt.Errorf("%w: %d bytes, want %d", ErrCorrupt, len(data), size)
+	}
+
+	r := &ReadOnlyRing{fn: fn, domains: flags&flatDomains != 0, n: int(points)}
+	off := uint64(flatHeaderSize)
+	r.positions, off = data[off:off+4*points], off+4*points
+	r.owners, off = data[off:off+4*points], off+4*points
+	r.offsets, off = data[off:off+4*(names+1)], off+4*(names+1)
+	r.strings = data[off:]
+
+	var prefix []byte
+	if r.domains {
+		prefix = []byte(nodeDomain)
+	}
+	if sum := fn(append(prefix, hashProbe...)); sum != word(2) {
+		return nil, fmt.Errorf("%w: written with a different hash function (check %08x, want %08x)", ErrMismatch, word(2), sum)
+	}
+
+	for i := 0; i < r.n; i++ {
+		if i > 0 && r.position(i) <= r.position(i-1) {
+			return nil, fmt.Errorf("%w: positions out of order at %d", ErrCorrupt, i)
+		}
+		if o := r.owner(i); o != noOwner && uint64(o) >= names {
+			return nil, fmt.Errorf("%w: point %d has owner %d of %d", ErrCorrupt, i, o, names)
+		}
+	}
+	if r.offset(0) != 0 || uint64(r.offset(int(names))) != strLen {
+		return nil, fmt.Errorf("%w: string table of %d bytes", ErrCorrupt, strLen)
+	}
+	for i := 1; i <= int(names); i++ {
+		if r.offset(i) < r.offset(i-1) {
+			return nil, fmt.Errorf("%w: name %d out of order", ErrCorrupt, i-1)
+		}
+	}
+	return r, nil
+}
+
+// Returns the number of points.
+func (r *ReadOnlyRing) Len() int {
+	return r.n
+}
+
+// Get the item for a hash, as Consistent.Get does after hashing, or "".
+func (r *ReadOnlyRing) Lookup(hash uint32) string {
+	if r.n == 0 {
+		return ""
+	}
+	// The last point at or below hash, wrapping to the last point
+	lo, hi := 0, r.n
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if r.position(mid) > hash {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	i := lo - 1
+	if i < 0 {
+		i = r.n - 1
+	}
+	o := r.owner(i)
+	if o == noOwner {
+		return ""
+	}
+	name := r.strings[r.offset(int(o)):r.offset(int(o)+1)]
+	return unsafe.String(unsafe.SliceData(name), len(name))
+}
+
+// Get the item for a key, hashed like Consistent.Hash without a key
+// transform or hash tags.
+func (r *ReadOnlyRing) Get(key string) string {
+	return r.Lookup(r.hash(key))
+}
+
+func (r *ReadOnlyRing) hash(key string) uint32 {
+	data := unsafe.Slice(unsafe.StringData(key), len(key))
+	if !r.domains {
+		return r.fn(data)
+	}
+	bp := partsPool.Get().(*[]byte)
+	b := append(append((*bp)[:0], keyDomain...), data...)
+	hash := r.fn(b)
+	*bp = b
+	partsPool.Put(bp)
+	return hash
+}
+
+func (r *ReadOnlyRing) position(i int) uint32 {
+	return binary.LittleEndian.Uint32(r.positions[4*i:])
+}
+
+func (r *ReadOnlyRing) owner(i int) uint32 {
+	return binary.LittleEndian.Uint32(r.owners[4*i:])
+}
+
+func (r *ReadOnlyRing) offset(i int) uint32 {
+	return binary.LittleEndian.Uint32(r.offsets[4*i:])
+}
@@ -0,0 +1,116 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+func flatRings() []*Consistent {
+	var rings []*Consistent
+	for _, opts := range [][]Option{nil, {WithDomainSeparation()}} {
+		c := New(nil, append([]Option{WithReplicas(20)}, opts...)...)
+		for i := 0; i < 8; i++ {
+			c.Add(fmt.Sprint("node-", i))
+		}
+		c.AddStandby("standby")
+		c.SetHealthy("node-3", false)
+		rings = append(rings, c)
+	}
+	tiered := tieredRing()
+	tiered.SetHealthy("onprem1", false)
+	return append(rings, tiered)
+}
+
+func TestFlatMatchesGet(t *testing.T) {
+	for _, c := range flatRings() {
+		data := c.MarshalFlat()
+		r, err := OpenFlat(data, crc32.ChecksumIEEE)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Len() != c.ring.size() {
+			t.Fatalf("%d points, want %d", r.Len(), c.ring.size())
+		}
+		for _, k := range testKeys(20000) {
+			if r.Get(k) != c.Get(k) {
+				t.Fatalf("%s: flat %s, Get %s", k, r.Get(k), c.Get(k))
+			}
+		}
+		if allocs := testing.AllocsPerRun(1000, func() { r.Get("some-key") }); allocs != 0 {
+			t.Fatalf("%v allocations per lookup", allocs)
+		}
+		for n := 0; n < len(data); n++ {
+			if _, err := OpenFlat(data[:n], crc32.ChecksumIEEE); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("truncated to %d bytes: %v", n, err)
+			}
+		}
+	}
+
+	empty, err := OpenFlat(New(nil).MarshalFlat(), crc32.ChecksumIEEE)
+	if err != nil || empty.Get("x") != "" || empty.Len() != 0 {
+		t.Fatal(err)
+	}
+	c := New(nil)
+	c.Add("a")
+	if _, err := OpenFlat(c.MarshalFlat(), func([]byte) uint32 { return 7 }); !errors.Is(err, ErrMismatch) {
+		t.Fatal(err)
+	}
+}
+
+func FuzzOpenFlat(f *testing.F) {
+	c := New(nil, WithReplicas(3))
+	c.Add("a")
+	c.Add("bb")
+	f.Add(c.MarshalFlat())
+	f.Add(New(nil).MarshalFlat())
+	for _, c := range flatRings() {
+		data := c.MarshalFlat()
+		f.Add(data)
+		// Corrupt a position, an owner and a name offset
+		for _, at := range []int{flatHeaderSize + 4, flatHeaderSize + 4*c.ring.size() + 1, len(data) - 5} {
+			bad := append([]byte(nil), data...)
+			bad[at] ^= 0xff
+			f.Add(bad)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := OpenFlat(data, crc32.ChecksumIEEE)
+		if err != nil {
+			if !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrMismatch) {
+				t.Fatalf("error %v is neither ErrCorrupt nor ErrMismatch", err)
+			}
+			return
+		}
+
+		names := make(map[string]bool)
+		ownerOf := func(i int) string {
+			if o := r.owner(i); o != noOwner {
+				return string(r.strings[r.offset(int(o)):r.offset(int(o)+1)])
+			}
+			return ""
+		}
+		for i := 0; i < r.Len(); i++ {
+			names[ownerOf(i)] = true
+		}
+		check := func(hash uint32) {
+			if node := r.Lookup(hash); node != "" && !names[node] {
+				t.Fatalf("hash %d gives %q, not a name of the ring", hash, node)
+			}
+		}
+		for i := 0; i < r.Len() && i < 1000; i++ {
+			pos := r.position(i)
+			if node := r.Lookup(pos); node != ownerOf(i) {
+				t.Fatalf("point %d at %d gives %q, owner %q", i, pos, node, ownerOf(i))
+			}
+			check(pos - 1)
+			check(pos + 1)
+		}
+		for i := uint32(0); i < 50; i++ {
+			check(i * 85899345)
+		}
+		r.Get("x")
+	})
+}
@@ -0,0 +1,238 @@
+package consistent
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoMembers = errors.New("consistent: member list is empty")
+
+// The interval FollowFile checks the file at when given one that is not
+// positive.
+const defaultFollowInterval = time.Second
+
+type FollowOption func(*following)
+
+// Call fn with every error reading, parsing or applying the file. The ring
+// is left as it was. An unchanged file is not reported again.
+func FollowErrors(fn func(error)) FollowOption {
+	return func(f *following) {
+		f.onError = fn
+	}
+}
+
+// Accept a file listing no members, removing every item. By default an
+// empty list is reported as ErrNoMembers, since it is more often a bad
+// write than an intent.
+func FollowAllowEmpty() FollowOption {
+	return func(f *following) {
+		f.allowEmpty = true
+	}
+}
+
+type following struct {
+	m          *Consistent
+	path       string
+	parse      func([]byte) ([]MemberConfig, error)
+	onError    func(error)
+	allowEmpty bool
+
+	modTime time.Time
+	size    int64
+	sum     uint64 // Of the contents last read, applied or not
+	read    bool
+}
+
+// Make the membership follow a file, checking its modification time and
+// size each interval until ctx is done and reading it when they change. The
+// contents are parsed and validated in full, then applied as one Update:
+// members missing from the file are removed, new ones added and weights
+// changed, so watchers receive a single EventUpdate. A member with weight
+// 0 gets the replicas. A standby in the file is added as one and promoted
+// once the file says so; an item is never made a standby again. Zones are
+// set in the same update. Contents that fail to parse or validate leave the
+// ring untouched. The file is read at once, and an interval that is not
+// positive is taken as one second. The returned channel is closed once
+// following stops.
+func (m *Consistent) FollowFile(ctx context.Context, path string, interval time.Duration, parse func([]byte) ([]MemberConfig, error), opts ...FollowOption) <-chan struct{} {
+	if interval <= 0 {
+		interval = defaultFollowInterval
+	}
+	f := &following{m: m, path: path, parse: parse}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := f.poll(); err != nil && f.onError != nil {
+				f.onError(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// Reload the file if it changed. Only the first error for the same
+// contents is returned.
+func (f *following) poll() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return f.failStat(err)
+	}
+	if f.read && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return f.failStat(err)
+	}
+	f.modTime, f.size = info.ModTime(), info.Size()
+
+	h := fnv.New64a()
+	h.Write(data)
+	sum := h.Sum64()
+	if f.read && sum == f.sum {
+		return nil
+	}
+	f.sum, f.read = sum, true
+
+	members, err := f.parse(data)
+	if err == nil {
+		err = validateMembers(members)
+	}
+	if err == nil && len(members) == 0 && !f.allowEmpty {
+		err = ErrNoMembers
+	}
+	if err != nil {
+		return fmt.Errorf("consistent: %s: %w", f.path, err)
+	}
+	return f.m.setMembers(members)
+}
+
+// Report a file that cannot be read, forgetting its contents so it is read
+// again once it can.
+func (f *following) failStat(err error) error {
+	f.read = false
+	return err
+}
+
+// Make the membership match a validated list in one Update.
+func (m *Consistent) setMembers(members []MemberConfig) error {
+	return m.Update(func(tx *Tx) error {
+		listed := make(map[string]bool, len(members))
+		for _, mc := range members {
+			listed[mc.Name] = true
+		}
+		for _, key := range tx.Members() {
+			if !listed[key] {
+				tx.Remove(key)
+			}
+		}
+
+		for _, mc := range members {
+			mem, ok := tx.r.nodes[mc.Name]
+			switch {
+			case !ok && mc.Standby:
+				tx.AddStandby(mc.Name)
+				if mc.Weight != 0 {
+					tx.SetWeight(mc.Name, mc.Weight)
+				}
+			case !ok && mc.Weight == 0:
+				tx.Add(mc.Name)
+			case !ok:
+				tx.AddWithWeight(mc.Name, mc.Weight)
+			default:
+				weight := mc.Weight
+				if weight == 0 {
+					weight = tx.replicas
+				}
+				if weight != mem.weight {
+					tx.SetWeight(mc.Name, weight)
+					mem.explicit = mc.Weight != 0
+				}
+				if mem.standby && !mc.Standby {
+					tx.Promote(mc.Name)
+				}
+			}
+			tx.SetZone(mc.Name, mc.Zone)
+		}
+		return nil
+	})
+}
+
+// Parse a JSON array of members, or an object with a "members" array such
+// as a Config, for FollowFile.
+func ParseMembersJSON(data []byte) ([]MemberConfig, error) {
+	data = bytes.TrimSpace(data)
+	var members []MemberConfig
+	if len(data) > 0 && data[0] == '[' {
+		err := json.Unmarshal(data, &members)
+		return members, err
+	}
+	var cfg Config
+	err := json.Unmarshal(data, &cfg)
+	return cfg.Members, err
+}
+
+// Parse CSV records of a name and optionally a weight, a zone and "standby",
+// for FollowFile. Blank lines and lines starting with '#' are skipped.
+func ParseMembersCSV(data []byte) ([]MemberConfig, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var members []MemberConfig
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return members, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) > 4 {
+			return nil, fmt.Errorf("consistent: %d fields for %q, want at most 4", len(record), record[0])
+		}
+
+		mc := MemberConfig{Name: strings.TrimSpace(record[0])}
+		if len(record) > 1 && record[1] != "" {
+			if mc.Weight, err = strconv.Atoi(strings.TrimSpace(record[1])); err != nil {
+				return nil, fmt.Errorf("consistent: weight of %q: %w", mc.Name, err)
+			}
+		}
+		if len(record) > 2 {
+			mc.Zone = strings.TrimSpace(record[2])
+		}
+		if len(record) > 3 {
+			switch strings.TrimSpace(record[3]) {
+			case "standby":
+				mc.Standby = true
+			case "":
+			default:
+				return nil, fmt.Errorf("consistent: unknown role %q for %q", record[3], mc.Name)
+			}
+		}
+		members = append(members, mc)
+	}
+}
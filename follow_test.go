@@ -0,0 +1,209 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A file in a temporary directory, written atomically with a modification
+// time a second later each time, so every version is seen as a change.
+type memberFile struct {
+	t       *testing.T
+	path    string
+	modTime time.Time
+}
+
+func newMemberFile(t *testing.T) *memberFile {
+	return &memberFile{t: t, path: filepath.Join(t.TempDir(), "members.csv"), modTime: time.Unix(1e9, 0)}
+}
+
+func (f *memberFile) write(contents string) {
+	f.t.Helper()
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(contents), 0o644); err != nil {
+		f.t.Fatal(err)
+	}
+	f.modTime = f.modTime.Add(time.Second)
+	if err := os.Chtimes(tmp, f.modTime, f.modTime); err != nil {
+		f.t.Fatal(err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// Each version of the file is applied in turn, and a bad version leaves the
+// ring as the last good one made it.
+func TestFollowFileVersions(t *testing.T) {
+	file := newMemberFile(t)
+	m := New(nil, WithReplicas(2))
+	f := &following{m: m, path: file.path, parse: ParseMembersCSV}
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+
+	if err := f.poll(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing file: %v", err)
+	}
+
+	file.write("a\nb,3\nc,,z1,standby\n")
+	if err := f.poll(); err != nil {
+		t.Fatal(err)
+	}
+	want := New(nil, WithReplicas(2))
+	want.Add("a")
+	want.AddWithWeight("b", 3)
+	want.AddStandby("c")
+	if m.Fingerprint() != want.Fingerprint() {
+		t.Fatalf("version 1: members %q", m.Members())
+	}
+	if zone, _ := m.Zone("c"); zone != "z1" {
+		t.Fatalf("version 1: c in zone %q", zone)
+	}
+
+	// Unchanged, the file is not applied or read again
+	if err := f.poll(); err != nil || len(events) != 1 {
+		t.Fatalf("unchanged file: %v, %d events", err, len(events))
+	}
+
+	fp := m.Fingerprint()
+	for _, bad := range []struct {
+		contents string
+		want     error
+	}{
+		{"a\na\n", ErrNodeExists},
+		{"", ErrNoMembers},
+		{"a,-1\n", ErrInvalidWeight},
+	} {
+		file.write(bad.contents)
+		if err := f.poll(); !errors.Is(err, bad.want) {
+			t.Fatalf("%q: %v, want %v", bad.contents, err, bad.want)
+		}
+		// The same bad contents are reported once
+		if err := f.poll(); err != nil {
+			t.Fatalf("%q reported again: %v", bad.contents, err)
+		}
+		if m.Fingerprint() != fp {
+			t.Fatalf("%q changed the ring", bad.contents)
+		}
+	}
+	file.write("a\nb,notanumber\n")
+	if err := f.poll(); err == nil || m.Fingerprint() != fp {
+		t.Fatalf("unparsable file: %v", err)
+	}
+
+	file.write("a,4\nc\nd\n")
+	if err := f.poll(); err != nil {
+		t.Fatal(err)
+	}
+	want = New(nil, WithReplicas(2))
+	want.AddWithWeight("a", 4)
+	want.Add("c")
+	want.Add("d")
+	if m.Fingerprint() != want.Fingerprint() || m.IsStandby("c") {
+		t.Fatalf("version 2: members %q", m.Members())
+	}
+	if len(events) != 2 || events[1].Type != EventUpdate {
+		t.Fatalf("events %+v, want two updates", events)
+	}
+
+	// With FollowAllowEmpty an empty file removes every item
+	f.allowEmpty = true
+	file.write("")
+	if err := f.poll(); err != nil || len(m.Members()) != 0 {
+		t.Fatalf("empty file: %v, members %q", err, m.Members())
+	}
+}
+
+// Following reads the file at once, reports errors and stops with the
+// context, also with an interval that is not positive.
+func TestFollowFile(t *testing.T) {
+	for _, interval := range []time.Duration{time.Millisecond, 0, -time.Second} {
+		file := newMemberFile(t)
+		file.write("a\nb\n")
+		m := New(nil)
+		errs := make(chan error, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := m.FollowFile(ctx, file.path, interval, ParseMembersCSV, FollowErrors(func(err error) { errs <- err }))
+
+		for i := 0; len(m.Members()) != 2; i++ {
+			if i == 1000 {
+				t.Fatalf("interval %v: the file was not read", interval)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		<-done
+		select {
+		case err := <-errs:
+			t.Fatalf("interval %v: %v", interval, err)
+		default:
+		}
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	got, err := ParseMembersCSV([]byte("# name,weight,zone,role\na\n\nb, 3, z1\nc,,,standby\n"))
+	want := []MemberConfig{{Name: "a"}, {Name: "b", Weight: 3, Zone: "z1"}, {Name: "c", Standby: true}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("CSV: %+v, %v", got, err)
+	}
+	for _, bad := range []string{"a,x\n", "a,1,z,primary\n", "a,1,z,standby,more\n"} {
+		if _, err := ParseMembersCSV([]byte(bad)); err == nil {
+			t.Errorf("CSV %q accepted", bad)
+		}
+	}
+
+	got, err = ParseMembersJSON([]byte(`[{"name":"a","weight":2}]`))
+	if err != nil || !reflect.DeepEqual(got, []MemberConfig{{Name: "a", Weight: 2}}) {
+		t.Fatalf("JSON array: %+v, %v", got, err)
+	}
+	got, err = ParseMembersJSON([]byte(` {"members":[{"name":"a"},{"name":"b","tier":1}]}`))
+	if err != nil || !reflect.DeepEqual(got, []MemberConfig{{Name: "a"}, {Name: "b", Tier: 1}}) {
+		t.Fatalf("JSON config: %+v, %v", got, err)
+	}
+	if _, err := ParseMembersJSON([]byte(`[{"name":"a"`)); err == nil {
+		t.Fatal("truncated JSON accepted")
+	}
+}
+
+// Zones from the file arrive in the same update as the membership, so a
+// watcher of that update already sees them.
+func TestSetMembersZonesInUpdate(t *testing.T) {
+	m := New(nil, WithReplicas(2))
+	m.Add("a")
+	var events []Event
+	var zones []string
+	m.Watch(func(e Event) {
+		events = append(events, e)
+		za, _ := m.Zone("a")
+		zb, _ := m.Zone("b")
+		zones = append(zones, za, zb)
+	})
+
+	gen := m.Generation()
+	if err := m.setMembers([]MemberConfig{{Name: "a", Zone: "z1"}, {Name: "b", Zone: "z2"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != EventUpdate || m.Generation() != gen+1 {
+		t.Fatalf("events %+v at generation %d, want one update at %d", events, m.Generation(), gen+1)
+	}
+	if zones[0] != "z1" || zones[1] != "z2" {
+		t.Fatalf("watcher saw zones %q", zones)
+	}
+	if got := events[0].Changed; len(got) != 1 || got[0] != "a" {
+		t.Fatalf("changed %q, want [a]", got)
+	}
+
+	// A zone change alone is an update too
+	if err := m.setMembers([]MemberConfig{{Name: "a", Zone: "z1"}, {Name: "b", Zone: "z3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || zones[3] != "z3" {
+		t.Fatalf("events %+v, zones %q", events, zones)
+	}
+}
@@ -0,0 +1,40 @@
+package consistent
+
+import (
+	"strconv"
+)
+
+// Hash the bytes returned by fn to place each replica of a key, instead of
+// using DefaultReplicaFormatter. Every path placing or removing points uses
+// it, so it must be a pure function of its arguments. A ring file must be
+// loaded with the formatter it was saved with.
+func WithReplicaFormatter(fn func(node string, replica int) []byte) Option {
+	return func(m *Consistent) {
+		m.formatter = fn
+	}
+}
+
+// DefaultReplicaFormatter is the placement used without a formatter and
+// will not change: replica 0 is the key's own name, so a hash without
+// weights places each key at the hash of its name, and replica i > 0 is the
+// name followed by "#" and i in decimal, as in "node#3".
+func DefaultReplicaFormatter(node string, replica int) []byte {
+	if replica == 0 {
+		return []byte(node)
+	}
+	return []byte(node + "#" + strconv.Itoa(replica))
+}
+
+// PrefixIndexFormatter writes the replica number in decimal followed by the
+// name, as in "3node", the way groupcache and stathat/consistent place
+// their replicas.
+func PrefixIndexFormatter(node string, replica int) []byte {
+	return []byte(strconv.Itoa(replica) + node)
+}
+
+// SuffixIndexFormatter writes the name followed by the replica number in
+// decimal, as in "node3", the way buraksezer/consistent places its
+// replicas.
+func SuffixIndexFormatter(node string, replica int) []byte {
+	return []byte(node + strconv.Itoa(replica))
+}
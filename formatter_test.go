@@ -0,0 +1,64 @@
+package consistent
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// The CRC-32 of each replica name, computed outside this package.
+func TestReplicaFormatterPositions(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		formatter func(node string, replica int) []byte
+		want      []int // Replicas 0, 1 and 2
+	}{
+		{"default", nil, []int{2239752261, 2373005777, 343433323}}, // node, node#1, node#2
+		{"default given", DefaultReplicaFormatter, []int{2239752261, 2373005777, 343433323}},
+		{"prefix", PrefixIndexFormatter, []int{3275258050, 4267200882, 3120049058}}, // 0node, 1node, 2node
+		{"suffix", SuffixIndexFormatter, []int{4075296214, 2247042368, 484865274}},  // node0, node1, node2
+	} {
+		var opts []Option
+		if tc.formatter != nil {
+			opts = append(opts, WithReplicaFormatter(tc.formatter))
+		}
+		m := New(nil, append(opts, WithReplicas(3))...)
+		if pos := m.Add("node"); pos != tc.want[0] {
+			t.Errorf("%s: Add placed replica 0 at %d, want %d", tc.name, pos, tc.want[0])
+		}
+		want := slices.Sorted(slices.Values(tc.want))
+		if got, _ := m.PositionsOf("node"); !slices.Equal(got, want) {
+			t.Errorf("%s: positions %v, want %v", tc.name, got, want)
+		}
+		for i, e := range m.Entries() {
+			if want := tc.want[e.Replica]; int(e.Position) != want {
+				t.Errorf("%s: entry %d is replica %d at %d, want %d", tc.name, i, e.Replica, e.Position, want)
+			}
+		}
+
+		// Every path finds the points it placed
+		m.SetWeight("node", 2)
+		if got, _ := m.PositionsOf("node"); !slices.Equal(got, slices.Sorted(slices.Values(tc.want[:2]))) {
+			t.Errorf("%s: positions %v after lowering the weight", tc.name, got)
+		}
+		m.SetWeight("node", 3)
+		path := filepath.Join(t.TempDir(), "ring.json")
+		if err := m.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := Load(path, append(opts, WithReplicas(3))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := loaded.PositionsOf("node"); !slices.Equal(got, want) {
+			t.Errorf("%s: loaded positions %v, want %v", tc.name, got, want)
+		}
+		if err := loaded.Validate(); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		m.Remove("node")
+		if n := len(m.Positions()); n != 0 || m.ring.index.len() != 0 {
+			t.Errorf("%s: %d points left after removing the only item", tc.name, n)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package consistent
+
+import (
+	"errors"
+)
+
+var (
+	ErrFrozen      = errors.New("consistent: hash is frozen")
+	ErrInvalidThaw = errors.New("consistent: thaw token does not match the freeze")
+)
+
+// ThawToken is returned by Freeze and must be passed to Thaw to make the hash
+// mutable again, so unfreezing takes the code that froze it.
+type ThawToken struct {
+	_ byte // Not zero-sized, so distinct tokens never compare equal
+}
+
+// Freeze the membership: from now on every change to it is refused, while
+// lookups keep working. Methods returning an error return ErrFrozen, in
+// Update and ApplyDelta too. Add returns -1; AddStandby, Remove, Unpin and
+// UnpinRange do nothing, and their Try variants return ErrFrozen. Health and
+// read-only flags, zones, capacities and load are not membership and can
+// still change. Freezing a frozen hash
+// returns nil, so only the first token thaws it; discard the token to freeze
+// the hash for good.
+func (m *Consistent) Freeze() *ThawToken {
+	m.Lock()
+	defer m.Unlock()
+	if m.frozen {
+		return nil
+	}
+	m.frozen = true
+	m.thaw = &ThawToken{}
+	return m.thaw
+}
+
+// Thaw a hash frozen by Freeze with the token it returned.
+func (m *Consistent) Thaw(token *ThawToken) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.frozen {
+		return nil
+	}
+	if token == nil || token != m.thaw {
+		return ErrInvalidThaw
+	}
+	m.frozen, m.thaw = false, nil
+	return nil
+}
+
+// Returns true if the hash is frozen.
+func (m *Consistent) Frozen() bool {
+	m.RLock()
+	defer m.RUnlock()
+	return m.frozen
+}
@@ -0,0 +1,146 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"hash/crc32"
+	"hash/fnv"
+	"testing"
+	"time"
+)
+
+func TestFrozenRefusesEveryMutator(t *testing.T) {
+	c := New(nil, WithReplicas(10))
+	c.Add("a")
+	c.Add("b")
+	c.AddStandby("s")
+	c.Pin("k", "a")
+	c.PinRange(10, 20, "b")
+
+	src := New(nil, WithReplicas(10))
+	src.Add("a")
+	gen := src.Generation()
+	src.Add("z")
+	delta, err := src.ChangesSince(gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := src.Snapshot()
+
+	token := c.Freeze()
+	if token == nil || c.Freeze() != nil || !c.Frozen() {
+		t.Fatal("not frozen")
+	}
+	fp, gen := c.Fingerprint(), c.Generation()
+	events := 0
+	c.Watch(func(Event) { events++ })
+
+	if pos := c.Add("c"); pos != -1 {
+		t.Fatalf("frozen Add returned position %d", pos)
+	}
+	if pos := c.Add("a"); pos != -1 {
+		t.Fatalf("frozen Add of a present key returned position %d", pos)
+	}
+	c.AddStandby("d")
+	c.Remove("a")
+	c.Unpin("k")
+	c.UnpinRange(10, 20)
+	if removed := c.RemoveByPrefix(""); len(removed) != 0 {
+		t.Fatal(removed)
+	}
+
+	fnv64 := func(data []byte) uint64 {
+		h := fnv.New64a()
+		h.Write(data)
+		return h.Sum64()
+	}
+	for name, fn := range map[string]func() error{
+		"TryAddStandby":       func() error { return c.TryAddStandby("d") },
+		"TryRemove":           func() error { return c.TryRemove("a") },
+		"TryUnpin":            func() error { return c.TryUnpin("k") },
+		"TryUnpinRange":       func() error { return c.TryUnpinRange(10, 20) },
+		"AddWithWeight":       func() error { return c.AddWithWeight("c", 3) },
+		"AddStrict":           func() error { return c.AddStrict("c") },
+		"AddStrictWithWeight": func() error { return c.AddStrictWithWeight("c", 3) },
+		"AddTiered":           func() error { return c.AddTiered("c", 1) },
+		"AddWithSlowStart":    func() error { return c.AddWithSlowStart("c", time.Minute, 4) },
+		"AddIf":               func() error { return c.AddIf(gen, "c") },
+		"RemoveIf":            func() error { return c.RemoveIf(gen, "a") },
+		"SetWeight":           func() error { return c.SetWeight("a", 4) },
+		"SetReplicas":         func() error { return c.SetReplicas(3) },
+		"Promote":             func() error { return c.Promote("s") },
+		"Rename":              func() error { return c.Rename("a", "x") },
+		"Pin":                 func() error { return c.Pin("q", "b") },
+		"PinRange":            func() error { return c.PinRange(30, 40, "a") },
+		"SetHash":             func() error { return c.SetHash(crc32.ChecksumIEEE) },
+		"SetHash64":           func() error { return c.SetHash64(fnv64) },
+		"Restore":             func() error { return c.Restore(snap) },
+		"ApplyDelta":          func() error { return c.ApplyDelta(delta) },
+		"DrainOver": func() error {
+			return c.DrainOver(context.Background(), "a", time.Millisecond, 1)
+		},
+		"AddDetailed": func() error {
+			_, err := c.AddDetailed("c")
+			return err
+		},
+		"RemoveDetailed": func() error {
+			_, err := c.RemoveDetailed("a")
+			return err
+		},
+		"RemoveFunc": func() error {
+			_, err := c.RemoveFunc(func(string) bool { return true })
+			return err
+		},
+		"Update": func() error {
+			return c.Update(func(tx *Tx) error {
+				tx.Add("c")
+				tx.Remove("a")
+				return nil
+			})
+		},
+		"UpdateIf": func() error {
+			return c.UpdateIf(gen, func(tx *Tx) error {
+				tx.AddStandby("d")
+				return tx.Promote("s")
+			})
+		},
+	} {
+		if err := fn(); !errors.Is(err, ErrFrozen) {
+			t.Errorf("frozen %s returned %v", name, err)
+		}
+	}
+
+	if c.Fingerprint() != fp || c.Generation() != gen || events != 0 {
+		t.Fatalf("frozen hash changed: generation %d, %d events", c.Generation(), events)
+	}
+	if got := c.Members(); len(got) != 3 {
+		t.Fatal(got)
+	}
+	if node, _ := c.Pinned("k"); node != "a" || len(c.PinnedRanges()) != 1 {
+		t.Fatal("pins changed")
+	}
+
+	// Routing is not membership
+	if err := c.SetHealthy("a", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetHealthy("a", true); err != nil || c.Get("k") != "a" {
+		t.Fatal(err, c.Get("k"))
+	}
+
+	if err := c.Thaw(&ThawToken{}); !errors.Is(err, ErrInvalidThaw) {
+		t.Fatal(err)
+	}
+	if err := c.Thaw(token); err != nil || c.Frozen() {
+		t.Fatal(err)
+	}
+	if pos := c.Add("c"); pos < 0 {
+		t.Fatal(pos)
+	}
+	if err := c.TryRemove("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.TryUnpin("k"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,81 @@
+package consistent
+
+import (
+	"time"
+)
+
+// Let GetWithPrevious report the item a key had before the last membership
+// change for d after it, so the old item can hand the key over. The routing
+// is saved as by MarshalFlat after every change, with the pins and range
+// pins as they resolve then, so it costs two copies of the points and pins,
+// the current routing and the one before the last change; only that one
+// change is remembered.
+func WithGraceWindow(d time.Duration) Option {
+	return func(m *Consistent) {
+		if d > 0 {
+			m.grace = &grace{window: d}
+		}
+	}
+}
+
+type grace struct {
+	window     time.Duration
+	generation uint64
+	current    *routing  // The routing now, nil before the first change
+	previous   *routing  // The routing before the last membership change
+	changed    time.Time // When the generation last moved
+}
+
+// A saved routing: the points and the pins resolved when it was saved.
+type routing struct {
+	flat      *ReadOnlyRing
+	pins      map[string]string
+	rangePins []rangePin
+}
+
+// Get the item a key had in the routing, as Get found it then.
+func (r *routing) get(m *Consistent, key string) string {
+	if node, ok := r.pins[m.pinKey(key)]; ok {
+		return node
+	}
+	// Hashed with the function the routing was saved with, in case of SetHash
+	hash := r.flat.hash(m.hashedKey(key))
+	if pin, ok := findRangePin(r.rangePins, int(hash)); ok {
+		return pin.node
+	}
+	return r.flat.Lookup(hash)
+}
+
+// Get the item for a key like Get, and while the last membership change is
+// within the window of WithGraceWindow, the item the key had before it.
+// inGrace is false, and previous empty, if the key's item did not change,
+// it had none, or the window has passed. Pins, range pins and tiers route
+// the key before the change as they did then.
+func (m *Consistent) GetWithPrevious(key string) (current, previous string, inGrace bool) {
+	current = m.Get(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	g := m.grace
+	if g == nil || g.previous == nil || m.clock.Now().Sub(g.changed) >= g.window {
+		return current, "", false
+	}
+	if previous = g.previous.get(m, key); previous == "" || previous == current {
+		return current, "", false
+	}
+	return current, previous, true
+}
+
+// Save the routing after a change, keeping the one before it when the
+// generation moves. Called under the write lock.
+func (g *grace) update(m *Consistent) {
+	if m.generation != g.generation {
+		g.previous, g.generation, g.changed = g.current, m.generation, m.clock.Now()
+	}
+	flat, _ := OpenFlat(m.marshalFlat(), *m.hash.Load())
+	g.current = &routing{
+		flat:      flat,
+		pins:      m.ring.resolvePins(m.strictPins),
+		rangePins: m.ring.resolveRangePins(m.strictPins),
+	}
+}
@@ -0,0 +1,139 @@
+package consistent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"testing"
+	"time"
+)
+
+func graceRing(clock Clock, opts ...Option) *Consistent {
+	c := New(nil, append([]Option{WithReplicas(20), WithGraceWindow(time.Minute), WithClock(clock)}, opts...)...)
+	for i := 0; i < 4; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	return c
+}
+
+// Check every key reports the item it had in before while in grace, and
+// nothing otherwise.
+func checkPrevious(t *testing.T, c *Consistent, before map[string]string, inWindow bool) int {
+	t.Helper()
+	moved := 0
+	for k, prev := range before {
+		cur, got, in := c.GetWithPrevious(k)
+		if cur != c.Get(k) {
+			t.Fatalf("%s: current %s, Get %s", k, cur, c.Get(k))
+		}
+		if want := inWindow && prev != "" && prev != cur; in != want || want && got != prev || !want && got != "" {
+			t.Fatalf("%s: on %s, previous %q in grace %v, had %s", k, cur, got, in, prev)
+		}
+		if in {
+			moved++
+		}
+	}
+	return moved
+}
+
+func TestGetWithPrevious(t *testing.T) {
+	clock := newTestClock()
+	c := graceRing(clock)
+	keys := testKeys(5000)
+	before := getAll(c, keys)
+
+	clock.advance(time.Hour)
+	c.Add("n4")
+	if n := checkPrevious(t, c, before, true); n == 0 {
+		t.Fatal("no key moved")
+	}
+
+	// Routing changes do not restart the window
+	clock.advance(30 * time.Second)
+	c.SetHealthy("n0", false)
+	c.SetHealthy("n0", true)
+	checkPrevious(t, c, before, true)
+	clock.advance(30 * time.Second)
+	checkPrevious(t, c, before, false)
+
+	// Only the last change is kept
+	before = getAll(c, keys)
+	c.Remove("n4")
+	checkPrevious(t, c, before, true)
+
+	// Hashed as before a change of hash function
+	before = getAll(c, keys)
+	c.SetHash64(func(data []byte) uint64 {
+		h := fnv.New64a()
+		h.Write(data)
+		return h.Sum64()
+	})
+	if n := checkPrevious(t, c, before, true); n == 0 {
+		t.Fatal("no key moved with the hash function")
+	}
+
+	if _, prev, in := New(nil).GetWithPrevious("k"); in || prev != "" {
+		t.Fatal("in grace without a window")
+	}
+}
+
+func TestGetWithPreviousRoutesLikeGet(t *testing.T) {
+	keys := testKeys(5000)
+	for _, strict := range []bool{false, true} {
+		var opts []Option
+		if strict {
+			opts = append(opts, WithStrictPins())
+		}
+		clock := newTestClock()
+		c := graceRing(clock, opts...)
+		c.Pin(keys[0], "n1")
+		c.Pin(keys[1], "n2")
+		c.PinRange(0, 1<<30, "n3")
+		before := getAll(c, keys)
+
+		// Pinned keys and keys in a pinned range stay put as the ring changes
+		c.Add("n4")
+		checkPrevious(t, c, before, true)
+		for _, k := range keys[:2] {
+			if _, prev, in := c.GetWithPrevious(k); in {
+				t.Fatalf("pinned %s reports %s", k, prev)
+			}
+		}
+
+		// And report their pin once moved
+		before = getAll(c, keys)
+		c.Pin(keys[0], "n0")
+		checkPrevious(t, c, before, true)
+		if _, prev, in := c.GetWithPrevious(keys[0]); !in || prev != "n1" {
+			t.Fatalf("repinned key reports %q", prev)
+		}
+		before = getAll(c, keys)
+		c.UnpinRange(0, 1<<30)
+		if n := checkPrevious(t, c, before, true); n == 0 {
+			t.Fatal("no key left the unpinned range")
+		}
+
+		// A pin whose item is gone falls back, or reports no item
+		before = getAll(c, keys)
+		c.Remove("n2")
+		checkPrevious(t, c, before, true)
+		before = getAll(c, keys)
+		c.Add("n5")
+		checkPrevious(t, c, before, true)
+		clock.advance(time.Minute)
+		checkPrevious(t, c, before, false)
+	}
+
+	// Keys move between tiers as Get moves them
+	clock := newTestClock()
+	c := New(nil, WithReplicas(40), WithGraceWindow(time.Minute), WithClock(clock))
+	for i := 0; i < 3; i++ {
+		c.AddTiered(fmt.Sprint("onprem", i), 1)
+		c.AddTiered(fmt.Sprint("cloud", i), 2)
+	}
+	c.SetHealthy("onprem1", false)
+	before := getAll(c, keys)
+	c.AddTiered("onprem3", 1)
+	if n := checkPrevious(t, c, before, true); n == 0 {
+		t.Fatal("no key moved")
+	}
+}
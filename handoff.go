@@ -0,0 +1,49 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Move is one step of a HandoffPlan: a range to copy from its owner in the
+// current hash to its owner in the target hash. Moves with the same Seq can
+// run at the same time.
+type Move struct {
+	Transfer
+	Seq int `json:"seq"`
+}
+
+// Plan the data movement from one hash to another: the ranges Diff reports,
+// each given the earliest sequence index at which neither its source nor
+// its destination already takes part in maxConcurrentPerNode moves. A limit
+// below 1 puts every move in sequence 0. An empty source or destination is
+// not limited. Moves are sorted by sequence index, then by position.
+func HandoffPlan(from, to *Consistent, maxConcurrentPerNode int) []Move {
+	transfers := Diff(from, to)
+	moves := make([]Move, len(transfers))
+	busy := make(map[string][]int) // Moves per sequence index, per item
+	fits := func(node string, seq int) bool {
+		return node == "" || seq >= len(busy[node]) || busy[node][seq] < maxConcurrentPerNode
+	}
+	take := func(node string, seq int) {
+		if node == "" {
+			return
+		}
+		for len(busy[node]) <= seq {
+			busy[node] = append(busy[node], 0)
+		}
+		busy[node][seq]++
+	}
+
+	for i, t := range transfers {
+		seq := 0
+		for maxConcurrentPerNode > 0 && !(fits(t.From, seq) && fits(t.To, seq)) {
+			seq++
+		}
+		take(t.From, seq)
+		take(t.To, seq)
+		moves[i] = Move{Transfer: t, Seq: seq}
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Seq < moves[j].Seq })
+	return moves
+}
@@ -0,0 +1,130 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestHandoffPlanCoversDiff(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	sequenced := false
+	for iter := 0; iter < 20; iter++ {
+		from := New(nil, WithReplicas(20))
+		to := New(nil, WithReplicas(20))
+		for i := 0; i < 2+rng.Intn(8); i++ {
+			from.Add(fmt.Sprint("n", i))
+			to.Add(fmt.Sprint("n", i))
+		}
+		for _, node := range from.Members() {
+			switch rng.Intn(5) {
+			case 0:
+				to.Remove(node)
+			case 1:
+				to.AddWithWeight(node, 1+rng.Intn(60))
+			case 2:
+				to.SetHealthy(node, false)
+			}
+		}
+		for i := 0; i < rng.Intn(3); i++ {
+			to.Add(fmt.Sprint("new", i))
+		}
+		if iter == 0 {
+			to = New(nil)
+		}
+
+		limit := iter%4 + 1
+		moves := HandoffPlan(from, to, limit)
+		diff := Diff(from, to)
+		if len(moves) != len(diff) {
+			t.Fatalf("%d moves for %d transfers", len(moves), len(diff))
+		}
+
+		// The moves are the transfers of Diff, in sequence order
+		transfers := make([]Transfer, len(moves))
+		for i, m := range moves {
+			if i > 0 && moves[i-1].Seq > m.Seq {
+				t.Fatalf("move %d out of sequence order", i)
+			}
+			transfers[i] = m.Transfer
+		}
+		sort.Slice(transfers, func(i, j int) bool { return transfers[i].Range.To < transfers[j].Range.To })
+		sort.Slice(diff, func(i, j int) bool { return diff[i].Range.To < diff[j].Range.To })
+		for i := range diff {
+			if transfers[i] != diff[i] {
+				t.Fatalf("move %v, Diff %v", transfers[i], diff[i])
+			}
+		}
+
+		// Their union is exactly the hashes whose owner differs. Ownership
+		// only changes at the points of either ring.
+		at := func(hash int) {
+			owner, next := from.lookupNode(hash), to.lookupNode(hash)
+			var in []Transfer
+			for _, tr := range diff {
+				if contains(tr.Range, hash) {
+					in = append(in, tr)
+				}
+			}
+			if owner == next && len(in) != 0 || owner != next && (len(in) != 1 || in[0].From != owner || in[0].To != next) {
+				t.Fatalf("hash %d moves from %q to %q, in moves %v", hash, owner, next, in)
+			}
+		}
+		bounds := []int{0}
+		for _, c := range []*Consistent{from, to} {
+			for i := 0; i < c.ring.size(); i++ {
+				bounds = append(bounds, c.ring.key(i))
+			}
+		}
+		sort.Ints(bounds)
+		bounds = slices.Compact(bounds)
+		var changed, covered uint64
+		for i, lo := range bounds {
+			hi := MaxPosition
+			if i+1 < len(bounds) {
+				hi = bounds[i+1] - 1
+			}
+			at(lo)
+			at(hi)
+			if from.lookupNode(lo) != to.lookupNode(lo) {
+				changed += uint64(hi-lo) + 1
+			}
+		}
+		for i := 0; i < 5000; i++ {
+			at(rng.Intn(MaxPosition + 1))
+		}
+		for _, tr := range diff {
+			covered += rangeLength(tr.Range)
+		}
+		if changed != covered {
+			t.Fatalf("%d positions change owner, moves cover %d", changed, covered)
+		}
+
+		// No item takes part in more than limit moves of a sequence index
+		busy := make(map[string]map[int]int)
+		for _, m := range moves {
+			for _, node := range []string{m.From, m.To} {
+				if node == "" {
+					continue
+				}
+				if busy[node] == nil {
+					busy[node] = make(map[int]int)
+				}
+				sequenced = sequenced || m.Seq > 0
+				if busy[node][m.Seq]++; busy[node][m.Seq] > limit {
+					t.Fatalf("%s in %d moves of sequence %d, limit %d", node, busy[node][m.Seq], m.Seq, limit)
+				}
+			}
+		}
+		for _, m := range HandoffPlan(from, to, 0) {
+			if m.Seq != 0 {
+				t.Fatalf("move %v without a limit", m)
+			}
+		}
+	}
+	if !sequenced {
+		t.Fatal("the limit never put a move after sequence 0")
+	}
+}
@@ -0,0 +1,68 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// The hash functions that can be chosen by name, each taking a seed. A zero
+// seed gives the plain function; any other seed hashes its 8 big-endian
+// bytes before the data.
+var namedHashes = map[string]func(seed uint64) Hash{
+	"crc32":   crc32Hash(crc32.IEEETable),
+	"crc32c":  crc32Hash(crc32.MakeTable(crc32.Castagnoli)),
+	"fnv1a32": fnv1a32Hash,
+}
+
+// Returns the names of the hash functions NamedHash accepts, sorted.
+func HashNames() []string {
+	return sortedKeys(namedHashes)
+}
+
+// Get a hash function by name, seeded.
+func NamedHash(name string, seed uint64) (Hash, error) {
+	fn, ok := namedHashes[name]
+	if !ok {
+		return nil, fmt.Errorf("consistent: unknown hash %q, want one of %v", name, HashNames())
+	}
+	return fn(seed), nil
+}
+
+func crc32Hash(table *crc32.Table) func(seed uint64) Hash {
+	return func(seed uint64) Hash {
+		if seed == 0 {
+			return func(data []byte) uint32 { return crc32.Checksum(data, table) }
+		}
+		prefix := crc32.Checksum(binary.BigEndian.AppendUint64(nil, seed), table)
+		return func(data []byte) uint32 { return crc32.Update(prefix, table, data) }
+	}
+}
+
+func fnv1a32Hash(seed uint64) Hash {
+	const prime = 16777619
+	basis := uint32(2166136261)
+	if seed != 0 {
+		for _, b := range binary.BigEndian.AppendUint64(nil, seed) {
+			basis = (basis ^ uint32(b)) * prime
+		}
+	}
+	return func(data []byte) uint32 {
+		h := basis
+		for _, b := range data {
+			h = (h ^ uint32(b)) * prime
+		}
+		return h
+	}
+}
+
+// Choose the hash function by name, as NamedHash does. An unknown name is
+// reported by NewFromConfig; New ignores it.
+func WithNamedHash(name string, seed uint64) Option {
+	return func(m *Consistent) {
+		if fn, err := NamedHash(name, seed); err == nil {
+			m.hash.Store(&fn)
+			m.hashName, m.seed = name, seed
+		}
+	}
+}
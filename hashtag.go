@@ -0,0 +1,31 @@
+package consistent
+
+import (
+	"strings"
+)
+
+// Hash only the tag of a key that has one, as Redis Cluster does, so keys
+// sharing a tag such as "order:{user123}:item:9" and "cart:{user123}" go to
+// the same items. The tag is what lies between the first "{" and the first
+// "}" after it, if that is not empty; a key without one is hashed whole. Tags
+// apply after the key transform, in Hash and every lookup built on it, and
+// in HashReader and GetReader, which then read the whole key first. They do
+// not apply to pins, which stay per key, or to HashParts.
+func WithHashTags() Option {
+	return func(m *Consistent) {
+		m.hashTags = true
+	}
+}
+
+// Returns the tag of a key, or the key if it has none.
+func hashTag(key string) string {
+	open := strings.IndexByte(key, '{')
+	if open < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[open+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[open+1 : open+1+end]
+}
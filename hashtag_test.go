@@ -0,0 +1,77 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The cases of the Redis Cluster specification, and the edges around them.
+var hashTagCases = map[string]string{
+	"{user1000}.following":   "user1000",
+	"{user1000}.followers":   "user1000",
+	"foo{}{bar}":             "foo{}{bar}", // The first braces are empty
+	"foo{{bar}}zap":          "{bar",       // The tag starts after the first "{"
+	"foo{bar}{zap}":          "bar",        // Only the first tag counts
+	"order:{user123}:item:9": "user123",
+	"{}":                     "{}",
+	"{{a}}":                  "{a",
+	"key{tag}":               "tag", // A tag at the end
+	"{tag}":                  "tag",
+	"key{":                   "key{",
+	"key}{":                  "key}{",
+	"key}{tag}":              "tag",
+	"":                       "",
+	"plain":                  "plain",
+}
+
+func TestHashTag(t *testing.T) {
+	for key, want := range hashTagCases {
+		if got := hashTag(key); got != want {
+			t.Errorf("hashTag(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestHashTagsLookups(t *testing.T) {
+	m := New(nil, WithHashTags(), WithReplicas(20))
+	plain := New(nil, WithReplicas(20))
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprint("n", i))
+		plain.Add(fmt.Sprint("n", i))
+	}
+	for key, tag := range hashTagCases {
+		if got, want := m.Hash(key), plain.Hash(tag); got != want {
+			t.Errorf("Hash(%q) = %d, want the hash of %q, %d", key, got, tag, want)
+		}
+		if got, want := m.Get(key), plain.Get(tag); got != want {
+			t.Errorf("Get(%q) = %s, want %s", key, got, want)
+		}
+		if got, want := m.NextN(key, 3), plain.NextN(tag, 3); !slices.Equal(got, want) {
+			t.Errorf("NextN(%q) = %v, want %v", key, got, want)
+		}
+		if got, want := m.GetNExcluding(key, 2, "n1"), plain.GetNExcluding(tag, 2, "n1"); !slices.Equal(got, want) {
+			t.Errorf("GetNExcluding(%q) = %v, want %v", key, got, want)
+		}
+		if got, err := m.GetReader(strings.NewReader(key)); err != nil || got != plain.Get(tag) {
+			t.Errorf("GetReader(%q) = %s, %v, want %s", key, got, err, plain.Get(tag))
+		}
+		if got, _ := m.HashReader(strings.NewReader(key)); got != plain.Hash(tag) {
+			t.Errorf("HashReader(%q) = %d, want %d", key, got, plain.Hash(tag))
+		}
+	}
+
+	owner := m.Get("order:{user123}:item:9")
+	for i := 0; i < 50; i++ {
+		if got := m.Get(fmt.Sprintf("cart%d:{user123}", i)); got != owner {
+			t.Fatalf("cart%d:{user123} went to %s, away from its tag on %s", i, got, owner)
+		}
+	}
+
+	// The tag is taken after the key transform
+	lower := New(nil, WithHashTags(), WithKeyTransform(strings.ToLower))
+	if got, want := lower.Hash("ORDER:{USER123}"), plain.Hash("user123"); got != want {
+		t.Errorf("the tag of a transformed key hashed to %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package consistent
+
+// Mark an item healthy or unhealthy. Lookups skip unhealthy items as if they
+// were absent, so their keys go where they would after a Remove, and come
+// back when the item is healthy again. It resets the item's circuit breaker,
+// ending the trickle to it if it is half-open, see WithEjection.
+func (m *Consistent) SetHealthy(key string, healthy bool) error {
+	var err error
+	fn := func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if mem.unhealthy == !healthy && !mem.halfOpen {
+			return nil
+		}
+		m.ring.setHalfOpen(mem, false)
+		mem.unhealthy = !healthy
+		return &Event{Type: EventHealth, Changed: []string{key}}
+	}
+
+	e := m.ejection
+	if e == nil {
+		m.reroute(fn)
+		return err
+	}
+	// Under the breakers, so a report cannot reopen the item in between
+	e.mu.Lock()
+	ev := m.apply(fn, nil, false)
+	delete(e.breakers, key)
+	e.schedule()
+	e.mu.Unlock()
+	if ev != nil {
+		m.notify(*ev)
+	}
+	return err
+}
+
+// Returns true if the item is present and not marked unhealthy.
+func (m *Consistent) IsHealthy(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	return ok && !mem.unhealthy
+}
@@ -0,0 +1,194 @@
+package consistent
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidPath  = errors.New("consistent: path must have a non-empty label without '/' for every level")
+	ErrUnknownLevel = errors.New("consistent: unknown level")
+)
+
+// Hierarchy places keys on items arranged in failure domains, such as
+// datacenter, rack and host, so that owners can be chosen from distinct
+// domains. Every domain is chosen by a ring of its siblings, so removing an
+// item only moves the keys it owned, to other items of the same parent.
+// Domains at a level get equal shares whatever the number of items in them.
+type Hierarchy struct {
+	sync.RWMutex
+	hash     Hash
+	opts     []Option
+	levels   []string
+	root     *domain
+	domains  map[string]*domain // By path
+	leafPath map[string][]string
+}
+
+// A failure domain and the ring choosing between its children.
+type domain struct {
+	children map[string]*domain
+	ring     *Consistent
+}
+
+// Create a hierarchy with the named levels, outermost first. The last
+// level is the items themselves. Options configure the ring of every
+// domain.
+func NewHierarchy(fn Hash, levels []string, opts ...Option) *Hierarchy {
+	h := &Hierarchy{
+		hash:     fn,
+		opts:     opts,
+		levels:   append([]string(nil), levels...),
+		domains:  make(map[string]*domain),
+		leafPath: make(map[string][]string),
+	}
+	h.root = h.newDomain()
+	return h
+}
+
+func (h *Hierarchy) newDomain() *domain {
+	return &domain{children: make(map[string]*domain), ring: New(h.hash, h.opts...)}
+}
+
+// Add an item at the given path of labels, one per level; the last label
+// names the item, which must be unique.
+func (h *Hierarchy) AddAt(path []string) error {
+	if len(path) != len(h.levels) {
+		return ErrInvalidPath
+	}
+	for _, label := range path {
+		if label == "" || strings.Contains(label, "/") {
+			return ErrInvalidPath
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	leaf := path[len(path)-1]
+	if _, ok := h.leafPath[leaf]; ok {
+		return ErrNodeExists
+	}
+	h.leafPath[leaf] = append([]string(nil), path...)
+
+	d := h.root
+	for i, label := range path {
+		child, ok := d.children[label]
+		if !ok {
+			child = h.newDomain()
+			d.children[label] = child
+			d.ring.Add(label)
+			h.domains[strings.Join(path[:i+1], "/")] = child
+		}
+		d = child
+	}
+	return nil
+}
+
+// Remove an item, and every domain left without items.
+func (h *Hierarchy) Remove(key string) {
+	h.Lock()
+	defer h.Unlock()
+	path, ok := h.leafPath[key]
+	if !ok {
+		return
+	}
+	delete(h.leafPath, key)
+
+	// Walk up from the item while domains become empty
+	for i := len(path) - 1; i >= 0; i-- {
+		name := strings.Join(path[:i+1], "/")
+		parent := h.root
+		if i > 0 {
+			parent = h.domains[strings.Join(path[:i], "/")]
+		}
+		if len(h.domains[name].children) > 0 {
+			return
+		}
+		delete(parent.children, path[i])
+		parent.ring.Remove(path[i])
+		delete(h.domains, name)
+	}
+}
+
+// Returns the path of an item.
+func (h *Hierarchy) Path(key string) ([]string, bool) {
+	h.RLock()
+	defer h.RUnlock()
+	path, ok := h.leafPath[key]
+	return append([]string(nil), path...), ok
+}
+
+// Get the item the provided key belongs to.
+func (h *Hierarchy) Get(key string) string {
+	h.RLock()
+	defer h.RUnlock()
+	return h.descend(h.root, key)
+}
+
+// Get up to n items for the provided key, no two of which share a domain
+// at the given level. The first is the item Get returns. Each further one
+// is found by the same walk down from the root, trying the children of
+// every domain in NextN order and skipping the domains of the level already
+// used; below the level the key descends by Get. Fewer items are returned
+// if the level has fewer than n domains.
+func (h *Hierarchy) GetOwnersHierarchical(key string, n int, level string) ([]string, error) {
+	h.RLock()
+	defer h.RUnlock()
+	depth := -1
+	for i, l := range h.levels {
+		if l == level {
+			depth = i
+		}
+	}
+	if depth < 0 {
+		return nil, ErrUnknownLevel
+	}
+
+	var owners []string
+	used := make(map[string]bool)
+	for len(owners) < n {
+		leaf, name, ok := h.walk(h.root, nil, key, depth, used)
+		if !ok {
+			break
+		}
+		used[name] = true
+		owners = append(owners, leaf)
+	}
+	return owners, nil
+}
+
+// Walk the key down from d, at the path given, to an item whose domain at
+// depth is not used, trying children in NextN order. Returns the item and
+// the path of that domain.
+func (h *Hierarchy) walk(d *domain, path []string, key string, depth int, used map[string]bool) (string, string, bool) {
+	level := len(path)
+	for _, label := range d.ring.NextN(key, len(d.children)) {
+		at := append(path[:level:level], label)
+		if level < depth {
+			if leaf, name, ok := h.walk(d.children[label], at, key, depth, used); ok {
+				return leaf, name, true
+			}
+			continue
+		}
+		name := strings.Join(at, "/")
+		if used[name] {
+			continue
+		}
+		if child := d.children[label]; len(child.children) > 0 {
+			return h.descend(child, key), name, true
+		}
+		return label, name, true
+	}
+	return "", "", false
+}
+
+// Follow the key down from d by the ring of each domain to an item.
+func (h *Hierarchy) descend(d *domain, key string) string {
+	var leaf string
+	for len(d.children) > 0 {
+		leaf = d.ring.Get(key)
+		d = d.children[leaf]
+	}
+	return leaf
+}
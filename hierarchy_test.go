@@ -0,0 +1,95 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testHierarchy() *Hierarchy {
+	h := NewHierarchy(nil, []string{"dc", "rack", "host"}, WithReplicas(50))
+	for dc := 0; dc < 2; dc++ {
+		for r := 0; r < 4; r++ {
+			for host := 0; host < 5; host++ {
+				h.AddAt([]string{fmt.Sprint("dc", dc), fmt.Sprintf("rack%d-%d", dc, r), fmt.Sprintf("h%d-%d-%d", dc, r, host)})
+			}
+		}
+	}
+	return h
+}
+
+func TestHierarchicalOwnersStartWithGet(t *testing.T) {
+	h := testHierarchy()
+	keys := testKeys(5000)
+	for level, depth := range map[string]int{"dc": 0, "rack": 1, "host": 2} {
+		for _, k := range keys {
+			owners, err := h.GetOwnersHierarchical(k, 3, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := min(3, []int{2, 8, 40}[depth]); len(owners) != want {
+				t.Fatalf("%s by %s: %d owners, want %d", k, level, len(owners), want)
+			}
+			if owners[0] != h.Get(k) {
+				t.Fatalf("%s by %s: first owner %s, Get %s", k, level, owners[0], h.Get(k))
+			}
+			seen := make(map[string]bool)
+			for _, node := range owners {
+				path, _ := h.Path(node)
+				if seen[path[depth]] {
+					t.Fatalf("%s by %s: owners %v share %s", k, level, owners, path[depth])
+				}
+				seen[path[depth]] = true
+			}
+		}
+	}
+
+	if _, err := h.GetOwnersHierarchical("k", 3, "row"); err != ErrUnknownLevel {
+		t.Fatal(err)
+	}
+	if err := h.AddAt([]string{"dc0", "rack0-0"}); err != ErrInvalidPath {
+		t.Fatal(err)
+	}
+	if err := h.AddAt([]string{"dc9", "rack9", "h0-0-0"}); err != ErrNodeExists {
+		t.Fatal(err)
+	}
+}
+
+func TestHierarchyRemoveStaysInDomain(t *testing.T) {
+	h := testHierarchy()
+	keys := testKeys(5000)
+	before := make(map[string][]string)
+	for _, k := range keys {
+		before[k], _ = h.GetOwnersHierarchical(k, 3, "rack")
+	}
+
+	// Only owners on the removed host move, to its rack
+	h.Remove("h1-2-3")
+	moved := 0
+	for _, k := range keys {
+		owners, _ := h.GetOwnersHierarchical(k, 3, "rack")
+		for i := range owners {
+			if owners[i] == before[k][i] {
+				continue
+			}
+			path, _ := h.Path(owners[i])
+			if before[k][i] != "h1-2-3" || path[1] != "rack1-2" {
+				t.Fatalf("%s: owner %d moved from %s to %s", k, i, before[k][i], owners[i])
+			}
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no owner moved")
+	}
+
+	// Removing the last item of a domain removes the domain
+	for host := 0; host < 5; host++ {
+		h.Remove(fmt.Sprintf("h0-0-%d", host))
+	}
+	for _, k := range keys[:500] {
+		owners, _ := h.GetOwnersHierarchical(k, 8, "rack")
+		if len(owners) != 7 {
+			t.Fatalf("%s: %d owners of 7 racks", k, len(owners))
+		}
+	}
+}
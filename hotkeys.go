@@ -0,0 +1,109 @@
+package consistent
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// Count a sampled fraction of lookups to find the hottest keys. Sampled
+// keys are tracked with the Space-Saving algorithm in space proportional to
+// topK, so counts are estimates; unsampled lookups only pay for a random
+// number. sampleRate is clamped to (0, 1].
+func WithHotKeyTracking(sampleRate float64, topK int) Option {
+	return func(m *Consistent) {
+		if sampleRate <= 0 || topK <= 0 {
+			return
+		}
+		sampleRate = math.Min(sampleRate, 1)
+		m.hot = &hotKeys{
+			rate:      sampleRate,
+			threshold: uint64(sampleRate * (1 << 63) * 2),
+			topK:      topK,
+			size:      4 * topK,
+			counts:    make(map[string]int64, 4*topK),
+		}
+		if sampleRate == 1 {
+			m.hot.threshold = math.MaxUint64
+		}
+	}
+}
+
+// KeyCount is a hot key, the estimated number of lookups of it and the item
+// it currently belongs to.
+type KeyCount struct {
+	Key   string
+	Count int64
+	Node  string
+}
+
+type hotKeys struct {
+	rate      float64
+	threshold uint64 // Sample when a random uint64 is below it
+	topK      int
+
+	sync.Mutex
+	size   int
+	counts map[string]int64
+}
+
+// Count a lookup of key if it is sampled.
+func (m *Consistent) sample(key string) {
+	if m.hot != nil && rand.Uint64() < m.hot.threshold {
+		m.hot.add(key)
+	}
+}
+
+// Add one sampled lookup. When the table is full the key with the smallest
+// count is replaced and its count inherited, which overestimates the new
+// key by at most that count.
+func (h *hotKeys) add(key string) {
+	h.Lock()
+	defer h.Unlock()
+	if _, ok := h.counts[key]; ok || len(h.counts) < h.size {
+		h.counts[key]++
+		return
+	}
+
+	victim, least := "", int64(math.MaxInt64)
+	for k, c := range h.counts {
+		if c < least || c == least && k < victim {
+			victim, least = k, c
+		}
+	}
+	delete(h.counts, victim)
+	h.counts[key] = least + 1
+}
+
+// Get the hottest keys, most looked up first, with their estimated number
+// of lookups. Returns nil unless WithHotKeyTracking was given.
+func (m *Consistent) HotKeys() []KeyCount {
+	if m.hot == nil {
+		return nil
+	}
+
+	m.hot.Lock()
+	hot := make([]KeyCount, 0, len(m.hot.counts))
+	for key, c := range m.hot.counts {
+		hot = append(hot, KeyCount{Key: key, Count: int64(float64(c) / m.hot.rate)})
+	}
+	m.hot.Unlock()
+
+	sort.Slice(hot, func(i, j int) bool {
+		if hot[i].Count != hot[j].Count {
+			return hot[i].Count > hot[j].Count
+		}
+		return hot[i].Key < hot[j].Key
+	})
+	if len(hot) > m.hot.topK {
+		hot = hot[:m.hot.topK]
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	for i := range hot {
+		hot[i].Node = m.ownerOf(hot[i].Key, m.Hash(hot[i].Key))
+	}
+	return hot
+}
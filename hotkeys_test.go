@@ -0,0 +1,67 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// The hottest keys of a Zipf workload surface at the top, with counts near
+// their real number of lookups. Those are the keys with more than 1/20 of
+// the samples, which Space-Saving with 20 counters is sure to keep; the
+// other slots can go to any key.
+func TestHotKeysZipf(t *testing.T) {
+	m := New(nil, WithHotKeyTracking(0.1, 5))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.2, 1, 100000)
+	lookups := make(map[string]int64)
+	for i := 0; i < 500000; i++ {
+		key := fmt.Sprint("key", z.Uint64())
+		lookups[key]++
+		m.Get(key)
+	}
+
+	hot := m.HotKeys()
+	if len(hot) != 5 {
+		t.Fatalf("%d hot keys, want 5", len(hot))
+	}
+	for i, h := range hot[:3] {
+		if h.Key != fmt.Sprint("key", i) {
+			t.Fatalf("hot key %d is %s, want key%d: %v", i, h.Key, i, hot)
+		}
+		if real := lookups[h.Key]; h.Count < real*8/10 || h.Count > real*12/10 {
+			t.Errorf("%s estimated at %d lookups, it had %d", h.Key, h.Count, real)
+		}
+	}
+	for _, h := range hot {
+		if h.Node != m.Get(h.Key) {
+			t.Errorf("%s reported on %s, it belongs to %s", h.Key, h.Node, m.Get(h.Key))
+		}
+	}
+}
+
+func TestHotKeysOptions(t *testing.T) {
+	for _, opt := range []Option{WithHotKeyTracking(0, 5), WithHotKeyTracking(-1, 5), WithHotKeyTracking(0.5, 0)} {
+		m := New(nil, opt)
+		m.Get("key")
+		if hot := m.HotKeys(); hot != nil {
+			t.Fatalf("hot keys without tracking: %v", hot)
+		}
+	}
+
+	// Sampling every lookup, counts are exact while the keys fit
+	m := New(nil, WithHotKeyTracking(2, 2))
+	m.Add("a")
+	for i, key := range []string{"x", "y", "y", "z", "z", "z"} {
+		m.Get(key)
+		if i == 0 {
+			m.Get("x")
+		}
+	}
+	want := []KeyCount{{"z", 3, "a"}, {"x", 2, "a"}}
+	if hot := m.HotKeys(); fmt.Sprint(hot) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", hot, want)
+	}
+}
@@ -0,0 +1,110 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// The sorted points of a ring, indexed both by position and by rank.
+type pointIndex interface {
+	len() int
+	at(i int) (int, point) // The position and point of rank i
+	search(pos int) int    // The rank of the first position at or after pos, or len
+	set(i int, p point)
+	insert(batch []entry) // Positions not yet present, in any order
+	delete(batch []int)   // Positions present, in any order
+}
+
+// Build an index from entries sorted by position.
+func newIndex(tree bool, sorted []entry) pointIndex {
+	if tree {
+		return &treeIndex{root: build(sorted)}
+	}
+	s := &sliceIndex{
+		keys:   make([]int, len(sorted)),
+		points: make([]point, len(sorted)),
+	}
+	for i, e := range sorted {
+		s.keys[i], s.points[i] = e.pos, e.point
+	}
+	return s
+}
+
+// Points in parallel slices sorted by position: the fastest to search, but
+// every insert or delete moves the points after it.
+type sliceIndex struct {
+	keys   []int
+	points []point
+}
+
+func (s *sliceIndex) len() int {
+	return len(s.keys)
+}
+
+func (s *sliceIndex) at(i int) (int, point) {
+	return s.keys[i], s.points[i]
+}
+
+func (s *sliceIndex) search(pos int) int {
+	return sort.SearchInts(s.keys, pos)
+}
+
+func (s *sliceIndex) set(i int, p point) {
+	s.points[i] = p
+}
+
+// A single point is spliced in at its index; a batch is sorted and merged
+// in one pass.
+func (s *sliceIndex) insert(batch []entry) {
+	if len(batch) == 1 {
+		i := sort.SearchInts(s.keys, batch[0].pos)
+		s.keys = append(s.keys, 0)
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = batch[0].pos
+		s.points = append(s.points, point{})
+		copy(s.points[i+1:], s.points[i:])
+		s.points[i] = batch[0].point
+		return
+	}
+
+	sort.Slice(batch, func(i, j int) bool { return batch[i].pos < batch[j].pos })
+	i, j := len(s.keys)-1, len(batch)-1
+	for range batch {
+		s.keys = append(s.keys, 0)
+		s.points = append(s.points, point{})
+	}
+	for k := len(s.keys) - 1; j >= 0; k-- {
+		if i >= 0 && s.keys[i] > batch[j].pos {
+			s.keys[k], s.points[k] = s.keys[i], s.points[i]
+			i--
+		} else {
+			s.keys[k], s.points[k] = batch[j].pos, batch[j].point
+			j--
+		}
+	}
+}
+
+// A batch is sorted in place and removed in one pass.
+func (s *sliceIndex) delete(batch []int) {
+	if len(batch) == 1 {
+		i := sort.SearchInts(s.keys, batch[0])
+		if i < len(s.keys) && s.keys[i] == batch[0] {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			s.points = append(s.points[:i], s.points[i+1:]...)
+		}
+		return
+	}
+
+	sort.Ints(batch)
+	n, j := 0, 0
+	for i, pos := range s.keys {
+		for j < len(batch) && batch[j] < pos {
+			j++
+		}
+		if j < len(batch) && batch[j] == pos {
+			continue
+		}
+		s.keys[n], s.points[n] = pos, s.points[i]
+		n++
+	}
+	s.keys, s.points = s.keys[:n], s.points[:n]
+}
@@ -0,0 +1,55 @@
+package consistent
+
+import (
+	"net"
+	"net/netip"
+)
+
+const (
+	defaultIPv4Bits = 24
+	defaultIPv6Bits = 64
+)
+
+// Get a key for a client address that is the same for every address in its
+// subnet: the address masked to its first v4Bits or v6Bits bits, as a
+// prefix such as "198.51.100.0/24". IPv4-mapped IPv6 addresses are keyed as
+// IPv4. Prefix lengths are clamped to the address size. Returns "" for an
+// invalid address.
+//
+// A net.IP carries no zone, so link-local addresses of different zones get
+// the same key.
+func IPKey(ip net.IP, v4Bits, v6Bits int) string {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := v6Bits
+	if addr.Is4() {
+		bits = v4Bits
+	}
+	prefix, err := addr.Prefix(min(max(bits, 0), addr.BitLen()))
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
+
+// Set the prefix lengths GetIP masks client addresses to. The defaults are
+// 24 and 64.
+func WithIPMask(v4Bits, v6Bits int) Option {
+	return func(m *Consistent) {
+		m.ipBits = [2]int{v4Bits, v6Bits}
+	}
+}
+
+// Get the item for a client address, keyed by IPKey with the prefix lengths
+// of WithIPMask, so clients moving within a subnet keep their item. Returns
+// "" for an invalid address.
+func (m *Consistent) GetIP(ip net.IP) string {
+	key := IPKey(ip, m.ipBits[0], m.ipBits[1])
+	if key == "" {
+		return ""
+	}
+	return m.Get(key)
+}
@@ -0,0 +1,76 @@
+package consistent
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIPKey(t *testing.T) {
+	for _, tc := range []struct {
+		ip             net.IP
+		v4Bits, v6Bits int
+		want           string
+	}{
+		{net.ParseIP("198.51.100.7"), 24, 64, "198.51.100.0/24"},
+		{net.IPv4(198, 51, 100, 7).To4(), 24, 64, "198.51.100.0/24"},
+		{net.ParseIP("::ffff:198.51.100.200"), 24, 64, "198.51.100.0/24"},
+		{net.ParseIP("198.51.100.7"), 16, 64, "198.51.0.0/16"},
+		{net.ParseIP("2001:db8:1:2:3:4:5:6"), 24, 64, "2001:db8:1:2::/64"},
+		{net.ParseIP("2001:db8:1:2:3:4:5:6"), 24, 48, "2001:db8:1::/48"},
+		{net.ParseIP("fe80::1"), 24, 64, "fe80::/64"},
+		{net.ParseIP("10.1.2.3"), 99, -5, "10.1.2.3/32"},
+		{net.ParseIP("10.1.2.3"), -5, 99, "0.0.0.0/0"},
+		{net.ParseIP("::1"), 99, -5, "::/0"},
+		{net.ParseIP("::1"), 24, 200, "::1/128"},
+		{net.IP{1, 2, 3}, 24, 64, ""},
+		{nil, 24, 64, ""},
+	} {
+		if got := IPKey(tc.ip, tc.v4Bits, tc.v6Bits); got != tc.want {
+			t.Errorf("IPKey(%v, %d, %d) = %q, want %q", tc.ip, tc.v4Bits, tc.v6Bits, got, tc.want)
+		}
+	}
+}
+
+func TestGetIP(t *testing.T) {
+	m := New(nil, WithReplicas(50))
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	if a, b := m.GetIP(net.ParseIP("10.0.0.1")), m.GetIP(net.ParseIP("10.0.0.254")); a != b {
+		t.Errorf("one /24 on %s and %s", a, b)
+	}
+	if a, b := m.GetIP(net.ParseIP("2001:db8::1")), m.GetIP(net.ParseIP("2001:db8::ffff:1")); a != b {
+		t.Errorf("one /64 on %s and %s", a, b)
+	}
+	apart := 0
+	for i := 0; i < 50; i++ {
+		last, next := net.IPv4(10, byte(i), 0, 255), net.IPv4(10, byte(i), 1, 0)
+		if got, want := m.GetIP(last), m.Get(fmt.Sprintf("10.%d.0.0/24", i)); got != want {
+			t.Fatalf("GetIP(%v) = %s, want the item of its subnet %s", last, got, want)
+		}
+		if m.GetIP(last) != m.GetIP(next) {
+			apart++
+		}
+	}
+	// Neighbouring subnets land apart about 9 times in 10
+	if apart < 30 {
+		t.Errorf("only %d of 50 neighbouring subnets on different items", apart)
+	}
+	if got := m.GetIP(net.IP{1}); got != "" {
+		t.Errorf("GetIP of an invalid address = %q", got)
+	}
+
+	masked := New(nil, WithReplicas(50), WithIPMask(16, 48))
+	for i := 0; i < 10; i++ {
+		masked.Add(fmt.Sprint("n", i))
+	}
+	for _, tc := range []struct{ ip, key string }{
+		{"10.1.2.3", "10.1.0.0/16"},
+		{"2001:db8:1:2::1", "2001:db8:1::/48"},
+	} {
+		if got, want := masked.GetIP(net.ParseIP(tc.ip)), masked.Get(tc.key); got != want {
+			t.Errorf("GetIP(%s) = %s, want the item of %s, %s", tc.ip, got, tc.key, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package consistent
+
+import (
+	"time"
+)
+
+// Keep the routing of the hash while it has an item to return, and when it
+// empties, such as during a discovery hiccup, let Get return the items of
+// that last routing for up to maxAge. GetStale tells such results apart.
+// The routing is saved as by MarshalFlat after every change, so it costs a
+// copy of the points per change. Other lookups return no item while the
+// hash is empty. A hash that never had an item has nothing to fall back to.
+func WithLastKnownFallback(maxAge time.Duration) Option {
+	return func(m *Consistent) {
+		m.lastKnown = &lastKnown{maxAge: maxAge}
+	}
+}
+
+type lastKnown struct {
+	maxAge  time.Duration
+	ring    *ReadOnlyRing // The last routing with an item, nil if never
+	emptied time.Time     // When the hash emptied, zero while it has an item
+}
+
+// Get the item for a key like Get, and true if it comes from the last
+// routing kept by WithLastKnownFallback because the hash is empty.
+func (m *Consistent) GetStale(key string) (string, bool) {
+	if node := m.get(key); node != "" || m.lastKnown == nil {
+		m.observe(key, node)
+		return node, false
+	}
+	node, stale := m.stale(key)
+	m.observe(key, node)
+	return node, stale
+}
+
+// Look a key up in the last known routing if the hash is empty and it is
+// recent enough.
+func (m *Consistent) stale(key string) (string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	lk := m.lastKnown
+	if lk.ring == nil || lk.emptied.IsZero() || m.clock.Now().Sub(lk.emptied) >= lk.maxAge {
+		return "", false
+	}
+	node := lk.ring.Lookup(uint32(m.Hash(key)))
+	return node, node != ""
+}
+
+// Save the routing after a change if the hash has an item, or note when it
+// emptied. Called under the write lock.
+func (lk *lastKnown) update(m *Consistent) {
+	for _, mem := range m.ring.nodes {
+		if mem.eligible() && len(mem.positions) > 0 {
+			lk.ring, _ = OpenFlat(m.marshalFlat(), *m.hash.Load())
+			lk.emptied = time.Time{}
+			return
+		}
+	}
+	if lk.ring != nil && lk.emptied.IsZero() {
+		lk.emptied = m.clock.Now()
+	}
+}
@@ -0,0 +1,74 @@
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastKnownFallback(t *testing.T) {
+	clock := newTestClock()
+	m := New(nil, WithClock(clock), WithLastKnownFallback(5*time.Second), WithReplicas(10))
+	m.Remove("nothing")
+	if node, stale := m.GetStale("key"); node != "" || stale {
+		t.Fatalf("a hash that never had an item returned %q, %v", node, stale)
+	}
+
+	m.Add("a")
+	m.Add("b")
+	m.Add("c")
+	m.Remove("a")
+	m.SetHealthy("b", false)
+	keys := testKeys(100)
+	want := make(map[string]string, len(keys))
+	for _, key := range keys {
+		want[key] = m.Get(key)
+	}
+
+	// Emptied by the last healthy item going down, the hash falls back
+	m.SetHealthy("c", false)
+	clock.advance(4 * time.Second)
+	for _, key := range keys {
+		if node, stale := m.GetStale(key); node != want[key] || !stale {
+			t.Fatalf("%s: got %q, %v, want %q from the last routing", key, node, stale, want[key])
+		}
+		if node := m.Get(key); node != want[key] {
+			t.Fatalf("%s: Get returned %q, want %q", key, node, want[key])
+		}
+	}
+
+	clock.advance(time.Second)
+	if node, stale := m.GetStale(keys[0]); node != "" || stale {
+		t.Fatalf("after max age got %q, %v", node, stale)
+	}
+
+	// An item coming back is served fresh and becomes the routing kept
+	m.Add("d")
+	if node, stale := m.GetStale(keys[0]); node != "d" || stale {
+		t.Fatalf("got %q, %v, want d fresh", node, stale)
+	}
+	m.Remove("d")
+	m.Remove("b")
+	m.Remove("c")
+	clock.advance(4 * time.Second)
+	if node, stale := m.GetStale(keys[0]); node != "d" || !stale {
+		t.Fatalf("got %q, %v, want d from the last routing", node, stale)
+	}
+	// The age counts from when the hash emptied, not from later changes
+	m.Remove("nothing")
+	clock.advance(time.Second)
+	if node, _ := m.GetStale(keys[0]); node != "" {
+		t.Fatalf("got %q past max age", node)
+	}
+}
+
+func TestGetStaleWithoutFallback(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	if node, stale := m.GetStale("key"); node != "a" || stale {
+		t.Fatalf("got %q, %v", node, stale)
+	}
+	m.Remove("a")
+	if node, stale := m.GetStale("key"); node != "" || stale {
+		t.Fatalf("empty hash without fallback got %q, %v", node, stale)
+	}
+}
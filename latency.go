@@ -0,0 +1,106 @@
+package consistent
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// The weight of each latency report when WithLatencySmoothing is not given.
+const defaultSmoothing = 0.25
+
+// Weight each latency report by alpha in an item's average, between 0 and 1.
+// A larger alpha follows changes sooner.
+func WithLatencySmoothing(alpha float64) Option {
+	return func(m *Consistent) {
+		if alpha > 0 && alpha <= 1 {
+			m.smoothing = alpha
+		}
+	}
+}
+
+// An exponentially weighted moving average of latency reports, starting at
+// the first report.
+type ewma struct {
+	sync.Mutex
+	value float64
+	seen  bool
+}
+
+func (e *ewma) add(sample, alpha float64) {
+	e.Lock()
+	defer e.Unlock()
+	if !e.seen {
+		e.value, e.seen = sample, true
+		return
+	}
+	e.value += alpha * (sample - e.value)
+}
+
+func (e *ewma) get() (float64, bool) {
+	e.Lock()
+	defer e.Unlock()
+	return e.value, e.seen
+}
+
+// Report how long a request to an item took, for GetFastest.
+func (m *Consistent) ReportLatency(node string, d time.Duration) {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[node]; ok {
+		mem.latency.add(float64(d), m.smoothing)
+	}
+}
+
+// Returns the moving average of the latencies reported for an item, and
+// false if none were.
+func (m *Consistent) Latency(node string) (time.Duration, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[node]
+	if !ok {
+		return 0, false
+	}
+	v, ok := mem.latency.get()
+	return time.Duration(v), ok
+}
+
+// Get the item for a key among its first k items in NextN order, skipping
+// standbys and unhealthy items. The first is returned unless the average
+// latency of another is lower by more than maxSkew, as a fraction of its
+// own, so a maxSkew of 0.2 moves the key once the first item is 20% slower.
+// The band keeps small fluctuations from moving keys between items, and the
+// key returns to the first item as soon as it recovers. Items without
+// reports are never preferred to the first. Returns "" if there are no
+// eligible items.
+func (m *Consistent) GetFastest(key string, k int, maxSkew float64) string {
+	nodes := m.nextNExcluding(key, max(k, 1), func(node string) bool {
+		return !m.ring.nodes[node].eligible()
+	})
+	if len(nodes) == 0 {
+		return ""
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	latencyOf := func(node string) (float64, bool) {
+		if mem, ok := m.ring.nodes[node]; ok {
+			return mem.latency.get()
+		}
+		return 0, false
+	}
+	primary, ok := latencyOf(nodes[0])
+	if !ok {
+		return nodes[0]
+	}
+	best, fastest := math.Inf(1), nodes[0]
+	for _, node := range nodes[1:] {
+		if v, ok := latencyOf(node); ok && v < best {
+			best, fastest = v, node
+		}
+	}
+	if primary > best*(1+max(maxSkew, 0)) {
+		return fastest
+	}
+	return nodes[0]
+}
@@ -0,0 +1,108 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLatencyAverage(t *testing.T) {
+	m := New(nil, WithLatencySmoothing(0.5))
+	m.Add("a")
+	if _, ok := m.Latency("a"); ok {
+		t.Fatal("latency before any report")
+	}
+	m.ReportLatency("missing", time.Second)
+	if _, ok := m.Latency("missing"); ok {
+		t.Fatal("latency for an item not in the hash")
+	}
+	for _, tc := range []struct {
+		report, want time.Duration
+	}{
+		{10 * time.Millisecond, 10 * time.Millisecond}, // The first report is taken as is
+		{20 * time.Millisecond, 15 * time.Millisecond},
+		{20 * time.Millisecond, 17500 * time.Microsecond},
+		{0, 8750 * time.Microsecond},
+	} {
+		m.ReportLatency("a", tc.report)
+		if got, ok := m.Latency("a"); !ok || got != tc.want {
+			t.Fatalf("after %v: got %v, want %v", tc.report, got, tc.want)
+		}
+	}
+
+	// Out of range smoothing keeps the default
+	d := New(nil, WithLatencySmoothing(0), WithLatencySmoothing(1.5))
+	d.Add("a")
+	d.ReportLatency("a", 0)
+	d.ReportLatency("a", 100*time.Millisecond)
+	if got, _ := d.Latency("a"); got != 25*time.Millisecond {
+		t.Fatalf("default smoothing gave %v", got)
+	}
+}
+
+func TestGetFastest(t *testing.T) {
+	m := New(nil, WithReplicas(10), WithLatencySmoothing(1))
+	for i := 0; i < 5; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	c := m.NextN("k", 3)
+	report := func(node string, ms float64) {
+		m.ReportLatency(node, time.Duration(ms*float64(time.Millisecond)))
+	}
+	check := func(k int, want string) {
+		t.Helper()
+		if got := m.GetFastest("k", k, 0.2); got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+
+	// Without reports, and while only backups have them, the primary is kept
+	check(3, c[0])
+	report(c[1], 1)
+	check(3, c[0])
+
+	report(c[0], 10)
+	report(c[1], 9)
+	report(c[2], 20)
+	check(3, c[0])
+
+	// The band: 20% slower than the fastest backup is still kept
+	report(c[0], 10.8)
+	check(3, c[0])
+	report(c[0], 10.9)
+	check(3, c[1])
+	report(c[2], 8)
+	check(3, c[2])
+
+	// Only the first k owners are candidates
+	check(2, c[1])
+	check(1, c[0])
+	for _, node := range m.Members() {
+		if !slices.Contains(c, node) {
+			report(node, 0.001)
+		}
+	}
+	check(3, c[2])
+
+	// The primary recovering takes the key back
+	report(c[0], 9)
+	check(3, c[0])
+
+	// Unhealthy owners are skipped, and the next owner fills in
+	report(c[0], 50)
+	m.SetHealthy(c[2], false)
+	owners := m.nextNExcluding("k", 3, func(node string) bool { return node == c[2] })
+	if slices.Contains(owners, c[2]) || slices.Contains(c, owners[2]) {
+		t.Fatalf("owners %v after %s went down", owners, c[2])
+	}
+	check(3, owners[2])
+
+	m.Remove(c[1])
+	for _, node := range m.Members() {
+		m.SetHealthy(node, false)
+	}
+	if got := m.GetFastest("k", 3, 0.2); got != "" {
+		t.Fatalf("got %s from a hash with no healthy items", got)
+	}
+}
@@ -0,0 +1,103 @@
+package consistent
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var ErrAllAtCapacity = errors.New("consistent: all items are at capacity")
+
+// Record that an item took on one more unit of load, such as a session.
+func (m *Consistent) Inc(key string) {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[key]; ok {
+		atomic.AddInt64(&mem.load, 1)
+	}
+}
+
+// Record that an item finished one unit of load.
+func (m *Consistent) Done(key string) {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[key]; ok {
+		atomic.AddInt64(&mem.load, -1)
+	}
+}
+
+// Returns the current load of an item.
+func (m *Consistent) Load(key string) int64 {
+	m.RLock()
+	defer m.RUnlock()
+	if mem, ok := m.ring.nodes[key]; ok {
+		return atomic.LoadInt64(&mem.load)
+	}
+	return 0
+}
+
+// Limit the load an item takes in GetWithinCapacity, in the units of the
+// load model. Zero removes the limit.
+func (m *Consistent) SetCapacity(key string, capacity int64) error {
+	m.Lock()
+	defer m.Unlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	mem.capacity = capacity
+	return nil
+}
+
+// Get the first item in NextN order for the provided key whose load is below
+// its capacity. While the key's item has headroom this is the same as Get,
+// pins included; otherwise the key spills clockwise. Standbys and unhealthy
+// items are never chosen. A key a strict pin leaves without an item gets
+// ErrEmpty, as if the hash were empty.
+func (m *Consistent) GetWithinCapacity(key string) (string, error) {
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return "", ErrEmpty
+	}
+
+	node, tried := "", false
+	m.ownerCandidates(key, hash, func(key string, first bool) bool {
+		tried = true
+		mem := m.ring.nodes[key]
+		if !first && !mem.eligible() || m.atCapacity(mem) {
+			return true
+		}
+		node = key
+		return false
+	})
+	switch {
+	case node != "":
+		return node, nil
+	case !tried:
+		return "", ErrEmpty
+	}
+	return "", ErrAllAtCapacity
+}
+
+// Visit the distinct items for a key in NextN order, starting with the item
+// Get returns, pins included, until fn returns false. That first item may be
+// a half-open one Get trickles the key to. A strict pin without an item
+// leaves nothing to visit. Called under the read lock.
+func (m *Consistent) ownerCandidates(key string, hash int, fn func(node string, first bool) bool) {
+	pinned, ok := m.pinnedHash(key, hash)
+	if ok && (pinned == "" || !fn(pinned, true)) {
+		return
+	}
+	primary := -1
+	if !ok {
+		primary = m.lookup(hash)
+	}
+	m.candidates(hash, func(i int) bool {
+		node := m.ring.nodeAt(i)
+		return node == pinned || fn(node, i == primary)
+	})
+}
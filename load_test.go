@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestWithinCapacityMatchesGet(t *testing.T) {
+	clock := newTestClock()
+	halfOpen := ejectionRing(clock)
+	for i := 0; i < 3; i++ {
+		halfOpen.ReportFailure("b")
+	}
+	clock.advance(10 * time.Second)
+	halfOpen.Get("k")
+
+	keys := testKeys(5000)
+	for name, c := range map[string]*Consistent{
+		"pinned":    pinnedRing(),
+		"strict":    pinnedRing(WithStrictPins()),
+		"half-open": halfOpen,
+		"standby":   standbyRing(),
+	} {
+		c.SetHealthy("n3", false)
+		for _, node := range c.Members() {
+			c.SetCapacity(node, 1000)
+		}
+		for _, k := range keys {
+			node, err := c.GetWithinCapacity(k)
+			if want := c.Get(k); node != want || want == "" && err != ErrEmpty || want != "" && err != nil {
+				t.Fatalf("%s: %s: %q, %v under capacity, Get %q", name, k, node, err, want)
+			}
+			least, err := c.GetLeastLoaded(k, 3)
+			if want := c.Get(k); least != want || want == "" && err != ErrEmpty {
+				t.Fatalf("%s: %s: least loaded %q, %v with no load, Get %q", name, k, least, err, want)
+			}
+		}
+	}
+}
+
+func TestWithinCapacitySpills(t *testing.T) {
+	c := New(nil, WithReplicas(20))
+	for i := 0; i < 4; i++ {
+		c.Add(fmt.Sprint("n", i))
+		c.SetCapacity(fmt.Sprint("n", i), 2)
+	}
+	c.AddStandby("s")
+	c.Pin("pinned", "n2")
+	keys := testKeys(1000)
+
+	// A full item's keys go to the next item in NextN order with headroom
+	c.Inc("n2")
+	c.Inc("n2")
+	for _, k := range append(keys, "pinned") {
+		node, err := c.GetWithinCapacity(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := c.Get(k)
+		if want == "n2" {
+			next := c.NextN(k, 5)
+			want = next[1]
+			if want == "s" {
+				want = next[2]
+			}
+		}
+		if node != want {
+			t.Fatalf("%s: %s with n2 full, want %s", k, node, want)
+		}
+	}
+
+	// Filling every item leaves nothing to return
+	taken := 0
+	for _, k := range keys {
+		node, err := c.GetWithinCapacity(k)
+		if err == ErrAllAtCapacity {
+			break
+		}
+		if err != nil || node == "s" {
+			t.Fatal(node, err)
+		}
+		c.Inc(node)
+		taken++
+	}
+	if taken != 6 {
+		t.Fatalf("%d keys placed in capacity for 6", taken)
+	}
+	if _, err := c.GetWithinCapacity("pinned"); err != ErrAllAtCapacity {
+		t.Fatal(err)
+	}
+	if _, err := New(nil).GetWithinCapacity("k"); err != ErrEmpty {
+		t.Fatal(err)
+	}
+	if err := c.SetCapacity("nope", 1); err != ErrNodeNotFound {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package consistent
+
+import (
+	"sync"
+)
+
+// Migrator routes keys while moving from one hash to another, such as when
+// changing the hash function or the replicas: writes go to the new hash,
+// and reads try the new hash before the old one, so keys written before the
+// migration can still be found.
+type Migrator struct {
+	mu       sync.RWMutex
+	old, new *Consistent // old is nil once the migration is complete
+}
+
+func NewMigrator(old, new *Consistent) *Migrator {
+	return &Migrator{old: old, new: new}
+}
+
+// Get the item a key should be written to.
+func (g *Migrator) WriteOwner(key string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.new.Get(key)
+}
+
+// Get the items to read a key from, in order: the new owner, then the old
+// owner if it differs. Once the migration is complete only the new owner is
+// returned.
+func (g *Migrator) ReadOwners(key string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var owners []string
+	if node := g.new.Get(key); node != "" {
+		owners = append(owners, node)
+	}
+	if g.old != nil {
+		if node := g.old.Get(key); node != "" && (len(owners) == 0 || owners[0] != node) {
+			owners = append(owners, node)
+		}
+	}
+	return owners
+}
+
+// Returns the fraction of keys whose old owner differs from the new one, so
+// reads written before the migration need the fallback. It is zero once the
+// migration is complete.
+func (g *Migrator) Pending(keys []string) float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.old == nil || len(keys) == 0 {
+		return 0
+	}
+	moved := 0
+	for _, key := range keys {
+		if g.old.Get(key) != g.new.Get(key) {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(keys))
+}
+
+// Finish the migration, dropping the old hash.
+func (g *Migrator) Complete() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.old = nil
+}
+
+// Returns true once Complete has been called.
+func (g *Migrator) Completed() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.old == nil
+}
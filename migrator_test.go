@@ -0,0 +1,70 @@
+package consistent
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestMigrator(t *testing.T) {
+	old, new := New(nil, WithReplicas(10)), New(fnv32, WithReplicas(10))
+	for i := 0; i < 5; i++ {
+		old.Add(fmt.Sprint("n", i))
+		new.Add(fmt.Sprint("n", i))
+	}
+	g := NewMigrator(old, new)
+	keys := testKeys(1000)
+	moved := 0
+	for _, key := range keys {
+		if got := g.WriteOwner(key); got != new.Get(key) {
+			t.Fatalf("%s written to %s, want the new owner %s", key, got, new.Get(key))
+		}
+		want := []string{new.Get(key)}
+		if old.Get(key) != new.Get(key) {
+			want = append(want, old.Get(key))
+			moved++
+		}
+		if got := g.ReadOwners(key); !slices.Equal(got, want) {
+			t.Fatalf("%s read from %v, want %v", key, got, want)
+		}
+	}
+	if moved == 0 || moved == len(keys) {
+		t.Fatalf("%d of %d keys moved", moved, len(keys))
+	}
+	if got, want := g.Pending(keys), float64(moved)/float64(len(keys)); got != want {
+		t.Fatalf("%v of the keys pending, want %v", got, want)
+	}
+	if got := g.Pending(nil); got != 0 {
+		t.Fatalf("%v of no keys pending", got)
+	}
+
+	if g.Completed() {
+		t.Fatal("completed before Complete")
+	}
+	g.Complete()
+	if !g.Completed() || g.Pending(keys) != 0 {
+		t.Fatal("a completed migration has keys pending")
+	}
+	for _, key := range keys[:100] {
+		if got := g.ReadOwners(key); !slices.Equal(got, []string{new.Get(key)}) {
+			t.Fatalf("%s read from %v after the migration", key, got)
+		}
+	}
+}
+
+// Reads fall back while either hash has no item for a key.
+func TestMigratorEmpty(t *testing.T) {
+	old, new := New(nil), New(nil)
+	g := NewMigrator(old, new)
+	if got := g.ReadOwners("key"); len(got) != 0 || g.WriteOwner("key") != "" {
+		t.Fatalf("both empty: read from %v, written to %q", got, g.WriteOwner("key"))
+	}
+	old.Add("a")
+	if got := g.ReadOwners("key"); !slices.Equal(got, []string{"a"}) || g.WriteOwner("key") != "" {
+		t.Fatalf("empty new hash: read from %v, written to %q", got, g.WriteOwner("key"))
+	}
+	new.Add("b")
+	if got := g.ReadOwners("key"); !slices.Equal(got, []string{"b", "a"}) {
+		t.Fatalf("read from %v, want b then a", got)
+	}
+}
@@ -0,0 +1,178 @@
+package consistent
+
+import (
+	"fmt"
+)
+
+// Owner is the point on the ring that a lookup matched.
+type Owner struct {
+	Node     string
+	Position int
+	Replica  int // Which of the node's points matched, see AddWithWeight
+}
+
+func (r *ring) owner(i int) Owner {
+	pos, p := r.index.at(i)
+	return Owner{Node: r.names[p.node], Position: pos, Replica: int(p.replica)}
+}
+
+// Get the point in the hash the provided key is in the range of.
+func (m *Consistent) GetOwner(key string) (Owner, bool) {
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if node, ok := m.pinned(key); ok {
+		return m.ring.pinOwner(node)
+	}
+	if m.ring.size() == 0 {
+		return Owner{}, false
+	}
+
+	i := m.lookup(hash)
+	if i < 0 {
+		return Owner{}, false
+	}
+
+	return m.ring.owner(i), true
+}
+
+// Get up to n distinct items for the provided key: the item it is in the
+// range of, followed by the next items clockwise. A count above the number
+// of items returns every item once, and a count of zero an empty slice
+// without a lookup. Panics if n is negative; see TryNextN.
+func (m *Consistent) NextN(key string, n int) []string {
+	owners := m.NextNOwners(key, n)
+	nodes := make([]string, len(owners))
+	for i, o := range owners {
+		nodes[i] = o.Node
+	}
+	return nodes
+}
+
+// Get the points behind NextN: the point serving the provided key, which is
+// never a standby's, then the first point of each other distinct item
+// clockwise from the key.
+func (m *Consistent) NextNOwners(key string, n int) []Owner {
+	if checkCount(n) {
+		return []Owner{}
+	}
+	owners := m.nextNOwners(key, n)
+	if len(owners) > 0 {
+		m.observe(key, owners[0].Node)
+	} else {
+		m.observe(key, "")
+	}
+	return owners
+}
+
+func (m *Consistent) nextNOwners(key string, n int) []Owner {
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return []Owner{}
+	}
+
+	owners := make([]Owner, 0, min(n, len(m.ring.nodes)))
+	pinned, ok := m.pinned(key)
+	if ok {
+		if pinned == "" {
+			return owners
+		}
+		o, _ := m.ring.pinOwner(pinned)
+		owners = append(owners, o)
+		if n == 1 {
+			return owners
+		}
+	}
+	m.candidates(hash, func(i int) bool {
+		if o := m.ring.owner(i); o.Node != pinned {
+			owners = append(owners, o)
+		}
+		return len(owners) < n
+	})
+
+	return owners
+}
+
+// Get up to n distinct items counter-clockwise from the provided key,
+// starting with the item Get returns for it. The count is treated as by
+// NextN. Panics if n is negative; see TryPrevN.
+func (m *Consistent) PrevN(key string, n int) []string {
+	return m.PrevNDistinct(key, n, true)
+}
+
+// Get up to n distinct items counter-clockwise from the provided key. With
+// includeSelf the item Get returns for the key comes first, otherwise it
+// is skipped entirely and n other items are returned if there are enough.
+func (m *Consistent) PrevNDistinct(key string, n int, includeSelf bool) []string {
+	if checkCount(n) {
+		return []string{}
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return []string{}
+	}
+
+	// Start at the item Get returns, like NextN
+	start := m.ring.prevIndex(hash)
+	self, pinned := m.pinnedHash(key, hash)
+	if i := m.lookup(hash); !pinned && i >= 0 {
+		start, self = i, m.ring.nodeAt(i)
+	} else if !pinned {
+		self = m.ring.nodeAt(start)
+	}
+	nodes := make([]string, 0, min(n, len(m.ring.nodes)))
+	if includeSelf && self != "" {
+		if nodes = append(nodes, self); n == 1 {
+			return nodes
+		}
+	}
+	seen := map[string]bool{self: true}
+	m.ring.walkBack(start, func(i int) bool {
+		node := m.ring.nodeAt(i)
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+		return len(nodes) < n
+	})
+
+	return nodes
+}
+
+// Like NextN, but returns ErrInvalidCount instead of panicking if n is
+// negative.
+func (m *Consistent) TryNextN(key string, n int) ([]string, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCount, n)
+	}
+	return m.NextN(key, n), nil
+}
+
+// Like PrevN, but returns ErrInvalidCount instead of panicking if n is
+// negative.
+func (m *Consistent) TryPrevN(key string, n int) ([]string, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidCount, n)
+	}
+	return m.PrevN(key, n), nil
+}
+
+// Returns true if n is zero, so there is nothing to look up. Panics if n
+// is negative.
+func checkCount(n int) bool {
+	if n < 0 {
+		panic(fmt.Sprintf("consistent: negative count %d", n))
+	}
+	return n == 0
+}
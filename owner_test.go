@@ -0,0 +1,177 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCountBoundaries(t *testing.T) {
+	for _, items := range []int{0, 1, 3} {
+		c := New(nil, WithReplicas(3))
+		for i := 0; i < items; i++ {
+			c.Add(fmt.Sprint("n", i))
+		}
+		for _, n := range []int{0, 1, 2, 3, 4, 100} {
+			want := min(n, items)
+			lists := map[string][]string{
+				"NextN":         c.NextN("k", n),
+				"PrevN":         c.PrevN("k", n),
+				"PrevNDistinct": c.PrevNDistinct("k", n, true),
+			}
+			for name, got := range lists {
+				if got == nil || len(got) != want {
+					t.Fatalf("%d items: %s(%d) = %#v, want %d items", items, name, n, got, want)
+				}
+				seen := make(map[string]bool)
+				for _, node := range got {
+					if seen[node] {
+						t.Fatalf("%d items: %s(%d) repeats %s in %v", items, name, n, node, got)
+					}
+					seen[node] = true
+				}
+				if want > 0 && got[0] != c.Get("k") {
+					t.Fatalf("%d items: %s(%d) starts with %s, Get %s", items, name, n, got[0], c.Get("k"))
+				}
+			}
+			if got := c.NextNOwners("k", n); got == nil || len(got) != want {
+				t.Fatalf("%d items: NextNOwners(%d) = %#v, want %d owners", items, n, got, want)
+			}
+			if got := c.PrevNDistinct("k", n, false); got == nil || len(got) != min(n, max(items-1, 0)) {
+				t.Fatalf("%d items: PrevNDistinct(%d, false) = %#v", items, n, got)
+			}
+			for name, try := range map[string]func(string, int) ([]string, error){"TryNextN": c.TryNextN, "TryPrevN": c.TryPrevN} {
+				if got, err := try("k", n); err != nil || len(got) != want {
+					t.Fatalf("%d items: %s(%d) = %v, %v", items, name, n, got, err)
+				}
+			}
+		}
+
+		for name, try := range map[string]func(string, int) ([]string, error){"TryNextN": c.TryNextN, "TryPrevN": c.TryPrevN} {
+			if _, err := try("k", -1); !errors.Is(err, ErrInvalidCount) {
+				t.Fatalf("%d items: %s(-1) = %v", items, name, err)
+			}
+		}
+		for name, f := range map[string]func(){
+			"NextN":         func() { c.NextN("k", -1) },
+			"PrevN":         func() { c.PrevN("k", -1) },
+			"NextNOwners":   func() { c.NextNOwners("k", -1) },
+			"PrevNDistinct": func() { c.PrevNDistinct("k", -1, false) },
+		} {
+			func() {
+				defer func() {
+					if r := recover(); fmt.Sprint(r) != "consistent: negative count -1" {
+						t.Fatalf("%d items: %s(-1) recovered %v", items, name, r)
+					}
+				}()
+				f()
+			}()
+		}
+	}
+}
+
+func TestGetOwnerAgreesWithGet(t *testing.T) {
+	clock := newTestClock()
+	halfOpen := ejectionRing(clock)
+	for i := 0; i < 3; i++ {
+		halfOpen.ReportFailure("b")
+	}
+	clock.advance(10 * time.Second)
+	halfOpen.Get("k")
+	if s := halfOpen.BreakerState("b"); s != BreakerHalfOpen {
+		t.Fatalf("breaker %v, want half-open", s)
+	}
+	unhealthy := standbyRing()
+	unhealthy.SetHealthy("n2", false)
+
+	keys := testKeys(5000)
+	for name, c := range map[string]*Consistent{
+		"standby and unhealthy": unhealthy,
+		"pinned":                pinnedRing(),
+		"half-open":             halfOpen,
+		"tiered":                tieredRing(),
+	} {
+		f := NewFailover(c)
+		for _, k := range keys {
+			node := c.Get(k)
+			if o, ok := c.GetOwner(k); !ok || o.Node != node {
+				t.Fatalf("%s: %s: GetOwner %s, Get %s", name, k, o.Node, node)
+			}
+			if next := c.NextN(k, 2); next[0] != node {
+				t.Fatalf("%s: %s: NextN %v, Get %s", name, k, next, node)
+			}
+			if prev := c.PrevN(k, 1); prev[0] != node {
+				t.Fatalf("%s: %s: PrevN %v, Get %s", name, k, prev, node)
+			}
+			if got := f.Get(k); got != node {
+				t.Fatalf("%s: %s: Failover %s, Get %s", name, k, got, node)
+			}
+		}
+	}
+}
+
+func TestPrevNDistinct(t *testing.T) {
+	// Walking back from 350: c at 300, a at 250, b at 200, a at 100, d at 400
+	c := New(fixedHash(map[string]uint32{"a#1": 100, "a": 250, "b": 200, "c": 300, "d": 400}))
+	c.AddWithWeight("a", 2)
+	c.Add("b")
+	c.Add("c")
+	c.Add("d")
+	for _, tc := range []struct {
+		key         string
+		n           int
+		includeSelf bool
+		want        []string
+	}{
+		{"350", 10, true, []string{"c", "a", "b", "d"}},
+		{"350", 2, true, []string{"c", "a"}},
+		{"350", 1, true, []string{"c"}},
+		{"350", 3, false, []string{"a", "b", "d"}},
+		{"350", 10, false, []string{"a", "b", "d"}}, // Every other item, and no more
+		{"350", 1, false, []string{"a"}},
+		{"350", 0, false, []string{}},
+		{"50", 10, false, []string{"c", "a", "b"}}, // Wrapping: 50 belongs to d at 400
+		{"250", 2, true, []string{"a", "b"}},
+	} {
+		if got := c.PrevNDistinct(tc.key, tc.n, tc.includeSelf); !slices.Equal(got, tc.want) {
+			t.Errorf("PrevNDistinct(%s, %d, %v) = %v, want %v", tc.key, tc.n, tc.includeSelf, got, tc.want)
+		}
+		if tc.includeSelf {
+			if got := c.PrevN(tc.key, tc.n); !slices.Equal(got, tc.want) {
+				t.Errorf("PrevN(%s, %d) = %v, want %v", tc.key, tc.n, got, tc.want)
+			}
+		}
+	}
+
+	// A pinned key starts at its pin
+	c.Pin("350", "d")
+	if got := c.PrevNDistinct("350", 10, true); len(got) != 4 || got[0] != "d" {
+		t.Errorf("pinned to d: PrevN = %v", got)
+	}
+	if got := c.PrevNDistinct("350", 10, false); slices.Contains(got, "d") || len(got) != 3 {
+		t.Errorf("pinned to d: PrevNDistinct without self = %v", got)
+	}
+
+	if got := New(nil).PrevNDistinct("key", 3, false); len(got) != 0 {
+		t.Errorf("an empty hash returned %v", got)
+	}
+}
+
+// Leaving out the key's own item comes to the same as taking one more and
+// dropping the first.
+func TestPrevNDistinctWithoutSelf(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 8; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	for _, key := range testKeys(500) {
+		for n := 1; n <= 8; n++ {
+			with := m.PrevN(key, n+1)
+			if got := m.PrevNDistinct(key, n, false); !slices.Equal(got, with[1:]) {
+				t.Fatalf("%s: PrevNDistinct(%d) = %v, PrevN(%d) = %v", key, n, got, n+1, with)
+			}
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package consistent
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// Buffers for encoding multi-part keys and replica names, so hashing them
+// does not allocate.
+var partsPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// Hash a key made of several parts. Each part, after the key transform, is
+// encoded as its length in bytes as a 4-byte big-endian integer followed by
+// its bytes, and the concatenated encoding is hashed. Parts can therefore
+// contain any bytes: ("a/b", "c") and ("a", "b/c") never collide by
+// construction. A single part does not hash like Hash of that part.
+func (m *Consistent) HashParts(parts ...string) int {
+	bp := partsPool.Get().(*[]byte)
+	b := (*bp)[:0]
+	if m.domains {
+		b = append(b, keyDomain...)
+	}
+	for _, part := range parts {
+		if m.transform != nil {
+			part = m.transform(part)
+		}
+		b = binary.BigEndian.AppendUint32(b, uint32(len(part)))
+		b = append(b, part...)
+	}
+	hash := int(m.sum(b))
+	*bp = b
+	partsPool.Put(bp)
+	return hash
+}
+
+// Get the item in the hash a key made of several parts is in the range of.
+// The parts are hashed as described by HashParts. Range pins apply; pins of
+// single keys do not.
+func (m *Consistent) GetParts(parts ...string) string {
+	if t := m.table.Load(); t != nil {
+		if node, ok := t.get(m.HashParts(parts...)); ok && t.current(m) {
+			return node
+		}
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.HashParts(parts...)
+	if node, ok := m.rangePinned(hash); ok {
+		return node
+	}
+	return m.lookupNode(hash)
+}
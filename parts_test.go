@@ -0,0 +1,102 @@
+package consistent
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A hash recording the bytes of the last key it hashed.
+func recordingHash(last *string) Hash {
+	return func(b []byte) uint32 {
+		*last = string(b)
+		return fnv32(b)
+	}
+}
+
+func TestHashPartsEncoding(t *testing.T) {
+	var last string
+	m := New(recordingHash(&last))
+	for _, tc := range []struct {
+		parts []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{""}, "\x00\x00\x00\x00"},
+		{[]string{"ab"}, "\x00\x00\x00\x02ab"},
+		{[]string{"a", "b/c"}, "\x00\x00\x00\x01a\x00\x00\x00\x03b/c"},
+		{[]string{strings.Repeat("x", 300)}, "\x00\x00\x01\x2c" + strings.Repeat("x", 300)},
+	} {
+		h := m.HashParts(tc.parts...)
+		if last != tc.want {
+			t.Errorf("%q encoded as %q, want %q", tc.parts, last, tc.want)
+		}
+		if h != int(fnv32([]byte(tc.want))) {
+			t.Errorf("%q hashed to %d, not the hash of its encoding", tc.parts, h)
+		}
+	}
+
+	d := New(recordingHash(&last), WithDomainSeparation(), WithKeyTransform(strings.ToLower))
+	d.HashParts("A", "b")
+	if want := keyDomain + "\x00\x00\x00\x01a\x00\x00\x00\x01b"; last != want {
+		t.Errorf("encoded as %q, want %q", last, want)
+	}
+}
+
+// Splits of the same bytes, and the same text with a filler part, encode
+// apart, where joining with a separator would not.
+func TestHashPartsUnambiguous(t *testing.T) {
+	var last string
+	m := New(recordingHash(&last))
+	for _, group := range [][][]string{
+		{{"a/b", "c"}, {"a", "b/c"}, {"a/b/c"}, {"a", "b", "c"}},
+		{{"ab"}, {"a", "b"}, {"ab", ""}, {"", "ab"}, {"a", "", "b"}},
+		{{}, {""}, {"", ""}},
+		{{"\x00\x00\x00\x01a"}, {"a"}},
+	} {
+		seen := make(map[string][]string)
+		for _, parts := range group {
+			m.HashParts(parts...)
+			if other, ok := seen[last]; ok {
+				t.Errorf("%q and %q encode the same", parts, other)
+			}
+			seen[last] = parts
+		}
+	}
+}
+
+func TestGetParts(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	if node := m.GetParts("tenant", "table", "pk"); node != "" {
+		t.Fatalf("an empty hash returned %q", node)
+	}
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	table := New(nil, WithReplicas(20), WithLookupTable(8))
+	for i := 0; i < 10; i++ {
+		table.Add(fmt.Sprint("n", i))
+	}
+	for i := 0; i < 1000; i++ {
+		parts := []string{"tenant", fmt.Sprint("table", i%7), fmt.Sprint(i)}
+		m.RLock()
+		want := m.lookupNode(m.HashParts(parts...))
+		m.RUnlock()
+		if got := m.GetParts(parts...); got != want {
+			t.Fatalf("%q: got %s, want %s", parts, got, want)
+		}
+		if got := table.GetParts(parts...); got != want {
+			t.Fatalf("%q: got %s with a lookup table, want %s", parts, got, want)
+		}
+	}
+
+	for _, c := range []*Consistent{m, table} {
+		allocs := testing.AllocsPerRun(1000, func() {
+			c.GetParts("tenant", "table", "pk")
+			c.HashParts("tenant", "table", "pk")
+		})
+		if allocs != 0 {
+			t.Fatalf("%v allocations per lookup", allocs)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package consistent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+var (
+	ErrCorrupt  = errors.New("consistent: ring file is corrupt")
+	ErrMismatch = errors.New("consistent: ring file does not match the options")
+)
+
+// The string hashed into Snapshot.HashCheck to tell hash functions apart.
+const hashProbe = "consistent-hash"
+
+// The version of the ring file format written by Save.
+const fileVersion = 1
+
+// Snapshot is the serializable state of a hash: every point with the
+// replica that placed it, so a restored hash routes exactly like the one
+// it was taken from.
+type Snapshot struct {
+	Generation uint64            `json:"generation"`
+	Replicas   int               `json:"replicas"`
+	Hash       string            `json:"hash,omitempty"` // A name from HashNames, empty for a hash function not chosen by name
+	Seed       uint64            `json:"seed,omitempty"`
+	HashCheck  uint32            `json:"hash_check"` // The hash of a fixed probe string
+	Members    []MemberState     `json:"members"`
+	Pins       map[string]string `json:"pins,omitempty"`       // Lookup key to item
+	RangePins  []RangePinState   `json:"range_pins,omitempty"` // Sorted by From
+
+	DomainSeparation bool `json:"domain_separation,omitempty"` // See WithDomainSeparation
+}
+
+// MemberState is the saved state of a single key.
+type MemberState struct {
+	Name    string       `json:"name"`
+	Weight  int          `json:"weight"`
+	Standby bool         `json:"standby,omitempty"`
+	Zone    string       `json:"zone,omitempty"`
+	Tier    int          `json:"tier,omitempty"`
+	Points  []PointState `json:"points"`
+
+	ExplicitWeight bool `json:"explicit_weight,omitempty"` // Kept by SetReplicas
+}
+
+// RangePinState is a range of hashes pinned to an item, see PinRange.
+type RangePinState struct {
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Node string `json:"node"`
+}
+
+// PointState is a position on the ring and the replica that placed it.
+type PointState struct {
+	Position int `json:"position"`
+	Replica  int `json:"replica"`
+}
+
+// The envelope written by Save. The checksum covers the ring bytes, so a
+// torn or edited file is rejected by Load.
+type ringFile struct {
+	Version  int             `json:"version"`
+	Checksum uint32          `json:"checksum"`
+	Ring     json.RawMessage `json:"ring"`
+}
+
+// Get the state of the hash, with members sorted by name and points by
+// position.
+func (m *Consistent) Snapshot() Snapshot {
+	m.RLock()
+	defer m.RUnlock()
+
+	s := Snapshot{
+		Generation: m.generation,
+		Replicas:   m.replicas,
+		Hash:       m.hashName,
+		Seed:       m.seed,
+		HashCheck:  uint32(m.nodeHash(hashProbe)),
+		Members:    make([]MemberState, 0, len(m.ring.nodes)),
+
+		DomainSeparation: m.domains,
+	}
+	for key, mem := range m.ring.nodes {
+		ms := MemberState{
+			Name:    key,
+			Weight:  mem.weight,
+			Standby: mem.standby,
+			Zone:    mem.zone,
+			Tier:    mem.tier,
+			Points:  make([]PointState, 0, len(mem.positions)),
+
+			ExplicitWeight: mem.explicit,
+		}
+		for _, pos := range mem.positions {
+			p, _ := m.ring.pointAt(pos)
+			ms.Points = append(ms.Points, PointState{Position: pos, Replica: int(p.replica)})
+		}
+		s.Members = append(s.Members, ms)
+	}
+	sort.Slice(s.Members, func(i, j int) bool { return s.Members[i].Name < s.Members[j].Name })
+	for key, node := range m.ring.pins {
+		if s.Pins == nil {
+			s.Pins = make(map[string]string, len(m.ring.pins))
+		}
+		s.Pins[key] = node
+	}
+	s.RangePins = m.ring.rangePinStates()
+
+	return s
+}
+
+// Save the hash to a file. The file is written to a temporary file in the
+// same directory and renamed into place, so readers never see a partial
+// ring.
+func (m *Consistent) Save(path string) error {
+	ring, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(ringFile{
+		Version:  fileVersion,
+		Checksum: crc32.ChecksumIEEE(ring),
+		Ring:     ring,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Load a hash saved with Save. The replica count, domain separation and a
+// hash function chosen by name, with its seed, are read from the file; the
+// options are applied after them, and must provide the hash function if it
+// was not chosen by name. Options giving a different hash function or
+// replica count return ErrMismatch.
+func Load(path string, opts ...Option) (*Consistent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file ringFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCorrupt, file.Version)
+	}
+	if sum := crc32.ChecksumIEEE(file.Ring); sum != file.Checksum {
+		return nil, fmt.Errorf("%w: checksum %08x, want %08x", ErrCorrupt, sum, file.Checksum)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(file.Ring, &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	saved := []Option{WithReplicas(s.Replicas)}
+	if s.Hash != "" {
+		saved = append(saved, WithNamedHash(s.Hash, s.Seed))
+	}
+	if s.DomainSeparation {
+		saved = append(saved, WithDomainSeparation())
+	}
+	m := New(nil, append(saved, opts...)...)
+	if sum := uint32(m.nodeHash(hashProbe)); sum != s.HashCheck {
+		return nil, fmt.Errorf("%w: saved with a different hash function (check %08x, want %08x)", ErrMismatch, s.HashCheck, sum)
+	}
+	if s.Replicas != m.replicas {
+		return nil, fmt.Errorf("%w: saved with %d replicas, configured with %d", ErrMismatch, s.Replicas, m.replicas)
+	}
+
+	r, err := m.restore(s)
+	if err != nil {
+		return nil, err
+	}
+	m.ring = r
+	m.generation = s.Generation
+	m.rebuildTable()
+
+	return m, nil
+}
+
+// Replace the ring with a snapshot in one step, so lookups see either the
+// old ring or the new one. The snapshot must be taken with the same hash
+// function; its replicas are adopted. An invalid snapshot returns an error
+// wrapping ErrCorrupt or ErrMismatch and leaves the ring as it was.
+// Health, read-only marks and capacities of items in both are kept, since
+// they are local to this hash. The generation moves once, and watchers
+// receive a single EventRestore listing the items added, removed, and
+// changed in weight, role, zone, tier or points.
+func (m *Consistent) Restore(s Snapshot) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if sum := uint32(m.nodeHash(hashProbe)); sum != s.HashCheck {
+			err = fmt.Errorf("%w: taken with a different hash function (check %08x, want %08x)", ErrMismatch, s.HashCheck, sum)
+			return nil
+		}
+		if s.Replicas < 1 {
+			err = fmt.Errorf("%w: %d replicas", ErrCorrupt, s.Replicas)
+			return nil
+		}
+		var r *ring
+		if r, err = m.restore(s); err != nil {
+			return nil
+		}
+
+		e := &Event{Type: EventRestore}
+		for _, key := range sortedKeys(m.ring.nodes) {
+			prev, mem := m.ring.nodes[key], r.nodes[key]
+			if mem == nil {
+				e.Removed = append(e.Removed, key)
+				continue
+			}
+			mem.unhealthy, mem.readOnly, mem.capacity, mem.load, mem.hits, mem.latency = prev.unhealthy, prev.readOnly, prev.capacity, prev.load, prev.hits, prev.latency
+			r.setHalfOpen(mem, prev.halfOpen)
+			if !slices.Equal(mem.positions, prev.positions) || mem.weight != prev.weight ||
+				mem.standby != prev.standby || mem.zone != prev.zone || mem.tier != prev.tier || mem.explicit != prev.explicit {
+				e.Changed = append(e.Changed, key)
+			}
+		}
+		for _, key := range sortedKeys(r.nodes) {
+			if _, ok := m.ring.nodes[key]; !ok {
+				e.Added = append(e.Added, key)
+			}
+		}
+
+		m.ring, m.replicas = r, s.Replicas
+		return e
+	})
+
+	return err
+}
+
+// Build a ring from a snapshot, placing each point where it was saved.
+func (m *Consistent) restore(s Snapshot) (*ring, error) {
+	r := m.newRing()
+	r.staged = make(map[int]point)
+	for _, ms := range s.Members {
+		if _, ok := r.nodes[ms.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicate member %q", ErrCorrupt, ms.Name)
+		}
+		if ms.Weight < 0 || len(ms.Points) > ms.Weight {
+			return nil, fmt.Errorf("%w: member %q has %d points and weight %d", ErrCorrupt, ms.Name, len(ms.Points), ms.Weight)
+		}
+
+		mem := newMember()
+		mem.id, mem.weight, mem.standby, mem.zone = int32(len(r.names)), ms.Weight, ms.Standby, ms.Zone
+		mem.explicit = ms.ExplicitWeight
+		r.names = append(r.names, ms.Name)
+		for _, p := range ms.Points {
+			if p.Position < 0 || p.Position > MaxPosition || p.Replica < 0 || p.Replica >= ms.Weight {
+				return nil, fmt.Errorf("%w: member %q has an invalid point %d/%d", ErrCorrupt, ms.Name, p.Position, p.Replica)
+			}
+			if prev, ok := r.staged[p.Position]; ok {
+				return nil, fmt.Errorf("%w: position %d is held by %q and %q", ErrCorrupt, p.Position, r.names[prev.node], ms.Name)
+			}
+			r.staged[p.Position] = point{node: mem.id, replica: int32(p.Replica)}
+			mem.positions = append(mem.positions, p.Position)
+		}
+		sort.Ints(mem.positions)
+		r.nodes[ms.Name] = mem
+		r.setTier(mem, ms.Tier)
+	}
+
+	for key, node := range s.Pins {
+		r.pin(key, node)
+	}
+	for _, p := range s.RangePins {
+		if p.From < 0 || p.From > MaxPosition || p.To < 0 || p.To > MaxPosition {
+			return nil, fmt.Errorf("%w: range pin %d-%d out of bounds", ErrCorrupt, p.From, p.To)
+		}
+		if _, ok := r.pinRange(rangePin{HashRange: HashRange{From: p.From, To: p.To}, node: p.Node}); !ok {
+			return nil, fmt.Errorf("%w: range pin %d-%d overlaps another", ErrCorrupt, p.From, p.To)
+		}
+	}
+
+	r.sortKeys()
+
+	return r, nil
+}
@@ -0,0 +1,275 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func savedRing(opts ...Option) *Consistent {
+	c := New(nil, append([]Option{WithReplicas(3)}, opts...)...)
+	for i := 0; i < 20; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	c.AddWithWeight("heavy", 10)
+	c.AddStandby("standby")
+	c.AddTiered("spill", 2)
+	c.SetZone("n1", "east")
+	c.Rename("n3", "renamed")
+	c.Pin("key1", "n5")
+	c.PinRange(100, 1<<20, "heavy")
+	return c
+}
+
+// A hash function not among HashNames.
+func fnv32(data []byte) uint32 {
+	h := fnv.New32()
+	h.Write(data)
+	return h.Sum32()
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring.json")
+	keys := testKeys(2000)
+	for name, opts := range map[string][]Option{
+		"default":           nil,
+		"named hash":        {WithNamedHash("fnv1a32", 0)},
+		"seeded":            {WithNamedHash("crc32c", 42)},
+		"domain separation": {WithDomainSeparation(), WithNamedHash("crc32", 7)},
+	} {
+		c := savedRing(opts...)
+		if err := c.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		// The settings come from the file
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if loaded.Fingerprint() != c.Fingerprint() || loaded.Generation() != c.Generation() {
+			t.Fatalf("%s: loaded hash differs", name)
+		}
+		if got, want := loaded.Config(), c.Config(); got.Hash != want.Hash || got.Seed != want.Seed || got.DomainSeparation != want.DomainSeparation {
+			t.Fatalf("%s: loaded %+v, saved %+v", name, got, want)
+		}
+		for _, k := range keys {
+			if loaded.Get(k) != c.Get(k) {
+				t.Fatalf("%s: %s on %s, saved on %s", name, k, loaded.Get(k), c.Get(k))
+			}
+		}
+		if zone, _ := loaded.Zone("n1"); zone != "east" {
+			t.Fatalf("%s: zone %q", name, zone)
+		}
+
+		// Options disagreeing with the file
+		if _, err := Load(path, WithReplicas(4)); !errors.Is(err, ErrMismatch) {
+			t.Fatalf("%s: other replicas: %v", name, err)
+		}
+		if _, err := Load(path, WithHash(fnv32)); !errors.Is(err, ErrMismatch) {
+			t.Fatalf("%s: other hash: %v", name, err)
+		}
+	}
+
+	// A hash function not chosen by name must be given again
+	c := savedRing(WithHash(fnv32))
+	if err := c.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); !errors.Is(err, ErrMismatch) {
+		t.Fatal(err)
+	}
+	if loaded, err := Load(path, WithHash(fnv32)); err != nil || loaded.Fingerprint() != c.Fingerprint() {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadRejectsDamage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring.json")
+	if err := savedRing().Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cut := range []int{0, 1, len(data) / 2, len(data) - 1} {
+		os.WriteFile(path, data[:cut], 0o644)
+		if _, err := Load(path); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("truncated to %d bytes: %v", cut, err)
+		}
+	}
+
+	// Changing a digit of the ring keeps the JSON valid but not the checksum
+	bad := append([]byte(nil), data...)
+	for i := len(bad) - 20; i > 0; i-- {
+		if bad[i] >= '1' && bad[i] <= '8' {
+			bad[i]++
+			break
+		}
+	}
+	os.WriteFile(path, bad, 0o644)
+	if _, err := Load(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("edited file: %v", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	m := New(nil, WithReplicas(30))
+	for i := 0; i < 4; i++ {
+		m.Add(fmt.Sprint("old", i))
+	}
+	m.SetHealthy("old0", false)
+	src := New(nil, WithReplicas(30))
+	for i := 0; i < 4; i++ {
+		src.Add(fmt.Sprint("new", i))
+	}
+	src.Add("old0")
+	src.AddWithWeight("old1", 5)
+
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+	gen := m.Generation()
+	if err := m.Restore(src.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	if m.Fingerprint() != src.Fingerprint() || m.Generation() != gen+1 {
+		t.Fatalf("restored generation %d, want %d, or another ring", m.Generation(), gen+1)
+	}
+	if len(events) != 1 || events[0].Type != EventRestore {
+		t.Fatalf("events %+v", events)
+	}
+	if e := events[0]; fmt.Sprint(e.Added, e.Removed, e.Changed) != "[new0 new1 new2 new3] [old2 old3] [old1]" {
+		t.Fatalf("added %q, removed %q, changed %q", e.Added, e.Removed, e.Changed)
+	}
+	if m.IsHealthy("old0") {
+		t.Fatal("health of a kept item was reset")
+	}
+	m.SetHealthy("old0", true)
+	for _, key := range testKeys(200) {
+		if m.Get(key) != src.Get(key) {
+			t.Fatalf("%s: restored ring routes to %s, want %s", key, m.Get(key), src.Get(key))
+		}
+	}
+}
+
+// An invalid snapshot is refused and leaves the ring, its generation and
+// its watchers alone.
+func TestRestoreRejectsInvalid(t *testing.T) {
+	m := savedRing()
+	src := New(nil, WithReplicas(3))
+	src.Add("a")
+	src.AddWithWeight("b", 4)
+
+	other := New(nil, WithNamedHash("fnv1a32", 0))
+	other.Add("x")
+	for _, tc := range []struct {
+		name string
+		snap func() Snapshot
+		want error
+	}{
+		{"other hash", other.Snapshot, ErrMismatch},
+		{"no replicas", func() Snapshot {
+			s := src.Snapshot()
+			s.Replicas = 0
+			return s
+		}, ErrCorrupt},
+		{"shared point", func() Snapshot {
+			s := src.Snapshot()
+			s.Members[0].Points = append(s.Members[0].Points, s.Members[1].Points[0])
+			s.Members[0].Weight += 100
+			return s
+		}, ErrCorrupt},
+		{"duplicate member", func() Snapshot {
+			s := src.Snapshot()
+			s.Members = append(s.Members, s.Members[0])
+			return s
+		}, ErrCorrupt},
+		{"more points than weight", func() Snapshot {
+			s := src.Snapshot()
+			s.Members[1].Weight = 1
+			return s
+		}, ErrCorrupt},
+		{"point out of range", func() Snapshot {
+			s := src.Snapshot()
+			s.Members[0].Points[0].Position = MaxPosition + 1
+			return s
+		}, ErrCorrupt},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []Event
+			cancel := m.Watch(func(e Event) { events = append(events, e) })
+			defer cancel()
+			gen, fp := m.Generation(), m.Fingerprint()
+			if err := m.Restore(tc.snap()); !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+			if m.Generation() != gen || m.Fingerprint() != fp || len(events) != 0 {
+				t.Fatal("a refused snapshot changed the ring")
+			}
+			if err := m.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+// Readers racing restores that swap between two rings see one or the
+// other, never a mix. Run with -race.
+func TestRestoreConcurrentReaders(t *testing.T) {
+	a, b := New(nil, WithReplicas(30)), New(nil, WithReplicas(30))
+	for i := 0; i < 4; i++ {
+		a.Add(fmt.Sprint("old", i))
+		b.Add(fmt.Sprint("new", i))
+	}
+	b.Add("old0")
+	snaps := []Snapshot{a.Snapshot(), b.Snapshot()}
+	entries := [][]Entry{a.Entries(), b.Entries()}
+	keys := testKeys(100)
+
+	m := New(nil, WithReplicas(30))
+	if err := m.Restore(snaps[0]); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if got := m.Entries(); !slices.Equal(got, entries[0]) && !slices.Equal(got, entries[1]) {
+					t.Errorf("%d points of neither ring", len(got))
+					return
+				}
+				for _, key := range keys {
+					if got := m.Get(key); got != a.Get(key) && got != b.Get(key) {
+						t.Errorf("%s: routed to %s, want %s or %s", key, got, a.Get(key), b.Get(key))
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 200; i++ {
+		if err := m.Restore(snaps[i%2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,227 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Make lookups of a key whose pinned item is gone return no item, instead of
+// falling back to the ring.
+func WithStrictPins() Option {
+	return func(m *Consistent) {
+		m.strictPins = true
+	}
+}
+
+// Pin a key to an item, overriding the ring in Get, GetOwner and NextN,
+// where the item comes first. The key is normalized by the key transform
+// like any lookup. If the item is
+// later removed, or is a standby or unhealthy, the key falls back to the
+// ring, or to no item with WithStrictPins.
+func (m *Consistent) Pin(key, node string) error {
+	key = m.pinKey(key)
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[node]; !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if !m.ring.pin(key, node) {
+			return nil
+		}
+		m.history.record(Change{Type: ChangePin, Key: key, To: node})
+		return &Event{Type: EventPin, Changed: []string{key}}
+	})
+
+	return err
+}
+
+// Remove the pin of a key.
+func (m *Consistent) Unpin(key string) {
+	m.TryUnpin(key)
+}
+
+// Like Unpin, but returns ErrFrozen if the hash is frozen instead of doing
+// nothing.
+func (m *Consistent) TryUnpin(key string) error {
+	key = m.pinKey(key)
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if !m.ring.unpin(key) {
+			return nil
+		}
+		m.history.record(Change{Type: ChangeUnpin, Key: key})
+		return &Event{Type: EventPin, Changed: []string{key}}
+	})
+
+	return err
+}
+
+// Returns the item a key is pinned to, whether or not it is still present.
+func (m *Consistent) Pinned(key string) (string, bool) {
+	key = m.pinKey(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	node, ok := m.ring.pins[key]
+	return node, ok
+}
+
+// Returns the pinned keys, sorted.
+func (m *Consistent) Pins() []string {
+	m.RLock()
+	defer m.RUnlock()
+	return sortedKeys(m.ring.pins)
+}
+
+func (m *Consistent) pinKey(key string) string {
+	if m.transform != nil {
+		return m.transform(key)
+	}
+	return key
+}
+
+// Resolve the pin of a lookup key, or of the range holding its hash, under
+// the read lock. Returns the item to
+// use and true if the pin decides the lookup; a strict pin whose item is
+// gone decides it with no item.
+func (m *Consistent) pinned(key string) (string, bool) {
+	if len(m.ring.pins) > 0 {
+		if node, ok := m.ring.resolvePin(m.pinKey(key), m.strictPins); ok {
+			return node, true
+		}
+	}
+	if len(m.ring.rangePins) > 0 {
+		return m.rangePinned(m.Hash(key))
+	}
+	return "", false
+}
+
+// Like pinned, for a key whose hash is known.
+func (m *Consistent) pinnedHash(key string, hash int) (string, bool) {
+	if len(m.ring.pins) > 0 {
+		if node, ok := m.ring.resolvePin(m.pinKey(key), m.strictPins); ok {
+			return node, true
+		}
+	}
+	return m.rangePinned(hash)
+}
+
+// Resolve the pin of the range holding hash, for lookups that are not by a
+// key that can be pinned.
+func (m *Consistent) rangePinned(hash int) (string, bool) {
+	if len(m.ring.rangePins) > 0 {
+		if pin, ok := m.ring.rangePinAt(hash); ok {
+			return m.ring.resolveRangePin(pin, m.strictPins)
+		}
+	}
+	return "", false
+}
+
+// The item a key with the provided hash belongs to under the read lock, as
+// Get finds it on the ring: its pin if one decides the lookup, otherwise the
+// item serving the hash. Returns "" if there is none.
+func (m *Consistent) ownerOf(key string, hash int) string {
+	if node, ok := m.pinnedHash(key, hash); ok {
+		return node
+	}
+	return m.lookupNode(hash)
+}
+
+// The item serving hash on the ring, tiers included and pins ignored, or "".
+func (m *Consistent) lookupNode(hash int) string {
+	if m.ring.size() == 0 {
+		return ""
+	}
+	if i := m.lookup(hash); i >= 0 {
+		return m.ring.nodeAt(i)
+	}
+	return ""
+}
+
+func (r *ring) pin(key, node string) bool {
+	if cur, ok := r.pins[key]; ok && cur == node {
+		return false
+	}
+	if r.pins == nil {
+		r.pins = make(map[string]string)
+	}
+	r.pins[key] = node
+	return true
+}
+
+func (r *ring) unpin(key string) bool {
+	if _, ok := r.pins[key]; !ok {
+		return false
+	}
+	delete(r.pins, key)
+	return true
+}
+
+func (r *ring) resolvePin(key string, strict bool) (string, bool) {
+	node, ok := r.pins[key]
+	if !ok {
+		return "", false
+	}
+	if mem, ok := r.nodes[node]; ok && mem.eligible() {
+		return node, true
+	}
+	return "", strict
+}
+
+// The owner reported for a pinned item: its first replica's point, or its
+// lowest point if that was taken by another item. A pinned item without
+// points is reported at position -1. A strict pin whose item is gone has no
+// owner.
+func (r *ring) pinOwner(node string) (Owner, bool) {
+	if node == "" {
+		return Owner{}, false
+	}
+	if pos, ok := r.origin(node); ok {
+		return Owner{Node: node, Position: pos}, true
+	}
+	o := Owner{Node: node, Position: -1}
+	for _, pos := range r.nodes[node].positions {
+		if o.Position < 0 || pos < o.Position {
+			p, _ := r.pointAt(pos)
+			o.Position, o.Replica = pos, int(p.replica)
+		}
+	}
+	return o, true
+}
+
+// Resolve every pin, for the lookup table.
+func (r *ring) resolvePins(strict bool) map[string]string {
+	if len(r.pins) == 0 {
+		return nil
+	}
+	resolved := make(map[string]string, len(r.pins))
+	for key := range r.pins {
+		if node, ok := r.resolvePin(key, strict); ok {
+			resolved[key] = node
+		}
+	}
+	return resolved
+}
+
+// Count the pins whose item is gone or ineligible.
+func (r *ring) danglingPins() int {
+	n := 0
+	for key := range r.pins {
+		if _, ok := r.resolvePin(key, false); !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// The pins sorted by key, for fingerprints and snapshots.
+func (r *ring) sortedPins() [][2]string {
+	pins := make([][2]string, 0, len(r.pins))
+	for key, node := range r.pins {
+		pins = append(pins, [2]string{key, node})
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i][0] < pins[j][0] })
+	return pins
+}
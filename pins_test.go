@@ -0,0 +1,132 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func pinnedRing(opts ...Option) *Consistent {
+	c := New(nil, append([]Option{WithReplicas(20)}, opts...)...)
+	for i := 0; i < 4; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	for i := 0; i < 200; i++ {
+		c.Pin(fmt.Sprint("key", i*7), "n0")
+	}
+	c.Pin("key3", "n3")
+	c.PinRange(1<<30, 1<<31, "n1")
+	c.PinRange(7<<29, 1<<28, "n0")
+	return c
+}
+
+func TestPinsApplyToBulkLookups(t *testing.T) {
+	keys := testKeys(5000)
+	for _, strict := range []bool{false, true} {
+		var opts []Option
+		if strict {
+			opts = append(opts, WithStrictPins())
+		}
+		c := pinnedRing(append(opts, WithHotKeyTracking(1, len(keys)))...)
+		c.SetHealthy("n3", false)
+
+		get := make(map[string]string, len(keys))
+		for _, k := range keys {
+			get[k] = c.Get(k)
+		}
+
+		groups := c.AssignAll(keys)
+		for node, group := range groups {
+			for _, k := range group {
+				if get[k] != node {
+					t.Fatalf("strict=%v: AssignAll puts %s on %s, Get %q", strict, k, node, get[k])
+				}
+			}
+		}
+		for _, k := range keys {
+			if get[k] != "" && !slices.Contains(groups[get[k]], k) {
+				t.Fatalf("strict=%v: AssignAll misses %s on %s", strict, k, get[k])
+			}
+		}
+
+		for i := 0; i < 4; i++ {
+			node := fmt.Sprint("n", i)
+			owned, _ := c.KeysOwnedBy(node, keys)
+			replicas, _ := c.KeysOwnedByN(node, keys, 2)
+			var want, wantN []string
+			for _, k := range keys {
+				if get[k] == node {
+					want = append(want, k)
+				}
+				if slices.Contains(c.NextN(k, 2), node) {
+					wantN = append(wantN, k)
+				}
+			}
+			if !slices.Equal(owned, want) {
+				t.Fatalf("strict=%v: KeysOwnedBy(%s) has %d keys, Get %d", strict, node, len(owned), len(want))
+			}
+			if !slices.Equal(replicas, wantN) {
+				t.Fatalf("strict=%v: KeysOwnedByN(%s) has %d keys, NextN %d", strict, node, len(replicas), len(wantN))
+			}
+		}
+
+		for _, k := range keys {
+			prev := c.PrevN(k, 3)
+			if get[k] != "" && prev[0] != get[k] || get[k] == "" && slices.Contains(prev, "") {
+				t.Fatalf("strict=%v: PrevN(%s) %v, Get %q", strict, k, prev, get[k])
+			}
+			if others := c.PrevNDistinct(k, 2, false); slices.Contains(others, get[k]) || len(others) != 2 {
+				t.Fatalf("strict=%v: PrevNDistinct(%s) %v without %q", strict, k, others, get[k])
+			}
+		}
+
+		for _, hk := range c.HotKeys() {
+			if hk.Node != get[hk.Key] {
+				t.Fatalf("strict=%v: HotKeys puts %s on %q, Get %q", strict, hk.Key, hk.Node, get[hk.Key])
+			}
+		}
+
+		for _, k := range keys {
+			if _, ok := c.ring.pins[k]; ok {
+				continue
+			}
+			if node, err := c.GetReader(strings.NewReader(k)); node != get[k] || node != "" && err != nil {
+				t.Fatalf("strict=%v: GetReader(%s) = %q, %v, Get %q", strict, k, node, err, get[k])
+			}
+			hash := c.HashParts(k, "x")
+			want, ok := c.rangePinned(hash)
+			if !ok {
+				want = c.lookupNode(hash)
+			}
+			if node := c.GetParts(k, "x"); node != want {
+				t.Fatalf("strict=%v: GetParts(%s) = %q, want %q", strict, k, node, want)
+			}
+			if contains(HashRange{From: 1 << 30, To: 1 << 31}, hash) && c.GetParts(k, "x") != "n1" {
+				t.Fatalf("strict=%v: GetParts(%s) ignores the range pin", strict, k)
+			}
+		}
+
+		after := pinnedRing(opts...)
+		after.SetHealthy("n3", false)
+		after.Remove("n0")
+		want := make(map[string]float64)
+		for _, k := range keys {
+			if get[k] == "n0" {
+				if node := after.Get(k); node != "" {
+					want[node] += 1 / float64(len(keys))
+				}
+			}
+		}
+		got := c.FailoverImpactKeys("n0", keys)
+		if len(got) != len(want) {
+			t.Fatalf("strict=%v: FailoverImpactKeys %v, want %v", strict, got, want)
+		}
+		for node, f := range want {
+			if math.Abs(got[node]-f) > 1e-9 {
+				t.Fatalf("strict=%v: FailoverImpactKeys %v, want %v", strict, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package consistent
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Place the points of an item being added, or of extra weight, at the
+// midpoints of the largest arcs of the ring instead of at the hashes of its
+// replicas, so a new item relieves the items with the most keys. Once the
+// item holds its share of the ring by points, the rest of its points split
+// its own largest arcs, moving no keys. Ties go to the arc at the lower
+// position, so every process applying the same changes in the same order
+// places the same points. The first point of an empty ring is placed by
+// hash. Removing an item releases exactly its points. SetHash places every
+// item again by hash.
+func WithArcSplittingPlacement() Option {
+	return func(m *Consistent) {
+		m.arcSplit = true
+	}
+}
+
+func (m *Consistent) newRing() *ring {
+	r := newRing(m.position, m.treeIndex)
+	r.splitArcs = m.arcSplit
+	return r
+}
+
+// Positions for n new points of a key: midpoints of the largest arcs of
+// other keys while the key holds less than its share, then of its own.
+func (r *ring) splitPositions(key string, n int) []int {
+	var positions []int
+	if r.staged != nil {
+		positions = make([]int, 0, len(r.staged))
+		for pos := range r.staged {
+			positions = append(positions, pos)
+		}
+		sort.Ints(positions)
+	} else {
+		positions = make([]int, r.size())
+		for i := range positions {
+			positions[i] = r.key(i)
+		}
+	}
+
+	mem := r.nodes[key]
+	split := make([]int, 0, n)
+	if len(positions) == 0 {
+		pos := r.position(key, 0)
+		positions, split = append(positions, pos), append(split, pos)
+	}
+
+	// The arcs of other keys and of this one, and the length it holds
+	var others, own arcHeap
+	var held uint64
+	for i, pos := range positions {
+		next := positions[(i+1)%len(positions)]
+		arc := splitArc{from: pos, length: uint64((next-pos-1)&MaxPosition) + 1}
+		if p, ok := r.pointAt(pos); !ok || p.node == mem.id {
+			own, held = append(own, arc), held+arc.length
+		} else {
+			others = append(others, arc)
+		}
+	}
+	heap.Init(&others)
+	heap.Init(&own)
+	share := uint64(MaxPosition+1) / uint64(len(positions)+n-len(split)) * uint64(mem.weight+n)
+
+	for len(split) < n {
+		from := &own
+		if held < share && others.Len() > 0 && others[0].length > 1 {
+			from = &others
+		}
+		if from.Len() == 0 || (*from)[0].length < 2 {
+			// Every arc is a single position, fall back to the hash
+			split = append(split, r.position(key, mem.weight+len(split)))
+			continue
+		}
+		arc := heap.Pop(from).(splitArc)
+		mid := (arc.from + int(arc.length/2)) & MaxPosition
+		split = append(split, mid)
+		heap.Push(from, splitArc{from: arc.from, length: arc.length / 2})
+		if from == &others {
+			held += arc.length - arc.length/2
+		}
+		heap.Push(&own, splitArc{from: mid, length: arc.length - arc.length/2})
+	}
+	return split
+}
+
+// The arc from a point up to the next point.
+type splitArc struct {
+	from   int
+	length uint64
+}
+
+// A max-heap of arcs by length, then lowest position.
+type arcHeap []splitArc
+
+func (h arcHeap) Len() int { return len(h) }
+func (h arcHeap) Less(i, j int) bool {
+	if h[i].length != h[j].length {
+		return h[i].length > h[j].length
+	}
+	return h[i].from < h[j].from
+}
+func (h arcHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *arcHeap) Push(x any)   { *h = append(*h, x.(splitArc)) }
+func (h *arcHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
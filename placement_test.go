@@ -0,0 +1,125 @@
+package consistent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"testing"
+)
+
+// The longest arc of the ring.
+func peakArc(c *Consistent) uint64 {
+	positions := c.Positions()
+	var peak uint64
+	for i, pos := range positions {
+		next := positions[(i+1)%len(positions)]
+		if arc := uint64((next-pos-1)&MaxPosition) + 1; arc > peak {
+			peak = arc
+		}
+	}
+	return peak
+}
+
+func TestArcSplittingRelievesPeak(t *testing.T) {
+	base := New(nil, WithReplicas(8))
+	for i := 0; i < 6; i++ {
+		base.Add(fmt.Sprint("n", i))
+	}
+	split := New(nil, WithReplicas(8), WithArcSplittingPlacement())
+	if err := split.Restore(base.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both start from the same points; only placement differs
+	for i := 0; i < 4; i++ {
+		node := fmt.Sprint("new", i)
+		peak := peakArc(split)
+		base.Add(node)
+		split.Add(node)
+		if got := peakArc(split); got >= peak {
+			t.Fatalf("adding %s left the peak arc at %d from %d", node, got, peak)
+		}
+		if peakArc(split) >= peakArc(base) {
+			t.Fatalf("after %s: peak arc %d, %d placing by hash", node, peakArc(split), peakArc(base))
+		}
+		if split.Stats().Max > base.Stats().Max {
+			t.Fatalf("after %s: largest share %v, %v placing by hash", node, split.Stats().Max, base.Stats().Max)
+		}
+	}
+}
+
+func TestArcSplittingDeterministic(t *testing.T) {
+	c := New(nil, WithReplicas(8), WithArcSplittingPlacement())
+	for i := 0; i < 6; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	other := New(nil, WithReplicas(8), WithArcSplittingPlacement())
+	if err := other.Restore(c.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	c.Add("new")
+	other.Add("new")
+	if c.Fingerprint() != other.Fingerprint() {
+		t.Fatal("the same add placed different points")
+	}
+
+	// Removing releases exactly the item's points, and adding it again
+	// places them again
+	positions, _ := c.PositionsOf("new")
+	fp := c.Fingerprint()
+	c.Remove("new")
+	for _, pos := range c.Positions() {
+		if slices.Contains(positions, int(pos)) {
+			t.Fatalf("point %d not released", pos)
+		}
+	}
+	if len(c.Positions()) != 6*8 {
+		t.Fatalf("%d points after the removal", len(c.Positions()))
+	}
+	c.Add("new")
+	if c.Fingerprint() != fp {
+		t.Fatal("adding again placed different points")
+	}
+
+	d, err := c.ChangesSince(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower := New(nil, WithReplicas(8), WithArcSplittingPlacement())
+	if err := follower.ApplyDelta(d); err != nil || follower.Fingerprint() != c.Fingerprint() {
+		t.Fatal("delta placed different points", err)
+	}
+}
+
+func TestSetHashPlacesByHash(t *testing.T) {
+	c := New(nil, WithReplicas(5), WithArcSplittingPlacement())
+	for i := 0; i < 4; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	c.AddWithWeight("heavy", 9)
+	if err := c.SetHash(func(data []byte) uint32 {
+		h := fnv.New32a()
+		h.Write(data)
+		return h.Sum32()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, node := range c.Members() {
+		weight, _ := c.Weight(node)
+		var want []int
+		for i := 0; i < weight; i++ {
+			want = append(want, c.position(node, i))
+		}
+		slices.Sort(want)
+		if got, _ := c.PositionsOf(node); !slices.Equal(got, want) {
+			t.Fatalf("%s at %v, hashes %v", node, got, want)
+		}
+	}
+
+	// Later adds split arcs again
+	c.Add("late")
+	if got, _ := c.PositionsOf("late"); slices.Contains(got, c.position("late", 0)) && slices.Contains(got, c.position("late", 1)) {
+		t.Fatalf("late item placed by hash at %v", got)
+	}
+}
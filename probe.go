@@ -0,0 +1,186 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Prober checks whether an item can serve requests.
+type Prober interface {
+	Check(ctx context.Context, node string) error
+}
+
+// ProberFunc adapts a function to a Prober.
+type ProberFunc func(ctx context.Context, node string) error
+
+func (f ProberFunc) Check(ctx context.Context, node string) error {
+	return f(ctx, node)
+}
+
+// The probe interval used when StartProbing is given one that is not
+// positive.
+const defaultProbeInterval = time.Second
+
+type ProbeOption func(*probing)
+
+// Run at most n probes at once. The default is 8.
+func ProbeWorkers(n int) ProbeOption {
+	return func(p *probing) {
+		if n > 0 {
+			p.workers = n
+		}
+	}
+}
+
+// Fail a probe that takes longer than d. The default is the probe interval.
+func ProbeTimeout(d time.Duration) ProbeOption {
+	return func(p *probing) {
+		if d > 0 {
+			p.timeout = d
+		}
+	}
+}
+
+type probing struct {
+	m        *Consistent
+	prober   Prober
+	interval time.Duration
+	workers  int
+	timeout  time.Duration
+
+	failures, recoveries int
+	streaks              map[string]int // Consecutive failures if negative, successes if positive
+}
+
+type probeJob struct {
+	node    string
+	results chan<- probeResult
+}
+
+type probeResult struct {
+	node string
+	err  error
+}
+
+// Probe every member each interval until ctx is done, marking an item
+// unhealthy after failureThreshold consecutive failed probes and healthy
+// again after recoveryThreshold consecutive successful ones. The first round
+// of probes runs at once, and a round finishes before the next starts. An
+// interval that is not positive is taken as one second. The returned
+// channel is closed once every probe has returned, which relies on the
+// prober honoring its context.
+func (m *Consistent) StartProbing(ctx context.Context, prober Prober, interval time.Duration, failureThreshold, recoveryThreshold int, opts ...ProbeOption) <-chan struct{} {
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+	p := &probing{
+		m:          m,
+		prober:     prober,
+		interval:   interval,
+		workers:    8,
+		timeout:    interval,
+		failures:   max(failureThreshold, 1),
+		recoveries: max(recoveryThreshold, 1),
+		streaks:    make(map[string]int),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.run(ctx)
+	}()
+	return done
+}
+
+func (p *probing) run(ctx context.Context) {
+	jobs := make(chan probeJob)
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				pctx, cancel := context.WithTimeout(ctx, p.timeout)
+				err := p.prober.Check(pctx, j.node)
+				cancel()
+				j.results <- probeResult{node: j.node, err: err}
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		if !p.round(ctx, jobs) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Probe every member once and apply the results. Returns false if ctx was
+// done first.
+func (p *probing) round(ctx context.Context, jobs chan<- probeJob) bool {
+	nodes := p.m.Members()
+	// Buffered so workers never wait on a round abandoned by cancellation.
+	results := make(chan probeResult, len(nodes))
+	for _, node := range nodes {
+		select {
+		case jobs <- probeJob{node: node, results: results}:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	for range nodes {
+		select {
+		case r := <-results:
+			seen[r.node] = true
+			p.record(r)
+		case <-ctx.Done():
+			return false
+		}
+	}
+	for node := range p.streaks {
+		if !seen[node] {
+			delete(p.streaks, node)
+		}
+	}
+	return true
+}
+
+func (p *probing) record(r probeResult) {
+	streak := p.streaks[r.node]
+	if r.err != nil {
+		streak = min(streak, 0) - 1
+	} else {
+		streak = max(streak, 0) + 1
+	}
+	p.streaks[r.node] = streak
+
+	var err error
+	switch healthy := p.m.IsHealthy(r.node); {
+	case healthy && -streak >= p.failures:
+		err = p.m.SetHealthy(r.node, false)
+	case !healthy && streak >= p.recoveries:
+		err = p.m.SetHealthy(r.node, true)
+	}
+	if errors.Is(err, ErrNodeNotFound) {
+		// Removed while it was being probed.
+		delete(p.streaks, r.node)
+	}
+}
@@ -0,0 +1,124 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// An item goes unhealthy only after 2 failures in a row and comes back only
+// after 3 successes in a row.
+func TestProbeHysteresis(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	p := &probing{m: m, failures: 2, recoveries: 3, streaks: make(map[string]int)}
+
+	down := errors.New("down")
+	script := []struct {
+		err     error
+		healthy bool
+	}{
+		{down, true},
+		{nil, true}, // The success resets the failures
+		{down, true},
+		{down, false},
+		{down, false},
+		{nil, false},
+		{nil, false},
+		{down, false}, // The failure resets the successes
+		{nil, false},
+		{nil, false},
+		{nil, true},
+		{down, true},
+	}
+	for i, step := range script {
+		p.record(probeResult{node: "a", err: step.err})
+		if got := m.IsHealthy("a"); got != step.healthy {
+			t.Fatalf("step %d: healthy %v, want %v", i, got, step.healthy)
+		}
+	}
+}
+
+// Probes run in rounds, the first at once, until the context is done.
+func TestStartProbing(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	m.Add("b")
+
+	var mu sync.Mutex
+	var seen []bool
+	ctx, cancel := context.WithCancel(context.Background())
+	prober := ProberFunc(func(ctx context.Context, node string) error {
+		if node != "a" {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, m.IsHealthy("a"))
+		if len(seen) == 4 {
+			cancel()
+		}
+		return errors.New("down")
+	})
+	done := m.StartProbing(ctx, prober, time.Millisecond, 2, 1, ProbeWorkers(2))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("probing did not stop")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Each probe runs after the previous round was applied
+	if len(seen) < 3 || !seen[0] || !seen[1] || seen[2] {
+		t.Fatalf("a seen healthy %v, want [true true false ...]", seen)
+	}
+}
+
+// An interval that is not positive falls back to the default instead of
+// panicking, and the first round still runs at once.
+func TestStartProbingNoInterval(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	for _, interval := range []time.Duration{0, -time.Second} {
+		probed := make(chan struct{}, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		prober := ProberFunc(func(ctx context.Context, node string) error {
+			select {
+			case probed <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+		done := m.StartProbing(ctx, prober, interval, 1, 1)
+		select {
+		case <-probed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("interval %v: no first round", interval)
+		}
+		cancel()
+		<-done
+	}
+}
+
+// Cancelling stops probing even while every probe is blocked.
+func TestStartProbingCancel(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	started := make(chan struct{}, 1)
+	prober := ProberFunc(func(ctx context.Context, node string) error {
+		started <- struct{}{}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := m.StartProbing(ctx, prober, time.Hour, 1, 1)
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("probing did not stop")
+	}
+}
@@ -0,0 +1,32 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInsufficientNodes = errors.New("consistent: not enough items for a quorum")
+
+// Make GetQuorum fail with ErrInsufficientNodes when fewer than n owners are
+// available. The default is 1.
+func WithMinQuorumNodes(n int) Option {
+	return func(m *Consistent) {
+		if n > 0 {
+			m.minQuorum = n
+		}
+	}
+}
+
+// Get up to rf distinct owners for the provided key, as NextN does, and the
+// majority quorum of the owners returned. With fewer items than rf the
+// quorum is of the smaller count: two owners for rf 3 need both of them, not
+// two of three. Returns ErrInsufficientNodes, along with the owners found,
+// when there are fewer owners than WithMinQuorumNodes requires.
+func (m *Consistent) GetQuorum(key string, rf int) (owners []string, quorum int, err error) {
+	owners = m.NextN(key, rf)
+	quorum = len(owners)/2 + 1
+	if need := max(m.minQuorum, 1); len(owners) < need {
+		return owners, quorum, fmt.Errorf("%w: %d of %d needed", ErrInsufficientNodes, len(owners), need)
+	}
+	return owners, quorum, nil
+}
@@ -0,0 +1,46 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestGetQuorum(t *testing.T) {
+	for _, tc := range []struct {
+		items, rf, min int
+		owners, quorum int
+		insufficient   bool
+	}{
+		{items: 0, rf: 3, owners: 0, quorum: 1, insufficient: true},
+		{items: 1, rf: 3, owners: 1, quorum: 1},
+		{items: 2, rf: 3, owners: 2, quorum: 2}, // Both of two, not two of three
+		{items: 3, rf: 3, owners: 3, quorum: 2},
+		{items: 5, rf: 3, owners: 3, quorum: 2},
+		{items: 5, rf: 4, owners: 4, quorum: 3},
+		{items: 5, rf: 5, owners: 5, quorum: 3},
+		{items: 5, rf: 1, owners: 1, quorum: 1},
+		{items: 1, rf: 3, min: 2, owners: 1, quorum: 1, insufficient: true},
+		{items: 2, rf: 3, min: 2, owners: 2, quorum: 2},
+		{items: 2, rf: 3, min: 3, owners: 2, quorum: 2, insufficient: true},
+	} {
+		name := fmt.Sprintf("%d items, rf %d, min %d", tc.items, tc.rf, tc.min)
+		m := New(nil, WithReplicas(10), WithMinQuorumNodes(tc.min))
+		for i := 0; i < tc.items; i++ {
+			m.Add(fmt.Sprint("n", i))
+		}
+		for _, key := range testKeys(20) {
+			owners, quorum, err := m.GetQuorum(key, tc.rf)
+			if len(owners) != tc.owners || quorum != tc.quorum {
+				t.Fatalf("%s: %v owners with quorum %d, want %d with %d", name, owners, quorum, tc.owners, tc.quorum)
+			}
+			if want := m.NextN(key, tc.rf); !slices.Equal(owners, want) {
+				t.Fatalf("%s: owners %v, want the NextN owners %v", name, owners, want)
+			}
+			if errors.Is(err, ErrInsufficientNodes) != tc.insufficient {
+				t.Fatalf("%s: error %v", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package consistent
+
+import (
+	"errors"
+	"sort"
+)
+
+var ErrRangeOverlap = errors.New("consistent: range overlaps a pinned range")
+
+// The item a range of hashes is pinned to, see PinRange.
+type rangePin struct {
+	HashRange
+	node string
+}
+
+// Pin the hashes from from to to, inclusive, to an item, overriding the ring
+// in Get, GetOwner and NextN like Pin does for a key. The range wraps past
+// MaxPosition to zero when from > to. Ranges, RangeOwners, ArcLength and
+// Stats count a pinned range as the item's. Pinning a range again replaces its item; a range
+// overlapping another pinned range returns ErrRangeOverlap. If the item is
+// later removed, or is a standby or unhealthy, the range falls back to the
+// ring, or to no item with WithStrictPins.
+func (m *Consistent) PinRange(from, to uint32, node string) error {
+	pin := rangePin{HashRange: HashRange{From: int(from), To: int(to)}, node: node}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[node]; !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		changed, ok := m.ring.pinRange(pin)
+		if !ok {
+			err = ErrRangeOverlap
+			return nil
+		}
+		if !changed {
+			return nil
+		}
+		m.history.record(Change{Type: ChangePinRange, To: node, Range: &pin.HashRange})
+		return &Event{Type: EventRangePin, Changed: []string{node}}
+	})
+
+	return err
+}
+
+// Remove the pin of the range from from to to, which must match a pinned
+// range exactly.
+func (m *Consistent) UnpinRange(from, to uint32) {
+	m.TryUnpinRange(from, to)
+}
+
+// Like UnpinRange, but returns ErrFrozen if the hash is frozen instead of
+// doing nothing.
+func (m *Consistent) TryUnpinRange(from, to uint32) error {
+	hr := HashRange{From: int(from), To: int(to)}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		node, ok := m.ring.unpinRange(hr)
+		if !ok {
+			return nil
+		}
+		m.history.record(Change{Type: ChangeUnpinRange, Range: &hr})
+		return &Event{Type: EventRangePin, Changed: []string{node}}
+	})
+
+	return err
+}
+
+// Returns the pinned ranges with their items, whether or not they are still
+// present, in order of position.
+func (m *Consistent) PinnedRanges() []OwnedRange {
+	m.RLock()
+	defer m.RUnlock()
+	owned := make([]OwnedRange, len(m.ring.rangePins))
+	for i, pin := range m.ring.rangePins {
+		owned[i] = OwnedRange{Range: pin.HashRange, Node: pin.node}
+	}
+	return owned
+}
+
+// Returns true if the range holds hash.
+func (hr HashRange) contains(hash int) bool {
+	if hr.From <= hr.To {
+		return hr.From <= hash && hash <= hr.To
+	}
+	return hash >= hr.From || hash <= hr.To
+}
+
+// Split a range wrapping past MaxPosition in two.
+func (hr HashRange) segments() []HashRange {
+	if hr.From <= hr.To {
+		return []HashRange{hr}
+	}
+	return []HashRange{{From: hr.From, To: MaxPosition}, {From: 0, To: hr.To}}
+}
+
+// The parts of a range that does not wrap outside another.
+func (hr HashRange) minus(cut HashRange) []HashRange {
+	if hr.To < cut.From || hr.From > cut.To {
+		return []HashRange{hr}
+	}
+	var kept []HashRange
+	if hr.From < cut.From {
+		kept = append(kept, HashRange{From: hr.From, To: cut.From - 1})
+	}
+	if hr.To > cut.To {
+		kept = append(kept, HashRange{From: cut.To + 1, To: hr.To})
+	}
+	return kept
+}
+
+// The number of positions two ranges share.
+func (hr HashRange) overlap(o HashRange) uint64 {
+	var n uint64
+	for _, a := range hr.segments() {
+		for _, b := range o.segments() {
+			if from, to := max(a.From, b.From), min(a.To, b.To); from <= to {
+				n += uint64(to-from) + 1
+			}
+		}
+	}
+	return n
+}
+
+// Pin a range, keeping the pins sorted by From. Returns whether anything
+// changed, and false if the range overlaps another.
+func (r *ring) pinRange(pin rangePin) (changed, ok bool) {
+	for i, cur := range r.rangePins {
+		if cur.HashRange == pin.HashRange {
+			r.rangePins[i].node = pin.node
+			return cur.node != pin.node, true
+		}
+		if cur.overlap(pin.HashRange) > 0 {
+			return false, false
+		}
+	}
+	i := sort.Search(len(r.rangePins), func(i int) bool { return r.rangePins[i].From > pin.From })
+	r.rangePins = append(r.rangePins, rangePin{})
+	copy(r.rangePins[i+1:], r.rangePins[i:])
+	r.rangePins[i] = pin
+	return true, true
+}
+
+func (r *ring) unpinRange(hr HashRange) (string, bool) {
+	for i, cur := range r.rangePins {
+		if cur.HashRange == hr {
+			r.rangePins = append(r.rangePins[:i], r.rangePins[i+1:]...)
+			return cur.node, true
+		}
+	}
+	return "", false
+}
+
+// The pinned range holding hash, if any.
+func (r *ring) rangePinAt(hash int) (rangePin, bool) {
+	return findRangePin(r.rangePins, hash)
+}
+
+// Find the range holding hash among range pins sorted by position.
+func findRangePin(pins []rangePin, hash int) (rangePin, bool) {
+	if len(pins) == 0 {
+		return rangePin{}, false
+	}
+	i := sort.Search(len(pins), func(i int) bool { return pins[i].From > hash }) - 1
+	if i < 0 {
+		// Only the last range can wrap to zero
+		i = len(pins) - 1
+	}
+	if pin := pins[i]; pin.contains(hash) {
+		return pin, true
+	}
+	return rangePin{}, false
+}
+
+// Resolve a range pin like resolvePin.
+func (r *ring) resolveRangePin(pin rangePin, strict bool) (string, bool) {
+	if mem, ok := r.nodes[pin.node]; ok && mem.eligible() {
+		return pin.node, true
+	}
+	return "", strict
+}
+
+// The range pins that decide lookups, with the item they resolve to, in
+// position order.
+func (r *ring) resolveRangePins(strict bool) []rangePin {
+	var resolved []rangePin
+	for _, pin := range r.rangePins {
+		if node, ok := r.resolveRangePin(pin, strict); ok {
+			resolved = append(resolved, rangePin{HashRange: pin.HashRange, node: node})
+		}
+	}
+	return resolved
+}
+
+// The number of positions of a range the range pins take from the ring.
+func (r *ring) pinnedLength(hr HashRange, strict bool) uint64 {
+	var n uint64
+	for _, pin := range r.rangePins {
+		if _, ok := r.resolveRangePin(pin, strict); ok {
+			n += pin.overlap(hr)
+		}
+	}
+	return n
+}
+
+// Replace the ranges of a key with what the range pins leave of them, and
+// add the ranges pinned to it, sorted and merged. Ranges wrapping past
+// MaxPosition come back split in two.
+func (r *ring) pinnedRanges(key string, ranges []HashRange, strict bool) []HashRange {
+	var segs []HashRange
+	for _, hr := range ranges {
+		segs = append(segs, hr.segments()...)
+	}
+	for _, pin := range r.rangePins {
+		node, ok := r.resolveRangePin(pin, strict)
+		if !ok {
+			continue
+		}
+		for _, cut := range pin.segments() {
+			kept := segs[:0:0]
+			for _, s := range segs {
+				kept = append(kept, s.minus(cut)...)
+			}
+			segs = kept
+		}
+		if node == key {
+			segs = append(segs, pin.segments()...)
+		}
+	}
+
+	sort.Slice(segs, func(i, j int) bool { return segs[i].From < segs[j].From })
+	merged := segs[:0]
+	for _, s := range segs {
+		if n := len(merged); n > 0 && merged[n-1].To+1 == s.From {
+			merged[n-1].To = s.To
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
+
+// Replace the parts of owned the range pins take from the ring, and add the
+// parts of hr they give their items, in ring order from hr.From with
+// neighbouring parts of the same item joined, as RangeOwners returns them.
+func (r *ring) pinnedOwners(owned []OwnedRange, hr HashRange, strict bool) []OwnedRange {
+	var parts []OwnedRange
+	for _, o := range owned {
+		for _, s := range o.Range.segments() {
+			parts = append(parts, OwnedRange{Node: o.Node, Range: s})
+		}
+	}
+	for _, pin := range r.rangePins {
+		node, ok := r.resolveRangePin(pin, strict)
+		if !ok {
+			continue
+		}
+		for _, cut := range pin.segments() {
+			kept := parts[:0:0]
+			for _, p := range parts {
+				for _, s := range p.Range.minus(cut) {
+					kept = append(kept, OwnedRange{Node: p.Node, Range: s})
+				}
+			}
+			parts = kept
+		}
+		if node == "" {
+			continue
+		}
+		for _, a := range pin.segments() {
+			for _, b := range hr.segments() {
+				if from, to := max(a.From, b.From), min(a.To, b.To); from <= to {
+					parts = append(parts, OwnedRange{Node: node, Range: HashRange{From: from, To: to}})
+				}
+			}
+		}
+	}
+
+	sort.Slice(parts, func(i, j int) bool {
+		return (parts[i].Range.From-hr.From)&MaxPosition < (parts[j].Range.From-hr.From)&MaxPosition
+	})
+	merged := parts[:0]
+	for _, p := range parts {
+		if n := len(merged); n > 0 && merged[n-1].Node == p.Node && (merged[n-1].Range.To+1)&MaxPosition == p.Range.From {
+			merged[n-1].Range.To = p.Range.To
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// The range pins sorted by position, for fingerprints and snapshots.
+func (r *ring) rangePinStates() []RangePinState {
+	if len(r.rangePins) == 0 {
+		return nil
+	}
+	pins := make([]RangePinState, len(r.rangePins))
+	for i, pin := range r.rangePins {
+		pins[i] = RangePinState{From: pin.From, To: pin.To, Node: pin.node}
+	}
+	return pins
+}
@@ -0,0 +1,98 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Check Ranges, ArcLength, RangeOwners and Stats agree with Get on a hash
+// with range pins.
+func checkRangePins(t *testing.T, c *Consistent, keys []string) {
+	t.Helper()
+	s := c.Stats()
+	full := c.RangeOwners(0, MaxPosition)
+	owned := make(map[string]uint64)
+	for _, o := range full {
+		owned[o.Node] += rangeLength(o.Range)
+	}
+	for _, node := range c.Members() {
+		length, _ := c.ArcLength(node)
+		var ranged uint64
+		for _, r := range c.Ranges(node) {
+			ranged += rangeLength(r)
+		}
+		if ranged != length || owned[node] != length || math.Abs(float64(length)/(MaxPosition+1)-s.Shares[node]) > 1e-9 {
+			t.Fatalf("%s: ranges %d, arc length %d, range owners %d, share %v", node, ranged, length, owned[node], s.Shares[node])
+		}
+	}
+
+	for _, hr := range []HashRange{{From: 0, To: MaxPosition}, {From: 6 << 29, To: 2 << 29}, {From: 1<<30 + 100, To: 1<<30 + 200}, {From: 3 << 30, To: 1 << 28}} {
+		parts := c.RangeOwners(hr.From, hr.To)
+		for i := 1; i < len(parts); i++ {
+			if parts[i-1].Node == parts[i].Node && (parts[i-1].Range.To+1)&MaxPosition == parts[i].Range.From {
+				t.Fatalf("%v: neighbouring parts of %s not joined", hr, parts[i].Node)
+			}
+			if (parts[i].Range.From-hr.From)&MaxPosition <= (parts[i-1].Range.From-hr.From)&MaxPosition {
+				t.Fatalf("%v: parts out of ring order: %v", hr, parts)
+			}
+		}
+		for _, k := range keys {
+			h := c.Hash(k)
+			if !contains(hr, h) {
+				continue
+			}
+			owner := ""
+			for _, p := range parts {
+				if contains(p.Range, h) {
+					owner = p.Node
+				}
+			}
+			if owner != c.Get(k) {
+				t.Fatalf("%v: hash %d of %s owned by %q, Get %q", hr, h, k, owner, c.Get(k))
+			}
+		}
+	}
+}
+
+func TestRangePinsAgree(t *testing.T) {
+	keys := testKeys(20000)
+	for _, strict := range []bool{false, true} {
+		var opts []Option
+		if strict {
+			opts = append(opts, WithStrictPins())
+		}
+		c := New(nil, append(opts, WithReplicas(20))...)
+		for i := 0; i < 4; i++ {
+			c.Add(fmt.Sprint("n", i))
+		}
+		// A quarter wrapping past the top, a quarter and a small range
+		for _, pin := range []struct {
+			from, to uint32
+			node     string
+		}{{7 << 29, 1<<29 - 1, "n0"}, {1 << 30, 1 << 31, "n1"}, {3 << 30, 3<<30 + 1<<20, "n3"}} {
+			if err := c.PinRange(pin.from, pin.to, pin.node); err != nil {
+				t.Fatal(err)
+			}
+		}
+		checkRangePins(t, c, keys)
+
+		c.SetHealthy("n1", false)
+		checkRangePins(t, c, keys)
+		c.Remove("n0")
+		checkRangePins(t, c, keys)
+
+		var total uint64
+		for _, node := range c.Members() {
+			length, _ := c.ArcLength(node)
+			total += length
+		}
+		gone := uint64(0)
+		if strict {
+			gone = 1<<30 + 1<<30 + 1
+		}
+		if total != MaxPosition+1-gone {
+			t.Fatalf("strict %v: arc lengths sum to %d", strict, total)
+		}
+	}
+}
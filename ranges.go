@@ -0,0 +1,274 @@
+package consistent
+
+// OwnedRange is part of the hash space and the item owning it.
+type OwnedRange struct {
+	Node  string
+	Range HashRange
+}
+
+// Get the distinct items owning any part of the range from..to, in ring
+// order starting at from. The range wraps past the top when from > to.
+func (m *Consistent) OwnersInRange(from, to int) []string {
+	var nodes []string
+	seen := make(map[string]bool)
+	for _, o := range m.RangeOwners(from, to) {
+		if !seen[o.Node] {
+			seen[o.Node] = true
+			nodes = append(nodes, o.Node)
+		}
+	}
+	return nodes
+}
+
+// Split the range from..to into the parts Get maps to each item, in ring
+// order starting at from, range pins included. The range wraps past the top
+// when from > to. Parts no item serves, when every item is a standby or
+// unhealthy or a strict pin's item is gone, are left out.
+func (m *Consistent) RangeOwners(from, to int) []OwnedRange {
+	m.RLock()
+	defer m.RUnlock()
+	if m.ring.size() == 0 {
+		return nil
+	}
+
+	var owned []OwnedRange
+	if from <= to {
+		owned = m.ring.split(nil, from, to, m.atCapacity)
+	} else {
+		owned = m.ring.split(m.ring.split(nil, from, MaxPosition, m.atCapacity), 0, to, m.atCapacity)
+	}
+	if len(m.ring.rangePins) > 0 {
+		owned = m.ring.pinnedOwners(owned, HashRange{From: from, To: to}, m.strictPins)
+	}
+	return owned
+}
+
+// Append the parts of from..to, which must not wrap, served by each item.
+func (r *ring) split(owned []OwnedRange, from, to int, full func(mem *member) bool) []OwnedRange {
+	i := r.prevIndex(from)
+	serving := r.arcServer(i, full)
+	for {
+		end := MaxPosition
+		if next := r.key((i + 1) % r.size()); next > from {
+			end = next - 1
+		}
+		if serving >= 0 {
+			part := OwnedRange{
+				Node:  r.nodeAt(serving),
+				Range: HashRange{From: from, To: min(end, to)},
+			}
+
+			// Join neighbouring parts of the same item, including across the top
+			if n := len(owned); n > 0 && owned[n-1].Node == part.Node && (owned[n-1].Range.To+1)&MaxPosition == from {
+				owned[n-1].Range.To = part.Range.To
+			} else {
+				owned = append(owned, part)
+			}
+		}
+
+		if end >= to {
+			return owned
+		}
+		from = end + 1
+		i = (i + 1) % r.size()
+		if r.tiered() {
+			serving = r.arcServer(i, full)
+		} else if r.nodes[r.nodeAt(i)].eligible() {
+			serving = i
+		}
+	}
+}
+
+// Get the number of hash values Get maps to the provided item, including
+// the arc wrapping past the top and range pins, as Ranges counts them. The
+// lengths of all items sum to 2^32 while any item can be returned and no
+// strict range pin's item is gone; a standby or unhealthy item has none.
+func (m *Consistent) ArcLength(key string) (uint64, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return 0, false
+	}
+	if !mem.eligible() {
+		return 0, true
+	}
+
+	var length uint64
+	if m.ring.tiered() || len(m.ring.rangePins) > 0 {
+		var arcs []HashRange
+		if m.ring.tiered() {
+			arcs = m.ring.tieredRanges(key, m.atCapacity)
+		} else {
+			for _, pos := range mem.positions {
+				arcs = append(arcs, m.ring.servedArc(m.ring.index.search(pos)))
+			}
+		}
+		if len(m.ring.rangePins) > 0 {
+			arcs = m.ring.pinnedRanges(key, arcs, m.strictPins)
+		}
+		for _, arc := range arcs {
+			length += uint64((arc.To-arc.From)&MaxPosition) + 1
+		}
+		return length, true
+	}
+	for _, pos := range mem.positions {
+		arc := m.ring.servedArc(m.ring.index.search(pos))
+		length += uint64((arc.To-arc.From)&MaxPosition) + 1
+	}
+	return length, true
+}
+
+func (r *ring) arcLength(i int) uint64 {
+	if r.size() == 1 {
+		return MaxPosition + 1
+	}
+	arc := r.arc(i)
+	return uint64((arc.To-arc.From)&MaxPosition) + 1
+}
+
+// Get the positions of the points of the provided item, sorted.
+func (m *Consistent) PositionsOf(key string) ([]int, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return nil, false
+	}
+	return append([]int(nil), mem.positions...), true
+}
+
+// Get the ranges that would move if the provided item were removed, and the
+// item each would move to, in order of position. The cost depends only on
+// the item's points. A standby or unhealthy item serves no keys, so nothing
+// moves.
+func (m *Consistent) DrainPlan(key string) []Transfer {
+	m.RLock()
+	defer m.RUnlock()
+	return m.ring.drainPlan(key)
+}
+
+func (r *ring) drainPlan(key string) []Transfer {
+	mem, ok := r.nodes[key]
+	if !ok || !mem.eligible() {
+		return nil
+	}
+
+	var plan []Transfer
+	for _, pos := range mem.positions {
+		i := r.index.search(pos)
+		t := Transfer{Range: r.servedArc(i), From: key}
+		r.walkBack(i, func(j int) bool {
+			node := r.nodeAt(j)
+			if node == key || !r.nodes[node].eligible() {
+				return true
+			}
+			t.To = node
+			return false
+		})
+		plan = appendTransfer(plan, t)
+	}
+	return joinWrapped(plan)
+}
+
+// Get the fraction of the hash space each item would take over if the
+// provided item were removed, from the ranges of DrainPlan, without
+// changing the ring. The fractions sum to the item's share in Stats, unless
+// no other item can take its keys.
+func (m *Consistent) FailoverImpact(key string) map[string]float64 {
+	m.RLock()
+	defer m.RUnlock()
+	impact := make(map[string]float64)
+	for _, t := range m.ring.drainPlan(key) {
+		if t.To != "" {
+			impact[t.To] += float64(uint64((t.Range.To-t.Range.From)&MaxPosition)+1) / (MaxPosition + 1)
+		}
+	}
+	return impact
+}
+
+// Get the fraction of the provided keys each item would take over if the
+// provided item were removed, as FailoverImpact does for the hash space.
+// Keys pinned to the item fall back as Get would once it is gone.
+func (m *Consistent) FailoverImpactKeys(key string, keys []string) map[string]float64 {
+	m.RLock()
+	defer m.RUnlock()
+	impact := make(map[string]float64)
+	for _, k := range keys {
+		hash := m.Hash(k)
+		if m.ownerOf(k, hash) != key {
+			continue
+		}
+		if node := m.failoverOf(k, hash, key); node != "" {
+			impact[node] += 1 / float64(len(keys))
+		}
+	}
+	return impact
+}
+
+// The item a key would belong to with the provided item removed, or "".
+func (m *Consistent) failoverOf(key string, hash int, gone string) string {
+	resolve := func(node string) (string, bool) {
+		if mem, ok := m.ring.nodes[node]; ok && node != gone && mem.eligible() {
+			return node, true
+		}
+		return "", m.strictPins
+	}
+	if node, ok := m.ring.pins[m.pinKey(key)]; ok {
+		if node, ok := resolve(node); ok {
+			return node
+		}
+	}
+	if len(m.ring.rangePins) > 0 {
+		if pin, ok := m.ring.rangePinAt(hash); ok {
+			if node, ok := resolve(pin.node); ok {
+				return node
+			}
+		}
+	}
+
+	if m.ring.tiered() {
+		if i := m.lookupTiered(hash, func(node string) bool { return node == gone }); i >= 0 {
+			return m.ring.nodeAt(i)
+		}
+		return ""
+	}
+	var to string
+	m.ring.walkBack(m.ring.prevIndex(hash), func(j int) bool {
+		node := m.ring.nodeAt(j)
+		if node == gone || !m.ring.nodes[node].eligible() {
+			return true
+		}
+		to = node
+		return false
+	})
+	return to
+}
+
+// Append a transfer, extending the last one if it continues it.
+func appendTransfer(plan []Transfer, t Transfer) []Transfer {
+	if n := len(plan); n > 0 && plan[n-1].From == t.From && plan[n-1].To == t.To && plan[n-1].Range.To+1 == t.Range.From {
+		plan[n-1].Range.To = t.Range.To
+		return plan
+	}
+	return append(plan, t)
+}
+
+// Join the transfer wrapping past the top to the one starting at zero.
+func joinWrapped(plan []Transfer) []Transfer {
+	if n := len(plan); n > 1 && plan[n-1].From == plan[0].From && plan[n-1].To == plan[0].To && (plan[n-1].Range.To+1)&MaxPosition == plan[0].Range.From {
+		plan[0].Range.From = plan[n-1].Range.From
+		plan = plan[:n-1]
+	}
+	return plan
+}
+
+// The range of keys served by the eligible point at index i: its arc and
+// those of the ineligible points after it, whose keys fall back to it.
+func (r *ring) servedArc(i int) HashRange {
+	j := (i + 1) % r.size()
+	for j != i && !r.nodes[r.nodeAt(j)].eligible() {
+		j = (j + 1) % r.size()
+	}
+	return HashRange{From: r.key(i), To: (r.key(j) - 1) & MaxPosition}
+}
@@ -0,0 +1,240 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestRangeOwners(t *testing.T) {
+	c := New(fixedHash(map[string]uint32{"a": 100, "b": 200, "c": 300}))
+	if got := c.RangeOwners(0, MaxPosition); got != nil {
+		t.Fatalf("an empty hash has owners %v", got)
+	}
+	c.Add("a")
+	c.Add("b")
+	c.Add("c")
+	for _, tc := range []struct {
+		name     string
+		from, to int
+		want     []OwnedRange
+	}{
+		{"inside one arc", 110, 150, []OwnedRange{{"a", HashRange{110, 150}}}},
+		{"one position", 200, 200, []OwnedRange{{"b", HashRange{200, 200}}}},
+		{"a whole arc", 100, 199, []OwnedRange{{"a", HashRange{100, 199}}}},
+		{"spanning arcs", 150, 250, []OwnedRange{{"a", HashRange{150, 199}}, {"b", HashRange{200, 250}}}},
+		{"all arcs", 0, MaxPosition, []OwnedRange{
+			{"c", HashRange{0, 99}}, {"a", HashRange{100, 199}}, {"b", HashRange{200, 299}}, {"c", HashRange{300, MaxPosition}},
+		}},
+		{"wrapping inside one arc", 350, 50, []OwnedRange{{"c", HashRange{350, 50}}}},
+		{"wrapping across arcs", 250, 150, []OwnedRange{
+			{"b", HashRange{250, 299}}, {"c", HashRange{300, 99}}, {"a", HashRange{100, 150}},
+		}},
+		{"wrapping from the top", MaxPosition, 0, []OwnedRange{{"c", HashRange{MaxPosition, 0}}}},
+	} {
+		got := c.RangeOwners(tc.from, tc.to)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: RangeOwners(%d, %d) = %v, want %v", tc.name, tc.from, tc.to, got, tc.want)
+		}
+		var nodes []string
+		for _, o := range tc.want {
+			if !slices.Contains(nodes, o.Node) {
+				nodes = append(nodes, o.Node)
+			}
+		}
+		if got := c.OwnersInRange(tc.from, tc.to); !slices.Equal(got, nodes) {
+			t.Errorf("%s: OwnersInRange(%d, %d) = %v, want %v", tc.name, tc.from, tc.to, got, nodes)
+		}
+	}
+}
+
+// The parts of a random interval cover it in order, and Get agrees with the
+// owner of each part.
+func TestRangeOwnersMatchGet(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	points := make(map[string]uint32)
+	for i := 0; i < 20; i++ {
+		points[fmt.Sprint("n", i)] = uint32(rnd.Intn(10000))
+	}
+	c := New(fixedHash(points))
+	for node := range points {
+		c.Add(node)
+	}
+	for i := 0; i < 200; i++ {
+		from, to := rnd.Intn(10000), rnd.Intn(10000)
+		owned := c.RangeOwners(from, to)
+		next := from
+		for j, o := range owned {
+			if o.Range.From != next {
+				t.Fatalf("%d-%d: part %d starts at %d, want %d", from, to, j, o.Range.From, next)
+			}
+			if j > 0 && owned[j-1].Node == o.Node {
+				t.Fatalf("%d-%d: parts %d and %d of %s are not joined", from, to, j-1, j, o.Node)
+			}
+			mid := (o.Range.From + ((o.Range.To-o.Range.From)&MaxPosition)/2) & MaxPosition
+			for _, h := range []int{o.Range.From, mid, o.Range.To} {
+				if got := c.Get(fmt.Sprint(h)); got != o.Node {
+					t.Fatalf("%d-%d: %d is in a part of %s, Get returns %s", from, to, h, o.Node, got)
+				}
+			}
+			next = (o.Range.To + 1) & MaxPosition
+		}
+		if next != (to+1)&MaxPosition {
+			t.Fatalf("%d-%d: parts end before %d", from, to, next)
+		}
+	}
+}
+
+func TestArcLength(t *testing.T) {
+	c := New(fixedHash(map[string]uint32{"a": 100, "b": 200, "m": MaxPosition}))
+	if _, ok := c.ArcLength("a"); ok {
+		t.Fatal("length for an item not in the hash")
+	}
+	c.Add("a")
+	if got, _ := c.ArcLength("a"); got != 1<<32 {
+		t.Fatalf("a single item owns %d", got)
+	}
+	c.Add("b")
+	c.Add("m")
+	for node, want := range map[string]uint64{
+		"a": 100,
+		"b": MaxPosition - 200,
+		"m": 1 + 100, // The top position and the arc wrapping past it
+	} {
+		if got, ok := c.ArcLength(node); !ok || got != want {
+			t.Errorf("%s owns %d, want %d", node, got, want)
+		}
+	}
+}
+
+// The lengths cover the whole space, and what an added item owns is exactly
+// what the others lost.
+func TestArcLengthSums(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	c := New(nil, WithReplicas(8))
+	lengths := func() (map[string]uint64, uint64) {
+		t.Helper()
+		all := make(map[string]uint64)
+		var sum uint64
+		for _, node := range c.Members() {
+			length, ok := c.ArcLength(node)
+			if !ok {
+				t.Fatalf("no length for member %s", node)
+			}
+			all[node] = length
+			sum += length
+		}
+		return all, sum
+	}
+	for i := 0; i < 100; i++ {
+		c.AddWithWeight(fmt.Sprint("n", i), 1+rnd.Intn(20))
+		if i%3 == 2 {
+			c.Remove(fmt.Sprint("n", rnd.Intn(i+1)))
+		}
+		before, sum := lengths()
+		if sum != 1<<32 {
+			t.Fatalf("lengths sum to %d with %d items", sum, len(before))
+		}
+
+		added := fmt.Sprint("x", i)
+		c.AddWithWeight(added, 1+rnd.Intn(20))
+		after, sum := lengths()
+		if sum != 1<<32 {
+			t.Fatalf("lengths sum to %d after adding %s", sum, added)
+		}
+		var delta int64
+		for node, length := range after {
+			delta += int64(length) - int64(before[node])
+		}
+		if delta != 0 {
+			t.Fatalf("adding %s moved %d more than it took", added, delta)
+		}
+		c.Remove(added)
+	}
+}
+
+// The drain plan of an item is the difference to the hash without it.
+func TestDrainPlan(t *testing.T) {
+	m, without := New(nil, WithReplicas(20)), New(nil, WithReplicas(20))
+	for i := 0; i < 6; i++ {
+		m.Add(fmt.Sprint("n", i))
+		if i != 3 {
+			without.Add(fmt.Sprint("n", i))
+		}
+	}
+	m.AddStandby("s")
+	without.AddStandby("s")
+	if plan, want := m.DrainPlan("n3"), Diff(m, without); !slices.Equal(plan, want) {
+		t.Fatalf("plan %v, the hashes differ by %v", plan, want)
+	}
+	if plan := m.DrainPlan("s"); len(plan) != 0 {
+		t.Fatalf("draining a standby moves %v", plan)
+	}
+	if plan := m.DrainPlan("missing"); len(plan) != 0 {
+		t.Fatalf("draining an item not in the hash moves %v", plan)
+	}
+}
+
+// What each survivor absorbs adds up to the failed item's share, and agrees
+// with its drain plan and with the keys that move when it is removed.
+func TestFailoverImpact(t *testing.T) {
+	m, without := New(nil, WithReplicas(30)), New(nil, WithReplicas(30))
+	for i := 0; i < 6; i++ {
+		m.Add(fmt.Sprint("n", i))
+		if i != 2 {
+			without.Add(fmt.Sprint("n", i))
+		}
+	}
+	m.SetHealthy("n5", false)
+	without.SetHealthy("n5", false)
+
+	impact := m.FailoverImpact("n2")
+	var sum float64
+	for node, f := range impact {
+		if node == "n2" || node == "n5" {
+			t.Fatalf("%s absorbs %v", node, f)
+		}
+		sum += f
+	}
+	if share := m.Stats().Shares["n2"]; math.Abs(sum-share) > 1e-12 {
+		t.Fatalf("survivors absorb %v, n2 has %v", sum, share)
+	}
+
+	planned := make(map[string]float64)
+	for _, tr := range m.DrainPlan("n2") {
+		planned[tr.To] += float64(rangeLength(tr.Range)) / (1 << 32)
+	}
+	if len(planned) != len(impact) {
+		t.Fatalf("the drain plan moves to %v, the impact is on %v", planned, impact)
+	}
+	for node, f := range planned {
+		if math.Abs(impact[node]-f) > 1e-12 {
+			t.Fatalf("%s absorbs %v, its drain plan ranges %v", node, impact[node], f)
+		}
+	}
+
+	keys := testKeys(20000)
+	moved := make(map[string]float64)
+	for _, key := range keys {
+		if m.Get(key) == "n2" {
+			moved[without.Get(key)] += 1 / float64(len(keys))
+		}
+	}
+	sampled := m.FailoverImpactKeys("n2", keys)
+	for node, f := range moved {
+		if math.Abs(sampled[node]-f) > 1e-9 {
+			t.Fatalf("%s absorbs %v of the keys, %v moved to it", node, sampled[node], f)
+		}
+		if math.Abs(impact[node]-f) > 0.02 {
+			t.Fatalf("%s absorbs %v of the space and %v of the keys", node, impact[node], f)
+		}
+	}
+
+	one := New(nil)
+	one.Add("solo")
+	if len(one.FailoverImpact("solo")) != 0 || len(one.FailoverImpact("missing")) != 0 {
+		t.Fatal("an impact with no survivors or no item")
+	}
+}
@@ -0,0 +1,114 @@
+package consistent
+
+import (
+	"errors"
+	"hash"
+	"io"
+)
+
+var ErrHashChanged = errors.New("consistent: hash function changed while reading the key")
+
+// Hash with a new hash.Hash32 from fn for each key, so HashReader and
+// GetReader can stream keys through it. Keys given as strings hash exactly as
+// with WithHash of the same algorithm.
+func WithHasher(fn func() hash.Hash32) Option {
+	if fn == nil {
+		return func(*Consistent) {}
+	}
+	return withStream(func() hash.Hash { return fn() }, func(h hash.Hash) uint32 {
+		return h.(hash.Hash32).Sum32()
+	})
+}
+
+// Hash with a new hash.Hash64 from fn for each key, folded to 32 bits as
+// WithHash64 does.
+func WithHasher64(fn func() hash.Hash64) Option {
+	if fn == nil {
+		return func(*Consistent) {}
+	}
+	return withStream(func() hash.Hash { return fn() }, func(h hash.Hash) uint32 {
+		sum := h.(hash.Hash64).Sum64()
+		return uint32(sum ^ sum>>32)
+	})
+}
+
+func withStream(fn func() hash.Hash, sum func(hash.Hash) uint32) Option {
+	return func(m *Consistent) {
+		f := Hash(func(data []byte) uint32 {
+			h := fn()
+			h.Write(data)
+			return sum(h)
+		})
+		m.hash.Store(&f)
+		m.hashName, m.seed = "", 0
+		m.stream = &streamHash{fn: fn, sum: sum, of: &f}
+	}
+}
+
+// A streaming form of a hash function, usable while it is the hash's.
+type streamHash struct {
+	fn  func() hash.Hash
+	sum func(hash.Hash) uint32
+	of  *Hash
+}
+
+// Hash a key read from r until EOF, as Hash would hash the same bytes,
+// except that the key transform is not applied. With WithHasher the key is
+// streamed, unless WithHashTags is set; otherwise it is read into memory
+// first.
+func (m *Consistent) HashReader(r io.Reader) (int, error) {
+	hash, _, err := m.hashReader(r)
+	return hash, err
+}
+
+func (m *Consistent) hashReader(r io.Reader) (int, *Hash, error) {
+	fn := m.hash.Load()
+	if s := m.stream; s != nil && s.of == fn && !m.hashTags {
+		h := s.fn()
+		if m.domains {
+			io.WriteString(h, keyDomain)
+		}
+		if _, err := io.Copy(h, r); err != nil {
+			return 0, fn, err
+		}
+		return int(s.sum(h)), fn, nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fn, err
+	}
+	if m.hashTags {
+		data = []byte(hashTag(string(data)))
+	}
+	if m.domains {
+		data = append([]byte(keyDomain), data...)
+	}
+	return int((*fn)(data)), fn, nil
+}
+
+// Get the item a key read from r belongs to, as Get would for the same
+// bytes. Range pins apply; pins of keys and the key transform do not.
+// Returns ErrEmpty if there is no item for the key, the reader's error if it
+// fails, and ErrHashChanged if SetHash ran while the key was read, since it
+// cannot be read again.
+func (m *Consistent) GetReader(r io.Reader) (string, error) {
+	hash, fn, err := m.hashReader(r)
+	if err != nil {
+		return "", err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	if m.hash.Load() != fn {
+		return "", ErrHashChanged
+	}
+	node, ok := m.rangePinned(hash)
+	if !ok {
+		node = m.lookupNode(hash)
+	}
+	if node == "" {
+		return "", ErrEmpty
+	}
+	return node, nil
+}
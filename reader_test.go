@@ -0,0 +1,115 @@
+package consistent
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// A reader of n generated bytes, failing with err after them if err is set.
+type patternReader struct {
+	n   int
+	err error
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		return 0, io.EOF
+	}
+	n := min(len(p), r.n)
+	for i := range n {
+		p[i] = byte(r.n - i)
+	}
+	r.n -= n
+	return n, nil
+}
+
+// The streamed hashes place items and keys as the functions of the same
+// algorithm do.
+func TestHasherMatchesHash(t *testing.T) {
+	pairs := [][2]*Consistent{
+		{New(nil), New(nil, WithHasher(crc32.NewIEEE))},
+		{New(nil, WithHash64(fnv64), WithDomainSeparation()), New(nil, WithHasher64(fnv.New64a), WithDomainSeparation())},
+	}
+	big := bytes.Repeat([]byte("0123456789abcdef"), 1<<18) // 4 MiB
+	for i, pair := range pairs {
+		for _, m := range pair {
+			for n := 0; n < 5; n++ {
+				m.AddWithWeight(fmt.Sprint("n", n), 10)
+			}
+		}
+		if pair[0].Fingerprint() != pair[1].Fingerprint() {
+			t.Fatalf("pair %d: placement differs", i)
+		}
+		for _, m := range pair {
+			for _, key := range []string{"", "key", string(big)} {
+				h, err := m.HashReader(strings.NewReader(key))
+				if err != nil || h != pair[0].Hash(key) {
+					t.Fatalf("pair %d: HashReader of %d bytes = %d, %v, want %d", i, len(key), h, err, pair[0].Hash(key))
+				}
+				node, err := m.GetReader(strings.NewReader(key))
+				if err != nil || node != pair[0].Get(key) {
+					t.Fatalf("pair %d: GetReader of %d bytes = %s, %v, want %s", i, len(key), node, err, pair[0].Get(key))
+				}
+			}
+		}
+	}
+}
+
+// A streamed key is hashed as it is read, not held in memory.
+func TestHashReaderStreams(t *testing.T) {
+	m := New(nil, WithHasher(crc32.NewIEEE))
+	m.Add("a")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := m.GetReader(&patternReader{n: 64 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Fatalf("reading a 64 MiB key allocated %d bytes", n)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	boom := errors.New("boom")
+	for name, m := range map[string]*Consistent{
+		"streamed": New(nil, WithHasher(crc32.NewIEEE)),
+		"buffered": New(nil),
+	} {
+		m.Add("a")
+		if _, err := m.GetReader(&patternReader{n: 3 << 20, err: boom}); !errors.Is(err, boom) {
+			t.Errorf("%s: GetReader of a failing reader returned %v", name, err)
+		}
+		if _, err := m.HashReader(&patternReader{n: 10, err: boom}); !errors.Is(err, boom) {
+			t.Errorf("%s: HashReader of a failing reader returned %v", name, err)
+		}
+		m.Remove("a")
+		if _, err := m.GetReader(strings.NewReader("key")); !errors.Is(err, ErrEmpty) {
+			t.Errorf("%s: GetReader on an empty hash returned %v", name, err)
+		}
+	}
+
+	m := New(nil, WithHasher(crc32.NewIEEE))
+	m.Add("a")
+	r := io.MultiReader(strings.NewReader("half"), readFunc(func([]byte) (int, error) {
+		m.SetHash64(fnv64)
+		return 0, io.EOF
+	}))
+	if _, err := m.GetReader(r); !errors.Is(err, ErrHashChanged) {
+		t.Fatalf("the hash changed while reading, GetReader returned %v", err)
+	}
+}
+
+type readFunc func(p []byte) (int, error)
+
+func (f readFunc) Read(p []byte) (int, error) { return f(p) }
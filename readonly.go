@@ -0,0 +1,122 @@
+package consistent
+
+// Mark an item read-only or writable. A read-only item still serves reads,
+// but write lookups skip it as if it were absent, so its keys are written
+// where they would go after a Remove until the flag clears.
+func (m *Consistent) SetReadOnly(key string, readOnly bool) error {
+	var err error
+	m.reroute(func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if mem.readOnly == readOnly {
+			return nil
+		}
+		mem.readOnly = readOnly
+		return &Event{Type: EventReadOnly, Changed: []string{key}}
+	})
+
+	return err
+}
+
+// Returns true if the item is present and marked read-only.
+func (m *Consistent) IsReadOnly(key string) bool {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	return ok && mem.readOnly
+}
+
+// Returns the items marked read-only, sorted by name.
+func (m *Consistent) ReadOnlyMembers() []string {
+	m.RLock()
+	defer m.RUnlock()
+	var members []string
+	for _, key := range sortedKeys(m.ring.nodes) {
+		if m.ring.nodes[key].readOnly {
+			members = append(members, key)
+		}
+	}
+	return members
+}
+
+// Get the item to read the provided key from. Read-only items serve reads,
+// so this is Get.
+func (m *Consistent) GetForRead(key string) string {
+	return m.Get(key)
+}
+
+// Get the item to write the provided key to, skipping read-only items as
+// well as those Get skips. A pin to a read-only item is ignored for writes.
+func (m *Consistent) GetForWrite(key string) string {
+	owners := m.GetOwnersForWrite(key, 1)
+	if len(owners) == 0 {
+		return ""
+	}
+	return owners[0]
+}
+
+// Get up to n distinct items to write the provided key to, in NextN order
+// with read-only items skipped: the item GetForWrite returns, then the next
+// writable items clockwise.
+func (m *Consistent) GetOwnersForWrite(key string, n int) []string {
+	if checkCount(n) {
+		return []string{}
+	}
+	m.coolDue()
+	m.sample(key)
+
+	m.RLock()
+	defer m.RUnlock()
+	hash := m.Hash(key)
+	if m.ring.size() == 0 {
+		return nil
+	}
+
+	var nodes []string
+	pinned, ok := m.pinned(key)
+	if ok {
+		if pinned == "" {
+			return nil
+		}
+		if m.ring.nodes[pinned].readOnly {
+			pinned = ""
+		} else {
+			nodes = append(nodes, pinned)
+		}
+	}
+
+	start := m.ring.prevIndex(hash)
+	primary := -1
+	m.ring.walkBack(start, func(i int) bool {
+		if mem := m.ring.nodes[m.ring.nodeAt(i)]; !mem.eligible() || mem.readOnly {
+			return true
+		}
+		primary = i
+		return false
+	})
+
+	seen := map[string]bool{pinned: pinned != ""}
+	if primary >= 0 && len(nodes) < n {
+		node := m.ring.nodeAt(primary)
+		if !seen[node] {
+			seen[node] = true
+			nodes = append(nodes, node)
+		}
+	}
+	m.ring.walk(start, func(i int) bool {
+		if len(nodes) >= n {
+			return false
+		}
+		node := m.ring.nodeAt(i)
+		if !seen[node] && !m.ring.nodes[node].readOnly {
+			nodes = append(nodes, node)
+		}
+		seen[node] = true
+		return true
+	})
+
+	return nodes
+}
@@ -0,0 +1,100 @@
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	without := New(nil, WithReplicas(10))
+	for _, node := range []string{"a", "b", "c", "d"} {
+		m.Add(node)
+		if node != "b" {
+			without.Add(node)
+		}
+	}
+	keys := testKeys(500)
+	for _, key := range keys {
+		if w := m.GetForWrite(key); w != m.Get(key) {
+			t.Fatalf("%s is written to %s but read from %s with nothing read-only", key, w, m.Get(key))
+		}
+		if w, n := m.GetOwnersForWrite(key, 3), m.NextN(key, 3); !slices.Equal(w, n) {
+			t.Fatalf("%s: writers %v, NextN %v", key, w, n)
+		}
+	}
+
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+	gen := m.Generation()
+	if err := m.SetReadOnly("b", true); err != nil {
+		t.Fatal(err)
+	}
+	m.SetReadOnly("b", true)
+	if len(events) != 1 || events[0].Type != EventReadOnly || !slices.Equal(events[0].Changed, []string{"b"}) {
+		t.Errorf("events %+v, want one read-only event for b", events)
+	}
+	if m.Generation() != gen {
+		t.Errorf("marking read-only changed the generation from %d to %d", gen, m.Generation())
+	}
+	if !m.IsReadOnly("b") || m.IsReadOnly("a") || m.IsReadOnly("missing") {
+		t.Error("IsReadOnly reports the wrong items")
+	}
+	if got := m.ReadOnlyMembers(); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("read-only members %v", got)
+	}
+
+	// Reads stay put; writes go where they would without b
+	for _, key := range keys {
+		if r := m.GetForRead(key); r != m.Get(key) {
+			t.Fatalf("%s is read from %s, Get says %s", key, r, m.Get(key))
+		}
+		if w := m.GetForWrite(key); w != without.Get(key) {
+			t.Fatalf("%s is written to %s, %s without b", key, w, without.Get(key))
+		}
+		if w, n := m.GetOwnersForWrite(key, 4), without.NextN(key, 4); !slices.Equal(w, n) {
+			t.Fatalf("%s: writers %v, %v without b", key, w, n)
+		}
+	}
+	s := m.Stats()
+	if s.ReadOnly != 1 || s.WriteRestricted != s.Shares["b"] {
+		t.Errorf("stats: %d read-only, %v write restricted, b has %v", s.ReadOnly, s.WriteRestricted, s.Shares["b"])
+	}
+
+	if err := m.SetReadOnly("b", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range keys {
+		if m.GetForWrite(key) != m.Get(key) {
+			t.Fatalf("%s still written away from b", key)
+		}
+	}
+}
+
+func TestReadOnlyEdges(t *testing.T) {
+	m := New(nil)
+	if err := m.SetReadOnly("missing", true); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("SetReadOnly of a missing item: %v", err)
+	}
+	if w := m.GetForWrite("k"); w != "" {
+		t.Errorf("written to %q in an empty hash", w)
+	}
+
+	m.Add("a")
+	m.Add("b")
+	if err := m.Pin("k", "a"); err != nil {
+		t.Fatal(err)
+	}
+	m.SetReadOnly("a", true)
+	if r, w := m.GetForRead("k"), m.GetForWrite("k"); r != "a" || w != "b" {
+		t.Errorf("pinned to read-only a: read from %s, written to %s", r, w)
+	}
+	m.SetReadOnly("b", true)
+	if w := m.GetOwnersForWrite("k", 2); len(w) != 0 {
+		t.Errorf("written to %v with every item read-only", w)
+	}
+	if w := m.GetOwnersForWrite("k", 0); w == nil || len(w) != 0 {
+		t.Errorf("GetOwnersForWrite(k, 0) = %#v, want an empty slice", w)
+	}
+}
@@ -0,0 +1,76 @@
+package consistent
+
+import (
+	"errors"
+)
+
+// Hash64 is a 64-bit hash function. Its halves are folded together into the
+// 32-bit positions of the ring.
+type Hash64 func(data []byte) uint64
+
+func fold64(fn Hash64) Hash {
+	return func(data []byte) uint32 {
+		h := fn(data)
+		return uint32(h ^ h>>32)
+	}
+}
+
+// Use a 64-bit hash function, as WithHash does.
+func WithHash64(fn Hash64) Option {
+	if fn == nil {
+		return func(*Consistent) {}
+	}
+	return WithHash(fold64(fn))
+}
+
+// Replace the hash function, placing every point again with it under one
+// write lock, so lookups see either the old or the new placement. Items keep
+// their weights, roles, health, zones, capacities and load, and pins stay,
+// but a renamed item is placed by its new name. The result is the ring a new
+// hash with fn and the same members would have without
+// WithArcSplittingPlacement, and watchers receive a
+// single EventRebuild listing every item as changed. The generation moves
+// on but the history is dropped, since the change cannot be replayed by
+// ApplyDelta: followers have to start over from a snapshot. To move keys
+// gradually, build a second hash and route through a Migrator instead.
+func (m *Consistent) SetHash(fn Hash) error {
+	if fn == nil {
+		return errors.New("consistent: hash function must not be nil")
+	}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		m.hash.Store(&fn)
+		m.hashName, m.seed = "", 0
+
+		// Placed by hash even with WithArcSplittingPlacement
+		r := m.newRing()
+		r.splitArcs = false
+		r.staged = make(map[int]point, m.ring.size())
+		members := sortedKeys(m.ring.nodes)
+		for _, key := range members {
+			prev := m.ring.nodes[key]
+			r.add(key, prev.weight)
+			mem := r.nodes[key]
+			id, positions := mem.id, mem.positions
+			*mem = *prev
+			mem.id, mem.positions = id, positions
+		}
+		r.pins, r.rangePins, r.halfOpen, r.tiers = m.ring.pins, m.ring.rangePins, m.ring.halfOpen, m.ring.tiers
+		r.sortKeys()
+		r.splitArcs = m.arcSplit
+		m.ring = r
+
+		return &Event{Type: EventRebuild, Changed: members}
+	})
+
+	return err
+}
+
+// Replace the hash function with a 64-bit one, as SetHash does.
+func (m *Consistent) SetHash64(fn Hash64) error {
+	if fn == nil {
+		return errors.New("consistent: hash function must not be nil")
+	}
+	return m.SetHash(fold64(fn))
+}
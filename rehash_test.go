@@ -0,0 +1,122 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func fnv64(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// A rebuilt ring is the ring a new hash with the same members would have,
+// and keeps the items' state.
+func TestSetHashMatchesFresh(t *testing.T) {
+	for _, bits := range []int{0, 8} {
+		m := New(nil, WithReplicas(20), WithLookupTable(bits))
+		fresh := New(nil, WithReplicas(20), WithHash64(fnv64), WithLookupTable(bits))
+		for _, c := range []*Consistent{m, fresh} {
+			for i := 0; i < 10; i++ {
+				c.AddWithWeight(fmt.Sprint("n", i), 10+i)
+			}
+			c.AddStandby("s")
+			c.Pin("pinned", "n3")
+			c.SetHealthy("n2", false)
+		}
+		m.SetZone("n1", "z")
+		gen := m.Generation()
+		var events []Event
+		m.Watch(func(e Event) { events = append(events, e) })
+
+		if err := m.SetHash64(fnv64); err != nil {
+			t.Fatal(err)
+		}
+		if m.Fingerprint() != fresh.Fingerprint() {
+			t.Fatalf("table bits %d: placement differs from a new hash", bits)
+		}
+		for _, key := range testKeys(2000) {
+			if got, want := m.NextN(key, 3), fresh.NextN(key, 3); m.Get(key) != fresh.Get(key) || !slices.Equal(got, want) {
+				t.Fatalf("table bits %d: %s goes to %s %v, want %s %v", bits, key, m.Get(key), got, fresh.Get(key), want)
+			}
+		}
+		if zone, _ := m.Zone("n1"); zone != "z" || m.IsHealthy("n2") || !m.IsStandby("s") || m.Get("pinned") != "n3" {
+			t.Fatal("the rebuild lost the zone, health, role or pin of an item")
+		}
+		for i := 0; i < 10; i++ {
+			if w, _ := m.Weight(fmt.Sprint("n", i)); w != 10+i {
+				t.Fatalf("n%d has weight %d, want %d", i, w, 10+i)
+			}
+		}
+
+		if len(events) != 1 || events[0].Type != EventRebuild || len(events[0].Changed) != 11 {
+			t.Fatalf("events %v, want one rebuild of 11 items", events)
+		}
+		if m.Generation() != gen+1 {
+			t.Fatalf("generation %d, want %d", m.Generation(), gen+1)
+		}
+		if _, err := m.ChangesSince(gen); !errors.Is(err, ErrHistoryTooOld) {
+			t.Fatalf("history from before the rebuild: %v", err)
+		}
+		m.Add("x")
+		if d, err := m.ChangesSince(gen + 1); err != nil || len(d.Steps) != 1 {
+			t.Fatalf("history after the rebuild: %v, %v", d, err)
+		}
+		if err := m.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := New(nil)
+	if err := m.SetHash(nil); err == nil {
+		t.Fatal("set a nil hash")
+	}
+	if err := m.SetHash64(nil); err == nil {
+		t.Fatal("set a nil 64-bit hash")
+	}
+}
+
+// Lookups racing rebuilds see either placement, never a mix.
+func TestSetHashConcurrentReaders(t *testing.T) {
+	m := New(nil, WithReplicas(50), WithLookupTable(10))
+	old := New(nil, WithReplicas(50))
+	rebuilt := New(nil, WithReplicas(50), WithHash64(fnv64))
+	for _, c := range []*Consistent{m, old, rebuilt} {
+		for i := 0; i < 20; i++ {
+			c.Add(fmt.Sprint("n", i))
+		}
+	}
+	keys := testKeys(500)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := keys[i%len(keys)]
+				if got := m.Get(key); got != old.Get(key) && got != rebuilt.Get(key) {
+					t.Errorf("%s went to %s, on neither placement", key, got)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		m.SetHash64(fnv64)
+		m.SetHash(crc32.ChecksumIEEE)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,50 @@
+package consistent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Remove every key for which pred returns true, under a single write lock,
+// and return the removed keys sorted. Watchers receive a single EventRemove
+// listing them. pred is called once per key in name order, under the lock,
+// so it must not call methods of the hash. If it panics nothing is removed
+// and the panic is returned as an error.
+func (m *Consistent) RemoveFunc(pred func(node string) bool) (removed []string, err error) {
+	m.mutate(&err, func() *Event {
+		removed, err = m.matching(pred)
+		if err != nil || len(removed) == 0 {
+			return nil
+		}
+		for _, key := range removed {
+			m.ring.remove(key)
+			m.history.record(Change{Type: ChangeRemove, Key: key})
+		}
+		return &Event{Type: EventRemove, Removed: removed}
+	})
+
+	return removed, err
+}
+
+// Remove every key starting with prefix, as RemoveFunc does.
+func (m *Consistent) RemoveByPrefix(prefix string) []string {
+	removed, _ := m.RemoveFunc(func(node string) bool {
+		return strings.HasPrefix(node, prefix)
+	})
+	return removed
+}
+
+// Get the keys matching pred, recovering a panic in it.
+func (m *Consistent) matching(pred func(node string) bool) (matched []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			matched, err = nil, fmt.Errorf("consistent: predicate panicked: %v", r)
+		}
+	}()
+	for _, key := range sortedKeys(m.ring.nodes) {
+		if pred(key) {
+			matched = append(matched, key)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,77 @@
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRemoveFunc(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	for _, node := range []string{"us-east-1a/h2", "us-east-1a/h1", "us-east-1b/h3", "eu/h4"} {
+		m.Add(node)
+	}
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+
+	var asked []string
+	removed, err := m.RemoveFunc(func(node string) bool {
+		asked = append(asked, node)
+		return strings.HasSuffix(node, "1") || strings.HasPrefix(node, "eu/")
+	})
+	if err != nil || !slices.Equal(removed, []string{"eu/h4", "us-east-1a/h1"}) {
+		t.Fatalf("removed %v, %v", removed, err)
+	}
+	if !slices.Equal(asked, []string{"eu/h4", "us-east-1a/h1", "us-east-1a/h2", "us-east-1b/h3"}) {
+		t.Fatalf("the predicate was called for %v", asked)
+	}
+	if len(events) != 1 || events[0].Type != EventRemove || !slices.Equal(events[0].Removed, removed) {
+		t.Fatalf("events %v, want one removal of %v", events, removed)
+	}
+	if got := m.Members(); !slices.Equal(got, []string{"us-east-1a/h2", "us-east-1b/h3"}) {
+		t.Fatalf("members %v", got)
+	}
+
+	if removed := m.RemoveByPrefix("zz"); removed != nil || len(events) != 1 {
+		t.Fatalf("removing no items removed %v with %d events", removed, len(events))
+	}
+	if removed := m.RemoveByPrefix("us-east-1a/"); !slices.Equal(removed, []string{"us-east-1a/h2"}) {
+		t.Fatalf("removed %v by prefix", removed)
+	}
+}
+
+// A panicking predicate leaves the hash as it was and usable.
+func TestRemoveFuncPanic(t *testing.T) {
+	m := New(nil, WithReplicas(10))
+	for _, node := range []string{"a", "b", "c"} {
+		m.Add(node)
+	}
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+	fingerprint, gen := m.Fingerprint(), m.Generation()
+
+	removed, err := m.RemoveFunc(func(node string) bool {
+		if node == "c" {
+			panic("boom")
+		}
+		return true
+	})
+	if err == nil || !strings.Contains(err.Error(), "boom") || removed != nil {
+		t.Fatalf("a panicking predicate removed %v, %v", removed, err)
+	}
+	if m.Fingerprint() != fingerprint || m.Generation() != gen || len(events) != 0 {
+		t.Fatal("a panicking predicate changed the hash")
+	}
+	if err := m.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if removed := m.RemoveByPrefix("c"); !slices.Equal(removed, []string{"c"}) {
+		t.Fatalf("after the panic, removed %v", removed)
+	}
+
+	m.Freeze()
+	if removed, err := m.RemoveFunc(func(string) bool { return true }); !errors.Is(err, ErrFrozen) || removed != nil {
+		t.Fatalf("a frozen hash removed %v, %v", removed, err)
+	}
+}
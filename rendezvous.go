@@ -0,0 +1,172 @@
+package consistent
+
+import (
+	"hash/crc32"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Rendezvous is a weighted rendezvous (highest random weight) hash. Each item
+// scores every key with -weight / ln(u), where u is uniform in (0, 1) and
+// derived from the key and the item, and the highest score wins. An item's
+// share of keys is proportional to its weight, and changing one item's
+// weight only moves keys to or from that item.
+type Rendezvous struct {
+	sync.RWMutex
+	hash  Hash
+	nodes []rendezvousNode // Sorted by name
+}
+
+type rendezvousNode struct {
+	name   string
+	hash   uint64
+	weight float64
+}
+
+func NewRendezvous(fn Hash) *Rendezvous {
+	if fn == nil {
+		fn = crc32.ChecksumIEEE
+	}
+	return &Rendezvous{hash: fn}
+}
+
+// Returns true if there are no items with a weight above zero.
+func (r *Rendezvous) IsEmpty() bool {
+	r.RLock()
+	defer r.RUnlock()
+	for _, n := range r.nodes {
+		if n.weight > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the items, sorted by name.
+func (r *Rendezvous) Members() []string {
+	r.RLock()
+	defer r.RUnlock()
+	members := make([]string, len(r.nodes))
+	for i, n := range r.nodes {
+		members[i] = n.name
+	}
+	return members
+}
+
+// Add an item with weight one, or set the weight of an item already present
+// to one.
+func (r *Rendezvous) Add(key string) error {
+	return r.AddWithWeight(key, 1)
+}
+
+// Add an item with the given weight, or change the weight of an item already
+// present. An item with weight zero is kept but never chosen.
+func (r *Rendezvous) AddWithWeight(key string, weight int) error {
+	if weight < 0 {
+		return ErrInvalidWeight
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	i := sort.Search(len(r.nodes), func(i int) bool { return r.nodes[i].name >= key })
+	if i < len(r.nodes) && r.nodes[i].name == key {
+		r.nodes[i].weight = float64(weight)
+		return nil
+	}
+	r.nodes = append(r.nodes, rendezvousNode{})
+	copy(r.nodes[i+1:], r.nodes[i:])
+	r.nodes[i] = rendezvousNode{
+		name:   key,
+		hash:   uint64(r.hash([]byte(key))),
+		weight: float64(weight),
+	}
+	return nil
+}
+
+// Returns the weight of an item.
+func (r *Rendezvous) Weight(key string) (int, bool) {
+	r.RLock()
+	defer r.RUnlock()
+	i := sort.Search(len(r.nodes), func(i int) bool { return r.nodes[i].name >= key })
+	if i < len(r.nodes) && r.nodes[i].name == key {
+		return int(r.nodes[i].weight), true
+	}
+	return 0, false
+}
+
+// Remove an item.
+func (r *Rendezvous) Remove(key string) {
+	r.Lock()
+	defer r.Unlock()
+	i := sort.Search(len(r.nodes), func(i int) bool { return r.nodes[i].name >= key })
+	if i < len(r.nodes) && r.nodes[i].name == key {
+		r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+	}
+}
+
+// Get the item with the highest score for the provided key.
+func (r *Rendezvous) Get(key string) string {
+	hash := uint64(r.hash([]byte(key)))
+
+	r.RLock()
+	defer r.RUnlock()
+	best, node := math.Inf(-1), ""
+	for _, n := range r.nodes {
+		if n.weight == 0 {
+			continue
+		}
+		if s := n.score(hash); s > best {
+			best, node = s, n.name
+		}
+	}
+	return node
+}
+
+// Get the n items with the highest scores for the provided key, highest
+// first. The first is the item Get returns.
+func (r *Rendezvous) GetN(key string, n int) []string {
+	hash := uint64(r.hash([]byte(key)))
+
+	r.RLock()
+	type scored struct {
+		name  string
+		score float64
+	}
+	all := make([]scored, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		if node.weight > 0 {
+			all = append(all, scored{node.name, node.score(hash)})
+		}
+	}
+	r.RUnlock()
+
+	// Stable over the name order, so equal scores rank like Get
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+
+	n = min(n, len(all))
+	if n <= 0 {
+		return nil
+	}
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = all[i].name
+	}
+	return nodes
+}
+
+func (n rendezvousNode) score(key uint64) float64 {
+	// A uniform value in (0, 1) from the item and key hashes
+	u := (float64(mix64(n.hash<<32|key)>>11) + 0.5) / (1 << 53)
+	return -n.weight / math.Log(u)
+}
+
+// The splitmix64 finalizer, spreading every input bit over the output.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,76 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRendezvousWeightedShares(t *testing.T) {
+	if testing.Short() {
+		t.Skip("counts 1M keys")
+	}
+	r := NewRendezvous(nil)
+	weights := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4}
+	for node, w := range weights {
+		if err := r.AddWithWeight(node, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const n = 1000000
+	before := make([]string, n)
+	counts := make(map[string]int)
+	for i := range before {
+		before[i] = r.Get(fmt.Sprint(i))
+		counts[before[i]]++
+	}
+	for node, w := range weights {
+		want := float64(w) / 10
+		if got := float64(counts[node]) / n; math.Abs(got-want)/want > 0.03 {
+			t.Errorf("%s with weight %d has %.4f of the keys, want %.4f", node, w, got, want)
+		}
+	}
+
+	// Raising one weight only moves keys to that item, lowering it only away
+	r.AddWithWeight("b", 3)
+	raised := make([]string, n)
+	for i := range raised {
+		raised[i] = r.Get(fmt.Sprint(i))
+		if raised[i] != before[i] && raised[i] != "b" {
+			t.Fatalf("key %d moved from %s to %s", i, before[i], raised[i])
+		}
+	}
+	r.AddWithWeight("b", 1)
+	for i := range raised {
+		if got := r.Get(fmt.Sprint(i)); got != raised[i] && raised[i] != "b" {
+			t.Fatalf("key %d moved from %s to %s", i, raised[i], got)
+		}
+	}
+}
+
+func TestRendezvousGetN(t *testing.T) {
+	r := NewRendezvous(nil)
+	if err := r.Add("a"); err != nil {
+		t.Fatal(err)
+	}
+	r.AddWithWeight("b", 5)
+	r.AddWithWeight("off", 0)
+	if err := r.AddWithWeight("c", -1); err != ErrInvalidWeight {
+		t.Fatal(err)
+	}
+
+	second := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		k := fmt.Sprint(i)
+		got := r.GetN(k, 10)
+		if len(got) != 2 || got[0] != r.Get(k) {
+			t.Fatal(k, got)
+		}
+		second[got[1]]++
+	}
+	// The heavier item ranks first more often, so the lighter one is second
+	if second["a"] <= second["b"] {
+		t.Fatal(second)
+	}
+}
@@ -0,0 +1,51 @@
+package consistent
+
+import (
+	"fmt"
+)
+
+// Change the number of points of keys added without a weight, and of those
+// added later. Each such key gains or loses only the replicas between the
+// old and new counts, so only their arcs move, and the ring ends up as one
+// built with n replicas from the start. Keys given a weight by
+// AddWithWeight or SetWeight keep it, and keys still ramping up after
+// AddWithSlowStart ramp towards n from their next step. Watchers receive a
+// single EventWeight listing the keys that changed.
+func (m *Consistent) SetReplicas(n int) error {
+	if n < 1 {
+		return fmt.Errorf("consistent: replicas must be positive, got %d", n)
+	}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if n == m.replicas {
+			return nil
+		}
+		m.replicas = n
+
+		r := m.ring.clone()
+		var changed []string
+		for _, key := range sortedKeys(r.nodes) {
+			if mem := r.nodes[key]; mem.explicit || mem.ramp != nil || !r.setWeight(key, n) {
+				continue
+			}
+			changed = append(changed, key)
+			m.history.record(Change{Type: ChangeWeight, Key: key, Weight: n})
+		}
+		r.sortKeys()
+		if len(changed) == 0 {
+			return nil
+		}
+		m.ring = r
+		return &Event{Type: EventWeight, Changed: changed}
+	})
+
+	return err
+}
+
+// Returns the number of points of keys added without a weight.
+func (m *Consistent) Replicas() int {
+	m.RLock()
+	defer m.RUnlock()
+	return m.replicas
+}
@@ -0,0 +1,122 @@
+package consistent
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// A hash with items of the default weight and every kind that keeps its own.
+func replicasRing(n int, opts ...Option) *Consistent {
+	m := New(nil, append([]Option{WithReplicas(n)}, opts...)...)
+	for i := 0; i < 8; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	m.AddWithWeight("fixed", 7)
+	m.AddStandby("s")
+	m.AddStrict("strict")
+	m.Update(func(tx *Tx) error {
+		tx.Add("tx")
+		return tx.AddWithWeight("txfixed", 3)
+	})
+	return m
+}
+
+func TestSetReplicasMatchesFresh(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		var opts []Option
+		if tree {
+			opts = append(opts, WithChurnOptimizedIndex())
+		}
+		m := replicasRing(10, opts...)
+		m.SetHealthy("n1", false)
+		m.Pin("p", "n2")
+		gen := m.Generation()
+		var events []Event
+		m.Watch(func(e Event) { events = append(events, e) })
+
+		for _, n := range []int{40, 11, 3} {
+			if err := m.SetReplicas(n); err != nil {
+				t.Fatal(err)
+			}
+			fresh := replicasRing(n)
+			fresh.SetHealthy("n1", false)
+			fresh.Pin("p", "n2")
+			if m.Fingerprint() != fresh.Fingerprint() {
+				t.Fatalf("tree %v: the ring with %d replicas differs from a new one", tree, n)
+			}
+			if m.Replicas() != n {
+				t.Fatalf("tree %v: %d replicas, want %d", tree, m.Replicas(), n)
+			}
+			for node, want := range map[string]int{"fixed": 7, "txfixed": 3, "n0": n, "tx": n} {
+				if w, _ := m.Weight(node); w != want {
+					t.Fatalf("tree %v: %s has weight %d, want %d", tree, node, w, want)
+				}
+			}
+			if err := m.Validate(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if m.IsHealthy("n1") || m.Get("p") != "n2" || !m.IsStandby("s") {
+			t.Fatalf("tree %v: the health, pin or role of an item was lost", tree)
+		}
+		if len(events) != 3 || events[0].Type != EventWeight || len(events[0].Changed) != 11 {
+			t.Fatalf("tree %v: events %v, want 3 changing 11 items each", tree, events)
+		}
+		if m.Generation() != gen+3 {
+			t.Fatalf("tree %v: generation %d, want %d", tree, m.Generation(), gen+3)
+		}
+		if err := m.SetReplicas(3); err != nil || len(events) != 3 {
+			t.Fatalf("tree %v: setting the same count: %v, %d events", tree, err, len(events))
+		}
+	}
+	if err := New(nil).SetReplicas(0); err == nil {
+		t.Fatal("set no replicas")
+	}
+}
+
+// Going from 10 to 11 replicas moves about 1/11 of the keys of the items
+// with the default weight, not all of them.
+func TestSetReplicasMovesLittle(t *testing.T) {
+	m := replicasRing(10)
+	keys := testKeys(5000)
+	before := make(map[string]string, len(keys))
+	for _, key := range keys {
+		before[key] = m.Get(key)
+	}
+	m.SetReplicas(11)
+	moved := 0
+	for _, key := range keys {
+		if m.Get(key) != before[key] {
+			moved++
+		}
+	}
+	if moved == 0 || moved > len(keys)/5 {
+		t.Fatalf("%d of %d keys moved", moved, len(keys))
+	}
+}
+
+func TestSetReplicasKeepsSavedWeights(t *testing.T) {
+	m := replicasRing(10)
+	fromConfig, err := NewFromConfig(m.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ring.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path, WithReplicas(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, c := range map[string]*Consistent{"config": fromConfig, "loaded": loaded} {
+		c.SetReplicas(20)
+		if w, _ := c.Weight("fixed"); w != 7 {
+			t.Errorf("%s: fixed has weight %d, want 7", name, w)
+		}
+		if w, _ := c.Weight("n1"); w != 20 {
+			t.Errorf("%s: n1 has weight %d, want 20", name, w)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// The membership state of a hash. It is guarded by the lock of the Consistent
+// that owns it, and copied wholesale by transactions.
+//
+// Points are kept in an index sorted by position and name their item by id,
+// so a point costs a few words however long the names are.
+type ring struct {
+	index     pointIndex
+	tree      bool          // Whether index is a treeIndex, see WithChurnOptimizedIndex
+	staged    map[int]point // Holds the points instead while a copy is changed, see clone
+	names     []string      // Item names by id, empty for a free id
+	free      []int32       // Ids to reuse
+	nodes     map[string]*member
+	position  func(key string, replica int) int
+	pins      map[string]string // Lookup key to item, see Pin
+	rangePins []rangePin        // Sorted by From, see PinRange
+	halfOpen  int               // Members taking a trickle of keys, see WithEjection
+	splitArcs bool              // New points split the largest arcs, see WithArcSplittingPlacement
+	tiers     map[int]int       // Members per tier other than 0, see AddTiered
+}
+
+// The item holding a position on the ring and which of its replicas placed
+// it.
+type point struct {
+	node    int32 // Index into names
+	replica int32
+}
+
+// A point waiting to be inserted.
+type entry struct {
+	pos int
+	point
+}
+
+type member struct {
+	id        int32
+	weight    int
+	positions []int // Sorted, the reverse index of points
+	standby   bool  // Never returned as the primary for a key
+	unhealthy bool  // Skipped by lookups like a standby
+	readOnly  bool  // Skipped by write lookups only, see SetReadOnly
+	load      int64 // Updated atomically under the read lock
+	capacity  int64 // Zero is unlimited
+	hits      *decayed
+	latency   *ewma // See ReportLatency
+	zone      string
+	explicit  bool       // Weight given explicitly rather than the replicas, kept by SetReplicas
+	halfOpen  bool       // Unhealthy, but Get routes a trickle of keys to it
+	ramp      *slowStart // Set while AddWithSlowStart raises the weight
+	tier      int        // Get prefers lower tiers, see AddTiered
+}
+
+func newMember() *member {
+	return &member{hits: &decayed{}, latency: &ewma{}}
+}
+
+// Returns true if the item may be returned as the primary for a key.
+func (mem *member) eligible() bool {
+	return !mem.standby && !mem.unhealthy
+}
+
+func newRing(position func(key string, replica int) int, tree bool) *ring {
+	return &ring{
+		index:    newIndex(tree, nil),
+		tree:     tree,
+		nodes:    make(map[string]*member),
+		position: position,
+	}
+}
+
+// Copy the ring for staging changes. The copy keeps its points in a map
+// until sortKeys is called, so a batch of changes sorts them once.
+func (r *ring) clone() *ring {
+	c := &ring{
+		tree:      r.tree,
+		staged:    make(map[int]point, r.size()+len(r.staged)),
+		names:     append([]string(nil), r.names...),
+		free:      append([]int32(nil), r.free...),
+		nodes:     make(map[string]*member, len(r.nodes)),
+		position:  r.position,
+		halfOpen:  r.halfOpen,
+		splitArcs: r.splitArcs,
+	}
+	for pos, p := range r.staged {
+		c.staged[pos] = p
+	}
+	for i := 0; i < r.size(); i++ {
+		pos, p := r.index.at(i)
+		c.staged[pos] = p
+	}
+	for key, mem := range r.nodes {
+		cp := *mem
+		cp.positions = append([]int(nil), mem.positions...)
+		c.nodes[key] = &cp
+	}
+	if len(r.pins) > 0 {
+		c.pins = make(map[string]string, len(r.pins))
+		for key, node := range r.pins {
+			c.pins[key] = node
+		}
+	}
+	c.rangePins = append([]rangePin(nil), r.rangePins...)
+	if len(r.tiers) > 0 {
+		c.tiers = make(map[int]int, len(r.tiers))
+		for tier, n := range r.tiers {
+			c.tiers[tier] = n
+		}
+	}
+	return c
+}
+
+// Returns the number of points on the ring.
+func (r *ring) size() int {
+	if r.index == nil {
+		return 0
+	}
+	return r.index.len()
+}
+
+// Get the position of the point at index i.
+func (r *ring) key(i int) int {
+	pos, _ := r.index.at(i)
+	return pos
+}
+
+// Get the item of the point at index i.
+func (r *ring) nodeAt(i int) string {
+	_, p := r.index.at(i)
+	return r.names[p.node]
+}
+
+// Get the point at a position.
+func (r *ring) pointAt(pos int) (point, bool) {
+	if r.staged != nil {
+		p, ok := r.staged[pos]
+		return p, ok
+	}
+	if i := r.index.search(pos); i < r.size() {
+		if at, p := r.index.at(i); at == pos {
+			return p, true
+		}
+	}
+	return point{}, false
+}
+
+// Replace the point at a position already on the ring.
+func (r *ring) setPoint(pos int, p point) {
+	if r.staged != nil {
+		r.staged[pos] = p
+		return
+	}
+	r.index.set(r.index.search(pos), p)
+}
+
+// Add a key with the given number of points. Returns false if it is
+// already present.
+func (r *ring) add(key string, weight int) bool {
+	if _, ok := r.nodes[key]; ok {
+		return false
+	}
+
+	mem := newMember()
+	if n := len(r.free); n > 0 {
+		mem.id, r.free = r.free[n-1], r.free[:n-1]
+		r.names[mem.id] = key
+	} else {
+		mem.id = int32(len(r.names))
+		r.names = append(r.names, key)
+	}
+	r.nodes[key] = mem
+	r.setWeight(key, weight)
+	return true
+}
+
+// Remove a key and all of its points. Returns false if it is not present.
+func (r *ring) remove(key string) bool {
+	mem, ok := r.nodes[key]
+	if !ok {
+		return false
+	}
+
+	r.deletePoints(mem.positions)
+	if mem.halfOpen {
+		r.halfOpen--
+	}
+	r.setTier(mem, 0)
+	delete(r.nodes, key)
+	r.names[mem.id] = ""
+	r.free = append(r.free, mem.id)
+	return true
+}
+
+// Change the number of points of a key, placing or removing only the
+// replicas above the smaller of the two weights. Returns false if nothing
+// changed.
+func (r *ring) setWeight(key string, weight int) bool {
+	mem := r.nodes[key]
+	if mem.weight == weight {
+		return false
+	}
+
+	var added []entry
+	var placed map[int]bool
+	if weight-mem.weight > 1 {
+		placed = make(map[int]bool, weight-mem.weight)
+	}
+	var split []int
+	if r.splitArcs && weight > mem.weight {
+		split = r.splitPositions(key, weight-mem.weight)
+	}
+	for replica := mem.weight; replica < weight; replica++ {
+		pos := r.position(key, replica)
+		if split != nil {
+			pos = split[replica-mem.weight]
+		}
+		if e, ok := r.place(key, replica, pos, placed); ok {
+			added = append(added, e)
+			if placed != nil {
+				placed[e.pos] = true
+			}
+		}
+	}
+	r.insertPoints(added)
+	if weight > mem.weight {
+		sort.Ints(mem.positions)
+	}
+
+	if weight < mem.weight {
+		var removed []int
+		kept := mem.positions[:0]
+		for _, pos := range mem.positions {
+			if p, _ := r.pointAt(pos); int(p.replica) < weight {
+				kept = append(kept, pos)
+				continue
+			}
+			removed = append(removed, pos)
+		}
+		mem.positions = kept
+		r.deletePoints(removed)
+	}
+
+	mem.weight = weight
+	return true
+}
+
+// Relabel every point of a key.
+func (r *ring) rename(from, to string) {
+	mem := r.nodes[from]
+	r.names[mem.id] = to
+	delete(r.nodes, from)
+	r.nodes[to] = mem
+
+	for key, node := range r.pins {
+		if node == from {
+			r.pins[key] = to
+		}
+	}
+	for i, pin := range r.rangePins {
+		if pin.node == from {
+			r.rangePins[i].node = to
+		}
+	}
+}
+
+// Place a replica of a key at pos, taking the position from any other key.
+// Returns the point and true if the position is new to the ring and must be
+// inserted; placed holds the positions of the batch not yet inserted.
+func (r *ring) place(key string, replica, pos int, placed map[int]bool) (entry, bool) {
+	mem := r.nodes[key]
+	e := entry{pos: pos, point: point{node: mem.id, replica: int32(replica)}}
+
+	if placed[pos] {
+		// Two replicas of the same key collided, keep the first
+		return e, false
+	}
+	prev, taken := r.pointAt(pos)
+	if taken && prev.node == mem.id {
+		return e, false
+	}
+	mem.positions = append(mem.positions, pos)
+	if !taken {
+		return e, true
+	}
+
+	// The position now belongs to this key
+	owner := r.nodes[r.names[prev.node]]
+	if i := sort.SearchInts(owner.positions, pos); i < len(owner.positions) && owner.positions[i] == pos {
+		owner.positions = append(owner.positions[:i], owner.positions[i+1:]...)
+	}
+	r.setPoint(pos, e.point)
+	return e, false
+}
+
+// Get the position of a key's first replica.
+func (r *ring) origin(key string) (int, bool) {
+	mem, ok := r.nodes[key]
+	if !ok {
+		return 0, false
+	}
+	for _, pos := range mem.positions {
+		if p, _ := r.pointAt(pos); p.replica == 0 {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+// Insert points at positions not yet on the ring.
+func (r *ring) insertPoints(batch []entry) {
+	if len(batch) == 0 {
+		return
+	}
+	if r.staged != nil {
+		for _, e := range batch {
+			r.staged[e.pos] = e.point
+		}
+		return
+	}
+	r.index.insert(batch)
+}
+
+// Delete the points at the given positions.
+func (r *ring) deletePoints(batch []int) {
+	if len(batch) == 0 {
+		return
+	}
+	if r.staged != nil {
+		for _, pos := range batch {
+			delete(r.staged, pos)
+		}
+		return
+	}
+	r.index.delete(batch)
+}
+
+// Move staged points back into the index.
+func (r *ring) sortKeys() {
+	if r.staged == nil {
+		return
+	}
+	sorted := make([]entry, 0, len(r.staged))
+	for pos, p := range r.staged {
+		sorted = append(sorted, entry{pos: pos, point: p})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].pos < sorted[j].pos })
+	r.index = newIndex(r.tree, sorted)
+	r.staged = nil
+}
+
+func (r *ring) prev(hash int) int {
+	return r.key(r.prevIndex(hash))
+}
+
+// Index of the position owning hash: the last position at or before it,
+// wrapping around to the last position on the ring.
+func (r *ring) prevIndex(hash int) int {
+	i := r.index.search(hash+1) - 1
+
+	if i < 0 {
+		i = r.size() - 1
+	}
+
+	return i
+}
+
+// The range owned by the point at index i, up to the next point. It wraps
+// past the top of the hash space when From > To.
+func (r *ring) arc(i int) HashRange {
+	from := r.key(i)
+	to := r.key((i+1)%r.size()) - 1
+	if to < 0 {
+		to = MaxPosition
+	}
+	return HashRange{From: from, To: to}
+}
+
+func (r *ring) next(hash int) int {
+	return r.key(r.nextIndex(hash))
+}
+
+// Index of the first position after hash, wrapping around to the first
+// position on the ring.
+func (r *ring) nextIndex(hash int) int {
+	i := r.index.search(hash + 1)
+
+	if i == r.size() {
+		i = 0
+	}
+
+	return i
+}
+
+// Visit the points clockwise from index start for one revolution, until fn
+// returns false.
+func (r *ring) walk(start int, fn func(i int) bool) {
+	for n := 0; n < r.size(); n++ {
+		if !fn((start + n) % r.size()) {
+			return
+		}
+	}
+}
+
+// Visit the points counter-clockwise from index start for one revolution,
+// until fn returns false.
+func (r *ring) walkBack(start int, fn func(i int) bool) {
+	for n := 0; n < r.size(); n++ {
+		if !fn((start - n + r.size()) % r.size()) {
+			return
+		}
+	}
+}
+
+// Index of the point serving hash, or -1 if no eligible item has points.
+func (r *ring) lookup(hash int) int {
+	return r.serving(r.prevIndex(hash))
+}
+
+// Index of the point serving keys in the arc of the point at index i. Keys
+// go where they would if ineligible items were absent: the first point at
+// or counter-clockwise of i belonging to an eligible item, or -1.
+func (r *ring) serving(i int) int {
+	serving := -1
+	r.walkBack(i, func(j int) bool {
+		if !r.nodes[r.nodeAt(j)].eligible() {
+			return true
+		}
+		serving = j
+		return false
+	})
+	return serving
+}
+
+// Visit the distinct items in NextN order for hash, until fn returns false:
+// the point serving hash, then the first point of each other item clockwise.
+func (r *ring) candidates(hash int, fn func(i int) bool) {
+	start := r.prevIndex(hash)
+	r.candidatesFrom(start, r.serving(start), fn)
+}
+
+// Like candidates, from the point at index start with the point serving it
+// already chosen, or -1.
+func (r *ring) candidatesFrom(start, primary int, fn func(i int) bool) {
+	if primary >= 0 && !fn(primary) {
+		return
+	}
+
+	seen := make(map[string]bool)
+	if primary >= 0 {
+		seen[r.nodeAt(primary)] = true
+	}
+	r.walk(start, func(i int) bool {
+		key := r.nodeAt(i)
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+		return fn(i)
+	})
+}
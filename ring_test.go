@@ -0,0 +1,134 @@
+package consistent
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// A hash placing the named items at fixed positions and hashing any other
+// key as the number it spells.
+func fixedHash(positions map[string]uint32) Hash {
+	return func(b []byte) uint32 {
+		if pos, ok := positions[string(b)]; ok {
+			return pos
+		}
+		var h uint32
+		fmt.Sscan(string(b), &h)
+		return h
+	}
+}
+
+func contains(r HashRange, h int) bool {
+	if r.From <= r.To {
+		return r.From <= h && h <= r.To
+	}
+	return h >= r.From || h <= r.To
+}
+
+// A key hashing exactly onto a position belongs to the item there, and keys
+// below the lowest position wrap to the highest one.
+func TestOwnershipBoundaries(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		points map[string]uint32
+		want   map[int]string // Hash to owner
+		ranges map[string][]HashRange
+	}{{
+		name:   "points at both ends",
+		points: map[string]uint32{"z": 0, "a": 100, "b": 200, "m": MaxPosition},
+		want: map[int]string{
+			0: "z", 1: "z", 99: "z", 100: "a", 101: "a", 199: "a", 200: "b", 201: "b",
+			MaxPosition - 1: "b", MaxPosition: "m",
+		},
+		ranges: map[string][]HashRange{
+			"z": {{0, 99}}, "a": {{100, 199}}, "b": {{200, MaxPosition - 1}}, "m": {{MaxPosition, MaxPosition}},
+		},
+	}, {
+		name:   "wrapping to the top",
+		points: map[string]uint32{"a": 100, "m": MaxPosition},
+		want: map[int]string{
+			0: "m", 99: "m", 100: "a", 101: "a", MaxPosition - 1: "a", MaxPosition: "m",
+		},
+		ranges: map[string][]HashRange{
+			"a": {{100, MaxPosition - 1}}, "m": {{MaxPosition, 99}},
+		},
+	}, {
+		name:   "a single point at zero",
+		points: map[string]uint32{"z": 0},
+		want:   map[int]string{0: "z", 1: "z", MaxPosition: "z"},
+		ranges: map[string][]HashRange{"z": {{0, MaxPosition}}},
+	}} {
+		c := New(fixedHash(tc.points))
+		for node := range tc.points {
+			c.Add(node)
+		}
+		for h, want := range tc.want {
+			key := fmt.Sprint(h)
+			if got := c.Get(key); got != want {
+				t.Errorf("%s: Get(%d) = %q, want %q", tc.name, h, got, want)
+			}
+			if got := c.NextN(key, 1); len(got) != 1 || got[0] != want {
+				t.Errorf("%s: NextN(%d) = %v, want %q", tc.name, h, got, want)
+			}
+			if from, to := c.Range(want); !contains(HashRange{from, to}, h) {
+				t.Errorf("%s: Range(%q) = %d-%d leaves out %d", tc.name, want, from, to, h)
+			}
+			in := false
+			for _, r := range c.Ranges(want) {
+				in = in || contains(r, h)
+			}
+			if !in {
+				t.Errorf("%s: Ranges(%q) = %v leaves out %d", tc.name, want, c.Ranges(want), h)
+			}
+		}
+		for node, want := range tc.ranges {
+			if got := c.Ranges(node); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s: Ranges(%q) = %v, want %v", tc.name, node, got, want)
+			}
+		}
+	}
+}
+
+// Churn one item on a ring of 150k points, as an item flaps.
+func BenchmarkAdd(b *testing.B) {
+	for _, weight := range []int{1, 200} {
+		b.Run(fmt.Sprint("replicas=", weight), func(b *testing.B) {
+			m := New(nil)
+			m.Update(func(tx *Tx) error {
+				for i := 0; i < 750; i++ {
+					tx.AddWithWeight(fmt.Sprint("n", i), 200)
+				}
+				return nil
+			})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.AddWithWeight("flap", weight)
+				m.Remove("flap")
+			}
+		})
+	}
+}
+
+// The heap a ring of 500 items with 500 points each holds, per point.
+func BenchmarkRingMemory(b *testing.B) {
+	names := make([]string, 500)
+	for i := range names {
+		names[i] = fmt.Sprintf("node-%03d.example.internal:8080", i)
+	}
+	var before, after runtime.MemStats
+	var perPoint float64
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		m := New(nil, WithReplicas(500))
+		for _, name := range names {
+			m.Add(name)
+		}
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		perPoint = float64(after.HeapAlloc-before.HeapAlloc) / float64(len(m.Positions()))
+		runtime.KeepAlive(m)
+	}
+	b.ReportMetric(perPoint, "bytes/point")
+}
@@ -0,0 +1,40 @@
+package consistent
+
+import (
+	"math"
+)
+
+// Returns true if a key is in the first percent of keys, for rolling a
+// feature out gradually. A key's place is fixed by its hash, so raising the
+// percentage only ever adds keys: a key enabled at 7% is enabled at 12%.
+// A percentage that is NaN or not above 0 enables no keys, and one of 100 or
+// more enables every key. Keys are hashed like Get, so the population
+// changes with the hash function and key transform.
+func (m *Consistent) Rollout(key string, percent float64) bool {
+	return m.RolloutFor("", key, percent)
+}
+
+// Returns true if a key is in the first percent of keys for a feature, as
+// Rollout does. Each feature orders the keys independently, so features
+// rolled out to the same percentage reach different keys.
+func (m *Consistent) RolloutFor(feature, key string, percent float64) bool {
+	switch {
+	case math.IsNaN(percent) || percent <= 0:
+		return false
+	case percent >= 100:
+		return true
+	}
+	h := mix32(uint32(m.Hash(key)) ^ m.sum([]byte(feature)))
+	return float64(h) < percent/100*(1<<32)
+}
+
+// The murmur3 finalizer, spreading hashes that differ in few bits, such as
+// those of keys under two features.
+func mix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
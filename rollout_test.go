@@ -0,0 +1,78 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// The enabled fraction is within 4 standard deviations of the percentage,
+// for the binomial distribution of n independent keys.
+func TestRolloutFraction(t *testing.T) {
+	m := New(nil)
+	const n = 50000
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprint("tenant-", i)
+	}
+	for _, feature := range []string{"", "search", "billing"} {
+		for _, percent := range []float64{0.5, 1, 7, 12, 33.3, 50, 90, 99} {
+			on := 0
+			for _, key := range keys {
+				if m.RolloutFor(feature, key, percent) {
+					on++
+				}
+			}
+			p := percent / 100
+			if sd := math.Sqrt(p * (1 - p) / n); math.Abs(float64(on)/n-p) > 4*sd {
+				t.Errorf("%q at %v%%: %d of %d keys enabled", feature, percent, on, n)
+			}
+		}
+	}
+}
+
+func TestRolloutMonotonic(t *testing.T) {
+	m := New(nil)
+	steps := []float64{math.Inf(-1), -5, 0, 0.1, 1, 7, 12, 12.5, 50, 99.9, 100, 250, math.Inf(1)}
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprint("tenant-", i)
+		enabled := false
+		for _, percent := range steps {
+			on := m.Rollout(key, percent)
+			if enabled && !on {
+				t.Fatalf("%s enabled below %v%% and disabled at it", key, percent)
+			}
+			enabled = on
+			if percent <= 0 && on || percent >= 100 && !on {
+				t.Fatalf("%s: Rollout at %v%% returned %v", key, percent, on)
+			}
+		}
+		if m.Rollout(key, math.NaN()) {
+			t.Fatalf("%s enabled at NaN", key)
+		}
+		if m.Rollout(key, 30) != m.RolloutFor("", key, 30) {
+			t.Fatalf("%s: Rollout differs from the unnamed feature", key)
+		}
+	}
+}
+
+// Two features at 20% share about 20% of each other's keys, as independent
+// populations would.
+func TestRolloutFeaturesIndependent(t *testing.T) {
+	m := New(nil)
+	const n = 50000
+	a, both := 0, 0
+	for i := 0; i < n; i++ {
+		key := fmt.Sprint("tenant-", i)
+		if m.RolloutFor("a", key, 20) {
+			a++
+			if m.RolloutFor("b", key, 20) {
+				both++
+			}
+		}
+	}
+	p := float64(both) / float64(a)
+	if sd := math.Sqrt(0.2 * 0.8 / float64(a)); math.Abs(p-0.2) > 4*sd {
+		t.Fatalf("%.3f of the keys of one feature have the other, want 0.2", p)
+	}
+}
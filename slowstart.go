@@ -0,0 +1,87 @@
+package consistent
+
+import (
+	"errors"
+	"time"
+)
+
+// Identifies the ramp of a key added by AddWithSlowStart, so a key removed
+// and added again, or given a weight, is left alone.
+type slowStart struct{ _ byte }
+
+// Add a key to the hash with 1/steps of the replicas, then raise its weight
+// by another 1/steps every over/(steps-1) through SetWeight, so only the
+// keys of the new points move at each step, until it has the replicas after
+// over. The ring then ends up as if the key had been added by Add. A steps
+// below 2 adds the key at full weight. Returns ErrNodeExists if the key is
+// present.
+//
+// The ramp stops if the key is removed, or given a weight by SetWeight or
+// AddWithWeight, and waits while the hash is frozen. Steps are timed by the
+// clock like DrainOver.
+func (m *Consistent) AddWithSlowStart(key string, over time.Duration, steps int) error {
+	if steps < 2 {
+		steps = 1
+	}
+	ramp := &slowStart{}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[key]; ok {
+			err = ErrNodeExists
+			return nil
+		}
+		weight := rampWeight(m.replicas, 1, steps)
+		m.ring.add(key, weight)
+		if steps > 1 {
+			m.ring.nodes[key].ramp = ramp
+		}
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: weight})
+		return &Event{Type: EventAdd, Added: []string{key}}
+	})
+	if err != nil || steps == 1 {
+		return err
+	}
+
+	go func() {
+		for i := 2; i <= steps; {
+			<-m.after(over / time.Duration(steps-1))
+			switch err := m.rampStep(key, ramp, i, steps); {
+			case errors.Is(err, ErrFrozen):
+				continue
+			case err != nil:
+				return
+			}
+			i++
+		}
+	}()
+	return nil
+}
+
+// Raise a ramping key to step i of steps. Returns ErrNodeNotFound once the
+// ramp no longer applies to the key.
+func (m *Consistent) rampStep(key string, ramp *slowStart, i, steps int) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok || mem.ramp != ramp {
+			err = ErrNodeNotFound
+			return nil
+		}
+		weight := rampWeight(m.replicas, i, steps)
+		if i == steps {
+			mem.ramp = nil
+		}
+		if !m.ring.setWeight(key, weight) {
+			return nil
+		}
+		m.history.record(Change{Type: ChangeWeight, Key: key, Weight: weight})
+		return &Event{Type: EventWeight, Changed: []string{key}}
+	})
+	return err
+}
+
+// The weight at step i of steps towards target, at least 1.
+func rampWeight(target, i, steps int) int {
+	return max(1, target*i/steps)
+}
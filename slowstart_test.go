@@ -0,0 +1,127 @@
+package consistent
+
+import (
+	"testing"
+	"time"
+)
+
+// Collect the weights an item is given, one per event.
+func watchWeights(m *Consistent, node string) <-chan int {
+	ch := make(chan int, 16)
+	m.Watch(func(e Event) {
+		if e.Type == EventWeight && len(e.Changed) == 1 && e.Changed[0] == node {
+			w, _ := m.Weight(node)
+			ch <- w
+		}
+	})
+	return ch
+}
+
+func nextWeight(t *testing.T, ch <-chan int) int {
+	t.Helper()
+	select {
+	case w := <-ch:
+		return w
+	case <-time.After(5 * time.Second):
+		t.Fatal("no weight change")
+		return 0
+	}
+}
+
+// The item ramps up a quarter of the replicas at a time and ends up as if
+// added at full weight.
+func TestSlowStart(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock), WithReplicas(40))
+	want := New(nil, WithReplicas(40))
+	for _, node := range []string{"a", "b", "c"} {
+		want.Add(node)
+	}
+	m.Add("a")
+	m.Add("b")
+	weights := watchWeights(m, "c")
+
+	if err := m.AddWithSlowStart("c", 3*time.Minute, 4); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := m.Weight("c"); w != 10 {
+		t.Fatalf("starting weight %d, want 10", w)
+	}
+	for _, w := range []int{20, 30, 40} {
+		if d := clock.step(t); d != time.Minute {
+			t.Fatalf("waited %v between steps, want a minute", d)
+		}
+		if got := nextWeight(t, weights); got != w {
+			t.Fatalf("weight %d, want %d", got, w)
+		}
+	}
+	if m.Fingerprint() != want.Fingerprint() {
+		t.Fatal("the ramped ring differs from one with the item added at once")
+	}
+	if err := m.AddWithSlowStart("c", time.Minute, 2); err != ErrNodeExists {
+		t.Fatalf("present item: %v", err)
+	}
+
+	if err := m.AddWithSlowStart("d", time.Minute, 1); err != nil {
+		t.Fatal(err)
+	}
+	if w, _ := m.Weight("d"); w != 40 {
+		t.Fatalf("a single step added weight %d, want 40", w)
+	}
+}
+
+// Removing the item or giving it a weight ends its ramp.
+func TestSlowStartStops(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock), WithReplicas(40))
+	m.Add("a")
+
+	m.AddWithSlowStart("d", time.Minute, 4)
+	d := <-clock.waits
+	m.Remove("d")
+	m.Add("d")
+	clock.advance(d)
+
+	m.AddWithSlowStart("e", time.Minute, 4)
+	waits := []time.Duration{<-clock.waits}
+	m.SetWeight("e", 5)
+	clock.advance(waits[0])
+
+	// Neither ramp waits again
+	select {
+	case d := <-clock.waits:
+		t.Fatalf("a stopped ramp waited %v", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if w, _ := m.Weight("d"); w != 40 {
+		t.Fatalf("d re-added with weight %d, want 40", w)
+	}
+	if w, _ := m.Weight("e"); w != 5 {
+		t.Fatalf("e has weight %d, want the 5 it was given", w)
+	}
+}
+
+// A frozen hash holds the ramp at its step until it is thawed.
+func TestSlowStartFrozen(t *testing.T) {
+	clock := newTimerClock()
+	m := New(nil, WithClock(clock), WithReplicas(40))
+	weights := watchWeights(m, "c")
+	m.AddWithSlowStart("c", 2*time.Minute, 3)
+
+	token := m.Freeze()
+	clock.step(t) // Refused, the step is tried again
+	clock.step(t)
+	if w, _ := m.Weight("c"); w != 13 {
+		t.Fatalf("frozen weight %d, want 13", w)
+	}
+	d := <-clock.waits
+	m.Thaw(token)
+	clock.advance(d)
+	if got := nextWeight(t, weights); got != 26 {
+		t.Fatalf("weight %d after thawing, want 26", got)
+	}
+	clock.step(t)
+	if got := nextWeight(t, weights); got != 40 {
+		t.Fatalf("final weight %d, want 40", got)
+	}
+}
@@ -0,0 +1,176 @@
+// Package sqlshard routes database/sql queries to shards chosen by a
+// consistent hash.
+package sqlshard
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+var (
+	// Returned when no shard can serve a key. It wraps consistent.ErrEmpty.
+	ErrNoShards      = fmt.Errorf("sqlshard: no shards: %w", consistent.ErrEmpty)
+	ErrShardExists   = errors.New("sqlshard: shard already registered")
+	ErrShardNotFound = errors.New("sqlshard: shard not registered")
+)
+
+// ShardError is an error from a shard, naming it.
+type ShardError struct {
+	Shard string
+	Err   error
+}
+
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("sqlshard: shard %q: %v", e.Shard, e.Err)
+}
+
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// ShardRouter routes keys to the database pools of the shards in a hash.
+type ShardRouter struct {
+	mu       sync.RWMutex
+	ring     *consistent.Consistent
+	dbs      map[string]*sql.DB
+	observer func(shard, query string, err error)
+}
+
+type Option func(*ShardRouter)
+
+// Call fn after every query the router runs, with the shard that served it,
+// for logging.
+func WithObserver(fn func(shard, query string, err error)) Option {
+	return func(r *ShardRouter) {
+		r.observer = fn
+	}
+}
+
+// Create a router over ring, whose members are the shard names. A nil ring
+// uses consistent.New(nil). Shards must be registered with AddShard, not
+// added to the ring directly.
+func New(ring *consistent.Consistent, opts ...Option) *ShardRouter {
+	if ring == nil {
+		ring = consistent.New(nil)
+	}
+	r := &ShardRouter{ring: ring, dbs: make(map[string]*sql.DB)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register a shard and add it to the ring. Returns an error wrapping
+// consistent.ErrFrozen, leaving the shard unregistered, if the ring is
+// frozen.
+func (r *ShardRouter) AddShard(name string, db *sql.DB) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.dbs[name]; ok {
+		return fmt.Errorf("%w: %q", ErrShardExists, name)
+	}
+	if r.ring.Add(name) < 0 {
+		return fmt.Errorf("sqlshard: adding shard %q: %w", name, consistent.ErrFrozen)
+	}
+	r.dbs[name] = db
+	return nil
+}
+
+// Remove a shard from the ring and return its pool, leaving it open. If the
+// ring is frozen the shard stays registered and the error wraps
+// consistent.ErrFrozen.
+func (r *ShardRouter) RemoveShard(name string) (*sql.DB, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	db, ok := r.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrShardNotFound, name)
+	}
+	if err := r.ring.TryRemove(name); err != nil {
+		return nil, fmt.Errorf("sqlshard: removing shard %q: %w", name, err)
+	}
+	delete(r.dbs, name)
+	return db, nil
+}
+
+// Remove a shard from the ring, then close its pool once its connections
+// are no longer in use or the timeout has passed, whichever is first.
+// Returns the error of closing the pool.
+func (r *ShardRouter) DrainShard(name string, timeout time.Duration) error {
+	db, err := r.RemoveShard(name)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for db.Stats().InUse > 0 && time.Now().Before(deadline) {
+		time.Sleep(min(10*time.Millisecond, time.Until(deadline)))
+	}
+	if err := db.Close(); err != nil {
+		return &ShardError{Shard: name, Err: err}
+	}
+	return nil
+}
+
+// Get the name and pool of the shard a key belongs to. Returns ErrNoShards
+// if no shard can serve it.
+func (r *ShardRouter) Shard(key string) (string, *sql.DB, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name := r.ring.Get(key)
+	db, ok := r.dbs[name]
+	if !ok {
+		return "", nil, ErrNoShards
+	}
+	return name, db, nil
+}
+
+// Get the pool of the shard a key belongs to.
+func (r *ShardRouter) DB(key string) (*sql.DB, error) {
+	_, db, err := r.Shard(key)
+	return db, err
+}
+
+// Run a query on the shard a key belongs to. Errors from the shard are
+// returned as a *ShardError.
+func (r *ShardRouter) QueryContext(ctx context.Context, key, query string, args ...any) (*sql.Rows, error) {
+	name, db, err := r.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if r.observer != nil {
+		r.observer(name, query, err)
+	}
+	if err != nil {
+		return nil, &ShardError{Shard: name, Err: err}
+	}
+	return rows, nil
+}
+
+// Call fn for every shard in name order, as for a fan-out query, stopping at
+// the first error, which is returned as a *ShardError.
+func (r *ShardRouter) ForEachShard(fn func(name string, db *sql.DB) error) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.dbs))
+	dbs := make(map[string]*sql.DB, len(r.dbs))
+	for name, db := range r.dbs {
+		names = append(names, name)
+		dbs[name] = db
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := fn(name, dbs[name]); err != nil {
+			return &ShardError{Shard: name, Err: err}
+		}
+	}
+	return nil
+}
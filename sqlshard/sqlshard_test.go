@@ -0,0 +1,142 @@
+package sqlshard
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	consistent "github.com/tonglil/consistent-hash"
+)
+
+// A driver whose connections answer every query with the name they were
+// opened with, and fail every query if that name is "bad".
+type fakeDriver struct{}
+type fakeConn struct{ name string }
+type fakeStmt struct{ name string }
+type fakeRows struct {
+	name string
+	done bool
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error)         { return fakeConn{name}, nil }
+func (c fakeConn) Prepare(q string) (driver.Stmt, error)         { return fakeStmt{c.name}, nil }
+func (fakeConn) Close() error                                    { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                       { return nil, errors.New("no") }
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, errors.New("no") }
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.name == "bad" {
+		return nil, errors.New("broken")
+	}
+	return &fakeRows{name: s.name}, nil
+}
+func (*fakeRows) Columns() []string { return []string{"shard"} }
+func (*fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.name
+	return nil
+}
+
+func init() { sql.Register("fakeshard", fakeDriver{}) }
+
+func TestShardRouter(t *testing.T) {
+	var served []string
+	r := New(nil, WithObserver(func(shard, q string, err error) { served = append(served, shard) }))
+	if _, err := r.DB("k"); !errors.Is(err, ErrNoShards) {
+		t.Fatal(err)
+	}
+	for _, n := range []string{"s1", "s2", "bad"} {
+		db, _ := sql.Open("fakeshard", n)
+		if err := r.AddShard(n, db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		key := string(rune('a' + i))
+		name, _, _ := r.Shard(key)
+		rows, err := r.QueryContext(context.Background(), key, "select")
+		if name == "bad" {
+			var se *ShardError
+			if !errors.As(err, &se) || se.Shard != "bad" {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows.Next()
+		var got string
+		rows.Scan(&got)
+		rows.Close()
+		if got != name {
+			t.Fatal(got, name)
+		}
+	}
+	if len(served) != 20 {
+		t.Fatal(served)
+	}
+	var names []string
+	r.ForEachShard(func(n string, db *sql.DB) error { names = append(names, n); return nil })
+	if len(names) != 3 || names[0] != "bad" {
+		t.Fatal(names)
+	}
+	if err := r.DrainShard("bad", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DrainShard("bad", time.Second); !errors.Is(err, ErrShardNotFound) {
+		t.Fatal(err)
+	}
+}
+
+// A frozen ring refuses the shard, which is then not registered either.
+func TestAddShardFrozen(t *testing.T) {
+	ring := consistent.New(nil)
+	r := New(ring)
+	db, err := sql.Open("fakeshard", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := r.AddShard("s1", db); err != nil {
+		t.Fatal(err)
+	}
+
+	thaw := ring.Freeze()
+	if err := r.AddShard("s2", db); !errors.Is(err, consistent.ErrFrozen) {
+		t.Fatalf("AddShard on a frozen ring: %v", err)
+	}
+	if _, err := r.RemoveShard("s1"); !errors.Is(err, consistent.ErrFrozen) {
+		t.Fatalf("RemoveShard on a frozen ring: %v", err)
+	}
+	var names []string
+	r.ForEachShard(func(name string, _ *sql.DB) error {
+		names = append(names, name)
+		return nil
+	})
+	if len(names) != 1 || names[0] != "s1" {
+		t.Fatalf("registered shards %q, want [s1]", names)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if name, _, err := r.Shard(key); err != nil || name != "s1" {
+			t.Fatalf("%s: shard %q, %v", key, name, err)
+		}
+	}
+
+	ring.Thaw(thaw)
+	if err := r.AddShard("s2", db); err != nil {
+		t.Fatal(err)
+	}
+	if got := ring.Members(); len(got) != 2 {
+		t.Fatalf("ring members %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package consistent
+
+import (
+	"math"
+	"sort"
+)
+
+// Stats describes how evenly the hash space is spread over the items.
+type Stats struct {
+	Members  int                `json:"members"`
+	Points   int                `json:"points"`
+	Pins     int                `json:"pins"`          // Keys pinned to an item, see Pin
+	Dangling int                `json:"dangling_pins"` // Pins whose item is gone or ineligible
+	Ranges   int                `json:"range_pins"`    // Hash ranges pinned to an item, see PinRange
+	Shares   map[string]float64 `json:"shares"`        // The fraction of the hash space Get maps to each item
+	ReadOnly int                `json:"read_only"`     // Items marked read-only, see SetReadOnly
+
+	WriteRestricted float64 `json:"write_restricted"` // The fraction of the hash space whose reads go to a read-only item
+
+	// Over the items Get can return
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Mean      float64 `json:"mean"`
+	StdDev    float64 `json:"stddev"`
+	Imbalance float64 `json:"imbalance"` // Max over Mean, 1 when perfectly even
+}
+
+// Get the share of the hash space of each item.
+func (m *Consistent) Stats() Stats {
+	m.RLock()
+	defer m.RUnlock()
+	return m.ring.stats(m.strictPins, m.atCapacity)
+}
+
+func (r *ring) stats(strictPins bool, full func(mem *member) bool) Stats {
+	s := Stats{
+		Members:  len(r.nodes),
+		Points:   r.size(),
+		Pins:     len(r.pins),
+		Dangling: r.danglingPins(),
+		Ranges:   len(r.rangePins),
+		Shares:   make(map[string]float64, len(r.nodes)),
+	}
+	for key, mem := range r.nodes {
+		if mem.eligible() {
+			s.Shares[key] = 0
+		}
+	}
+	for _, mem := range r.nodes {
+		if mem.readOnly {
+			s.ReadOnly++
+		}
+	}
+	for i := 0; i < r.size(); i++ {
+		if j := r.arcServer(i, full); j >= 0 {
+			node := r.nodeAt(j)
+			length := r.arcLength(i)
+			if len(r.rangePins) > 0 {
+				length -= r.pinnedLength(r.arc(i), strictPins)
+			}
+			share := float64(length) / (MaxPosition + 1)
+			s.Shares[node] += share
+			if r.nodes[node].readOnly {
+				s.WriteRestricted += share
+			}
+		}
+	}
+	for _, pin := range r.rangePins {
+		if node, ok := r.resolveRangePin(pin, strictPins); ok && node != "" {
+			share := float64(pin.overlap(HashRange{From: 0, To: MaxPosition})) / (MaxPosition + 1)
+			s.Shares[node] += share
+			if r.nodes[node].readOnly {
+				s.WriteRestricted += share
+			}
+		}
+	}
+	if len(s.Shares) == 0 {
+		return s
+	}
+
+	s.Min = math.Inf(1)
+	for _, share := range s.Shares {
+		s.Min = math.Min(s.Min, share)
+		s.Max = math.Max(s.Max, share)
+		s.Mean += share
+	}
+	s.Mean /= float64(len(s.Shares))
+	for _, share := range s.Shares {
+		s.StdDev += (share - s.Mean) * (share - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(s.Shares)))
+	s.Imbalance = s.Max / s.Mean
+
+	return s
+}
+
+// Transfer is a range of the hash space that changes owner between two
+// hashes. From or To is empty when the range has no owner on that side.
+type Transfer struct {
+	Range HashRange `json:"range"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+}
+
+// Get the ranges of the hash space owned by different items in a and b, in
+// order of position. Both hashes should use the same hash function.
+func Diff(a, b *Consistent) []Transfer {
+	aKeys, aOwners := a.arcOwners()
+	bKeys, bOwners := b.arcOwners()
+
+	// Ownership only changes at the points of either ring
+	bounds := append(append([]int{0}, aKeys...), bKeys...)
+	sort.Ints(bounds)
+
+	var transfers []Transfer
+	for i, from := range bounds {
+		if i > 0 && from == bounds[i-1] {
+			continue
+		}
+		to := MaxPosition
+		for _, next := range bounds[i+1:] {
+			if next != from {
+				to = next - 1
+				break
+			}
+		}
+
+		t := Transfer{Range: HashRange{From: from, To: to}, From: ownerAt(aKeys, aOwners, from), To: ownerAt(bKeys, bOwners, from)}
+		if t.From == t.To {
+			continue
+		}
+		if n := len(transfers); n > 0 && transfers[n-1].From == t.From && transfers[n-1].To == t.To && transfers[n-1].Range.To+1 == from {
+			transfers[n-1].Range.To = to
+			continue
+		}
+		transfers = append(transfers, t)
+	}
+
+	// The range wrapping past the top joins the one starting at zero
+	if n := len(transfers); n > 1 && transfers[n-1].Range.To == MaxPosition && transfers[0].Range.From == 0 &&
+		transfers[n-1].From == transfers[0].From && transfers[n-1].To == transfers[0].To {
+		transfers[0].Range.From = transfers[n-1].Range.From
+		transfers = transfers[:n-1]
+	}
+
+	return transfers
+}
+
+// Copy the positions and the item Get maps each arc to, so two hashes can be
+// compared without holding both locks.
+func (m *Consistent) arcOwners() ([]int, []string) {
+	m.RLock()
+	defer m.RUnlock()
+	keys := make([]int, m.ring.size())
+	owners := make([]string, len(keys))
+	for i := range keys {
+		keys[i] = m.ring.key(i)
+		if j := m.ring.arcServer(i, m.atCapacity); j >= 0 {
+			owners[i] = m.ring.nodeAt(j)
+		}
+	}
+	return keys, owners
+}
+
+func ownerAt(keys []int, owners []string, hash int) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	i := sort.Search(len(keys), func(i int) bool { return keys[i] > hash }) - 1
+	if i < 0 {
+		i = len(keys) - 1
+	}
+	return owners[i]
+}
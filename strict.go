@@ -0,0 +1,99 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrEmptyName   = errors.New("consistent: item name is empty")
+	ErrNameTooLong = errors.New("consistent: item name is too long")
+	ErrNameDenied  = errors.New("consistent: item name contains a denied character")
+	ErrInvalidName = errors.New("consistent: item name rejected by the validator")
+)
+
+// The longest item name AddStrict accepts by default.
+const defaultMaxNameLength = 255
+
+// Make AddStrict reject names longer than n bytes. Zero allows any length.
+func WithMaxNameLength(n int) Option {
+	return func(m *Consistent) {
+		if n >= 0 {
+			m.maxNameLength = n
+		}
+	}
+}
+
+// Make AddStrict reject names containing any of the characters in chars.
+// The default is "#", the separator of the default replica names.
+func WithDeniedChars(chars string) Option {
+	return func(m *Consistent) {
+		m.deniedChars = chars
+	}
+}
+
+// Make AddStrict also check names with fn, after the built-in rules. Its
+// errors are wrapped in ErrInvalidName.
+func WithNodeValidator(fn func(string) error) Option {
+	return func(m *Consistent) {
+		m.validator = fn
+	}
+}
+
+// Add a key to the hash like Add, but first check its name: it must not be
+// empty, too long or contain a denied character, and must pass the
+// validator of WithNodeValidator. Returns ErrNodeExists if the key is
+// already present.
+func (m *Consistent) AddStrict(key string) error {
+	return m.addStrict(key, 0, false)
+}
+
+// Add a key with the given number of points, checking its name as AddStrict
+// does.
+func (m *Consistent) AddStrictWithWeight(key string, weight int) error {
+	return m.addStrict(key, weight, true)
+}
+
+// Add a checked key with the given weight, or the replicas unless explicit.
+func (m *Consistent) addStrict(key string, weight int, explicit bool) error {
+	if err := m.validateName(key); err != nil {
+		return err
+	}
+	if weight < 0 {
+		return ErrInvalidWeight
+	}
+
+	var err error
+	m.mutate(&err, func() *Event {
+		if !explicit {
+			weight = m.replicas
+		}
+		if !m.ring.add(key, weight) {
+			err = fmt.Errorf("%w: %q", ErrNodeExists, key)
+			return nil
+		}
+		m.ring.nodes[key].explicit = explicit
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: weight})
+		return &Event{Type: EventAdd, Added: []string{key}}
+	})
+
+	return err
+}
+
+func (m *Consistent) validateName(key string) error {
+	switch {
+	case key == "":
+		return ErrEmptyName
+	case m.maxNameLength > 0 && len(key) > m.maxNameLength:
+		return fmt.Errorf("%w: %d bytes, at most %d", ErrNameTooLong, len(key), m.maxNameLength)
+	case m.deniedChars != "" && strings.ContainsAny(key, m.deniedChars):
+		return fmt.Errorf("%w: %q", ErrNameDenied, key)
+	}
+	if m.validator != nil {
+		if err := m.validator(key); err != nil {
+			return fmt.Errorf("%w: %q: %w", ErrInvalidName, key, err)
+		}
+	}
+	return nil
+}
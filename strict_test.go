@@ -0,0 +1,85 @@
+package consistent
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddStrict(t *testing.T) {
+	errUpper := errors.New("no uppercase")
+	lower := WithNodeValidator(func(s string) error {
+		if strings.ToLower(s) != s {
+			return errUpper
+		}
+		return nil
+	})
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		key  string
+		want []error
+	}{
+		{"empty", nil, "", []error{ErrEmptyName}},
+		{"default length", nil, strings.Repeat("a", 255), nil},
+		{"too long by default", nil, strings.Repeat("a", 256), []error{ErrNameTooLong}},
+		{"too long", []Option{WithMaxNameLength(10)}, "abcdefghijk", []error{ErrNameTooLong}},
+		{"any length", []Option{WithMaxNameLength(0)}, strings.Repeat("a", 1000), nil},
+		{"negative length ignored", []Option{WithMaxNameLength(-1)}, strings.Repeat("a", 256), []error{ErrNameTooLong}},
+		{"denied by default", nil, "a#1", []error{ErrNameDenied}},
+		{"other characters", []Option{WithDeniedChars("/:")}, "a#1", nil},
+		{"denied", []Option{WithDeniedChars("/:")}, "host:80", []error{ErrNameDenied}},
+		{"none denied", []Option{WithDeniedChars("")}, "a#1", nil},
+		{"validator", []Option{lower}, "ABC", []error{ErrInvalidName, errUpper}},
+		{"validator passes", []Option{lower}, "abc", nil},
+		{"built-in rules first", []Option{lower}, "A#", []error{ErrNameDenied}},
+	} {
+		m := New(nil, tc.opts...)
+		err := m.AddStrict(tc.key)
+		if (err == nil) != (tc.want == nil) {
+			t.Errorf("%s: AddStrict = %v, want %v", tc.name, err, tc.want)
+		}
+		for _, want := range tc.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: AddStrict = %v, want %v", tc.name, err, want)
+			}
+		}
+		if added := slices.Contains(m.Members(), tc.key); added != (err == nil) {
+			t.Errorf("%s: added %v after %v", tc.name, added, err)
+		}
+		if err != nil && m.Generation() != 0 {
+			t.Errorf("%s: a rejected name changed the generation to %d", tc.name, m.Generation())
+		}
+	}
+}
+
+func TestAddStrictWithWeight(t *testing.T) {
+	m := New(nil, WithReplicas(3))
+	if err := m.AddStrict("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddStrict("a"); !errors.Is(err, ErrNodeExists) {
+		t.Errorf("adding a twice: %v", err)
+	}
+	if err := m.AddStrictWithWeight("b", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddStrictWithWeight("c", -1); !errors.Is(err, ErrInvalidWeight) {
+		t.Errorf("negative weight: %v", err)
+	}
+	if err := m.AddStrictWithWeight("c#", 2); !errors.Is(err, ErrNameDenied) {
+		t.Errorf("denied name with a weight: %v", err)
+	}
+	for key, want := range map[string]int{"a": 3, "b": 5} {
+		if positions, _ := m.PositionsOf(key); len(positions) != want {
+			t.Errorf("%s has %d points, want %d", key, len(positions), want)
+		}
+	}
+
+	// Names are only checked by the strict adds.
+	m.Add("")
+	if !slices.Contains(m.Members(), "") {
+		t.Error("Add rejected an empty name")
+	}
+}
@@ -0,0 +1,106 @@
+package consistent
+
+// Build a table of 2^bits buckets after every membership change, so a
+// lookup is one hash and one index. Buckets split by a point fall back to
+// the search. More bits cost memory but leave fewer split buckets; bits is
+// clamped to between 1 and 24.
+func WithLookupTable(bits int) Option {
+	return func(m *Consistent) {
+		m.tableBits = min(max(bits, 1), 24)
+	}
+}
+
+// An immutable bucket to owner table, swapped in whole after each change.
+type lookupTable struct {
+	shift uint
+	slots []int32 // Index into names, or -1 if the bucket has several owners
+	names []string
+	pins  map[string]string // Resolved, only the pins deciding a lookup
+	hash  *Hash             // The hash function the ring was placed with
+}
+
+// Returns true if the table was built with the hash function still in use.
+// A lookup loads the table before hashing and checks this after, so its
+// hash and the table always agree.
+func (t *lookupTable) current(m *Consistent) bool {
+	return t.hash == m.hash.Load()
+}
+
+func (t *lookupTable) get(hash int) (string, bool) {
+	i := t.slots[uint32(hash)>>t.shift]
+	if i < 0 {
+		return "", false
+	}
+	return t.names[i], true
+}
+
+func buildTable(r *ring, bits int, strictPins bool, hash *Hash) *lookupTable {
+	if r.size() == 0 || r.halfOpen > 0 || r.tiered() || len(r.rangePins) > 0 {
+		// Get decides the trickle to half-open items, the tier and range pins itself
+		return nil
+	}
+
+	t := &lookupTable{
+		shift: uint(32 - bits),
+		slots: make([]int32, 1<<bits),
+		pins:  r.resolvePins(strictPins),
+		hash:  hash,
+	}
+	index := make(map[string]int32)
+	width := 1 << t.shift
+
+	// The item serving each point's arc, skipping ineligible items
+	serving := make([]string, r.size())
+	last := ""
+	for k := 0; k < 2*r.size(); k++ {
+		i := k % r.size()
+		if node := r.nodeAt(i); r.nodes[node].eligible() {
+			last = node
+		}
+		if k >= r.size() {
+			serving[i] = last
+		}
+	}
+
+	for b := range t.slots {
+		from := b << t.shift
+		to := from + width - 1
+
+		// Every point between the owners of the bucket's ends owns part of it
+		i, last := r.prevIndex(from), r.prevIndex(to)
+		node := serving[i]
+		split := false
+		for i != last && !split {
+			i = (i + 1) % r.size()
+			split = serving[i] != node
+		}
+		if split {
+			t.slots[b] = -1
+			continue
+		}
+
+		n, ok := index[node]
+		if !ok {
+			n = int32(len(t.names))
+			t.names = append(t.names, node)
+			index[node] = n
+		}
+		t.slots[b] = n
+	}
+
+	return t
+}
+
+// Keep the table in step with the ring. Called under the write lock.
+func (m *Consistent) rebuildTable() {
+	if m.lastKnown != nil {
+		m.lastKnown.update(m)
+	}
+	if m.grace != nil {
+		m.grace.update(m)
+	}
+	if m.tableBits == 0 {
+		return
+	}
+	m.table.Store(buildTable(m.ring, m.tableBits, m.strictPins, m.hash.Load()))
+}
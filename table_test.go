@@ -0,0 +1,79 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// Every bucket the table answers must agree with the search, across a
+// random sample of hashes and on both sides of every point.
+func TestLookupTableMatchesSearch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, bits := range []int{1, 4, 8, 16} {
+		c := New(nil, WithLookupTable(bits))
+		for i := 0; i < 20; i++ {
+			c.AddWithWeight(fmt.Sprint("n", i), 1+i%7)
+		}
+		c.Remove("n3")
+		c.AddStandby("s")
+		c.SetHealthy("n5", false)
+
+		answered := 0
+		check := func(h int) {
+			i := c.ring.lookup(h)
+			want := ""
+			if i >= 0 {
+				want = c.ring.nodeAt(i)
+			}
+			if node, ok := c.table.Load().get(h); ok {
+				answered++
+				if node != want {
+					t.Fatalf("bits %d: hash %d maps to %q in the table, %q by search", bits, h, node, want)
+				}
+			}
+		}
+		for i := 0; i < 200000; i++ {
+			check(int(rnd.Uint32()))
+		}
+		for i := 0; i < c.ring.size(); i++ {
+			k := c.ring.key(i)
+			for d := -1; d <= 1; d++ {
+				if k+d >= 0 && k+d <= MaxPosition {
+					check(k + d)
+				}
+			}
+		}
+		check(0)
+		check(MaxPosition)
+		if bits >= 8 && answered == 0 {
+			t.Fatalf("bits %d: the table answered nothing", bits)
+		}
+	}
+}
+
+// Get answers like a hash without a table through changes to the ring.
+func TestLookupTableGet(t *testing.T) {
+	c, ref := New(nil, WithLookupTable(12), WithReplicas(10)), New(nil, WithReplicas(10))
+	keys := make([]string, 20000)
+	for i := range keys {
+		keys[i] = fmt.Sprint("key", i)
+	}
+	steps := []func(m *Consistent){
+		func(m *Consistent) { m.Add("a"); m.Add("b"); m.Add("c") },
+		func(m *Consistent) { m.AddWithWeight("d", 40) },
+		func(m *Consistent) { m.SetHealthy("b", false) },
+		func(m *Consistent) { m.Remove("a") },
+		func(m *Consistent) { m.Pin("key7", "c") },
+		func(m *Consistent) { m.SetHealthy("b", true); m.SetWeight("c", 3) },
+	}
+	for n, step := range steps {
+		step(c)
+		step(ref)
+		for _, k := range keys {
+			if got, want := c.Get(k), ref.Get(k); got != want {
+				t.Fatalf("step %d: %s maps to %q, want %q", n, k, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package consistent
+
+import (
+	"sort"
+)
+
+// Add a key to the hash in a priority tier. Lookups find a key's item among
+// the lowest tier first, as if the other tiers were absent, and move on to
+// the next tier only when the item the lowest picks is unhealthy or at its
+// capacity, see SetCapacity, so only the keys of that item spill over. NextN
+// lists the item Get returns, then the other items tier by tier, clockwise
+// within each. Keys added by Add are in tier 0. Returns ErrNodeExists if the
+// key is present.
+func (m *Consistent) AddTiered(key string, tier int) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if !m.ring.add(key, m.replicas) {
+			err = ErrNodeExists
+			return nil
+		}
+		m.ring.setTier(m.ring.nodes[key], tier)
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: m.replicas, Tier: tier})
+		return &Event{Type: EventAdd, Added: []string{key}}
+	})
+
+	return err
+}
+
+// Returns the tier of an item.
+func (m *Consistent) Tier(key string) (int, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return 0, false
+	}
+	return mem.tier, true
+}
+
+// Index of the point serving hash on a ring with several tiers, see
+// servingTiered. Items for which absent returns true are treated as if they
+// were not in the ring; absent may be nil.
+func (m *Consistent) lookupTiered(hash int, absent func(node string) bool) int {
+	return m.ring.servingTiered(m.ring.prevIndex(hash), m.atCapacity, absent)
+}
+
+// Returns true if an item with a capacity has reached it.
+func (m *Consistent) atCapacity(mem *member) bool {
+	return mem.capacity > 0 && m.loadOf(mem) >= float64(mem.capacity)
+}
+
+// Index of the point serving the arc of the point at index start on a ring
+// with several tiers: the point each tier's items, standbys aside, give the
+// arc, from the lowest tier whose item is healthy and not full. If every
+// such item is full the lowest tier's is used, and if none is healthy the
+// first eligible point of the lowest tier with one.
+func (r *ring) servingTiered(start int, full func(mem *member) bool, absent func(node string) bool) int {
+	order := r.tierOrder()
+	pick := func(tier int, eligible bool) int {
+		i := -1
+		r.walkBack(start, func(j int) bool {
+			node := r.nodeAt(j)
+			mem := r.nodes[node]
+			if mem.tier != tier || mem.standby || eligible && mem.unhealthy || absent != nil && absent(node) {
+				return true
+			}
+			i = j
+			return false
+		})
+		return i
+	}
+
+	fallback := -1
+	for _, tier := range order {
+		i := pick(tier, false)
+		if i < 0 {
+			continue
+		}
+		mem := r.nodes[r.nodeAt(i)]
+		if mem.unhealthy {
+			continue
+		}
+		if !full(mem) {
+			return i
+		}
+		if fallback < 0 {
+			fallback = i
+		}
+	}
+	if fallback >= 0 {
+		return fallback
+	}
+	for _, tier := range order {
+		if i := pick(tier, true); i >= 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Index of the point whose item serves the arc of the point at index i,
+// tiered if the items are in several tiers, or -1.
+func (r *ring) arcServer(i int, full func(mem *member) bool) int {
+	if r.tiered() {
+		return r.servingTiered(i, full, nil)
+	}
+	return r.serving(i)
+}
+
+// The arcs the items of several tiers give key, merged, in order of
+// position.
+func (r *ring) tieredRanges(key string, full func(mem *member) bool) []HashRange {
+	var ranges []HashRange
+	for i := 0; i < r.size(); i++ {
+		if j := r.servingTiered(i, full, nil); j < 0 || r.nodeAt(j) != key {
+			continue
+		}
+		arc := r.arc(i)
+		if n := len(ranges); n > 0 && ranges[n-1].To+1 == arc.From {
+			ranges[n-1].To = arc.To
+			continue
+		}
+		ranges = append(ranges, arc)
+	}
+	return ranges
+}
+
+// Call fn with the points of NextN for hash on a ring with several tiers,
+// as ring.candidates does: the point lookupTiered picks, then the first
+// point of each other item, tier by tier and clockwise within each, until
+// fn returns false.
+func (m *Consistent) candidatesTiered(hash int, fn func(i int) bool) {
+	seen := make(map[string]bool)
+	if i := m.lookupTiered(hash, nil); i >= 0 {
+		if !fn(i) {
+			return
+		}
+		seen[m.ring.nodeAt(i)] = true
+	}
+
+	start := m.ring.prevIndex(hash)
+	for _, tier := range m.ring.tierOrder() {
+		done := false
+		m.ring.walk(start, func(i int) bool {
+			key := m.ring.nodeAt(i)
+			if seen[key] || m.ring.nodes[key].tier != tier {
+				return true
+			}
+			seen[key] = true
+			done = !fn(i)
+			return !done
+		})
+		if done {
+			return
+		}
+	}
+}
+
+// Index of the point serving hash as Get finds it: the tiered lookup if the
+// items are in several tiers, otherwise the ring's with the trickle to
+// half-open items. Returns -1 if there is none.
+func (m *Consistent) lookup(hash int) int {
+	if m.ring.tiered() {
+		return m.lookupTiered(hash, nil)
+	}
+	return m.ring.lookupTrickle(hash, m.trickle())
+}
+
+// Call fn with the points of NextN for hash, starting with the one Get
+// picks, tiered if the items are in several tiers.
+func (m *Consistent) candidates(hash int, fn func(i int) bool) {
+	if m.ring.tiered() {
+		m.candidatesTiered(hash, fn)
+		return
+	}
+	m.ring.candidatesFrom(m.ring.prevIndex(hash), m.lookup(hash), fn)
+}
+
+func (r *ring) setTier(mem *member, tier int) {
+	if mem.tier == tier {
+		return
+	}
+	if mem.tier != 0 {
+		if r.tiers[mem.tier]--; r.tiers[mem.tier] == 0 {
+			delete(r.tiers, mem.tier)
+		}
+	}
+	if tier != 0 {
+		if r.tiers == nil {
+			r.tiers = make(map[int]int)
+		}
+		r.tiers[tier]++
+	}
+	mem.tier = tier
+}
+
+// Returns true if the items are in more than one tier.
+func (r *ring) tiered() bool {
+	switch len(r.tiers) {
+	case 0:
+		return false
+	case 1:
+		// Tier 0 is not counted
+		for _, n := range r.tiers {
+			return n < len(r.nodes)
+		}
+	}
+	return true
+}
+
+// The tiers of the items, lowest first.
+func (r *ring) tierOrder() []int {
+	tiers := make([]int, 0, len(r.tiers)+1)
+	total := 0
+	for tier, n := range r.tiers {
+		tiers = append(tiers, tier)
+		total += n
+	}
+	if total < len(r.nodes) {
+		tiers = append(tiers, 0)
+	}
+	sort.Ints(tiers)
+	return tiers
+}
@@ -0,0 +1,215 @@
+package consistent
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func tieredRing() *Consistent {
+	c := New(nil, WithReplicas(40))
+	for i := 0; i < 3; i++ {
+		c.AddTiered(fmt.Sprint("onprem", i), 1)
+		c.AddTiered(fmt.Sprint("cloud", i), 2)
+	}
+	return c
+}
+
+// A hash with only the items of one tier of tieredRing.
+func tierRing(prefix string) *Consistent {
+	c := New(nil, WithReplicas(40))
+	for i := 0; i < 3; i++ {
+		c.Add(fmt.Sprint(prefix, i))
+	}
+	return c
+}
+
+// Check every lookup agrees with Get on a tiered hash.
+func checkTieredLookups(t *testing.T, c *Consistent, keys []string) {
+	t.Helper()
+	v := c.ReadOnly()
+	f := NewFailover(c)
+	groups := c.AssignAll(keys)
+	owned := c.RangeOwners(0, MaxPosition)
+	for _, k := range keys {
+		node := c.Get(k)
+		if o, _ := c.GetOwner(k); o.Node != node {
+			t.Fatalf("%s: GetOwner %s, Get %s", k, o.Node, node)
+		}
+		if o, _ := v.GetOwner(k); o.Node != node || v.Get(k) != node {
+			t.Fatalf("%s: view %s, Get %s", k, o.Node, node)
+		}
+		if got := f.Get(k); got != node {
+			t.Fatalf("%s: Failover %s, Get %s", k, got, node)
+		}
+		if got := c.PrevN(k, 1); got[0] != node {
+			t.Fatalf("%s: PrevN %v, Get %s", k, got, node)
+		}
+		next := c.NextN(k, 6)
+		if len(next) != 6 || next[0] != node {
+			t.Fatalf("%s: NextN %v, Get %s", k, next, node)
+		}
+		for i := 2; i < len(next); i++ {
+			// Tier by tier after the first
+			if tierOf(c, next[i]) < tierOf(c, next[i-1]) {
+				t.Fatalf("%s: NextN %v out of tier order", k, next)
+			}
+		}
+		if !reflect.DeepEqual(v.NextN(k, 6), next) {
+			t.Fatalf("%s: view NextN %v, NextN %v", k, v.NextN(k, 6), next)
+		}
+		found := false
+		for _, g := range groups[node] {
+			found = found || g == k
+		}
+		if !found {
+			t.Fatalf("%s: AssignAll does not put it on %s", k, node)
+		}
+		inRanges := false
+		for _, r := range c.Ranges(node) {
+			inRanges = inRanges || contains(r, c.Hash(k))
+		}
+		if !inRanges {
+			t.Fatalf("%s: hash %d not in the ranges of %s", k, c.Hash(k), node)
+		}
+		for _, o := range owned {
+			if contains(o.Range, c.Hash(k)) && o.Node != node {
+				t.Fatalf("%s: RangeOwners gives %s, Get %s", k, o.Node, node)
+			}
+		}
+	}
+
+	var total uint64
+	s := c.Stats()
+	for _, node := range c.Members() {
+		length, _ := c.ArcLength(node)
+		var ranged uint64
+		for _, r := range c.Ranges(node) {
+			ranged += rangeLength(r)
+		}
+		if ranged != length || math.Abs(float64(length)/(MaxPosition+1)-s.Shares[node]) > 1e-9 {
+			t.Fatalf("%s: ranges %d, arc length %d, share %v", node, ranged, length, s.Shares[node])
+		}
+		total += length
+	}
+	if total != MaxPosition+1 {
+		t.Fatalf("arc lengths sum to %d", total)
+	}
+}
+
+func tierOf(c *Consistent, node string) int {
+	tier, _ := c.Tier(node)
+	return tier
+}
+
+func TestTiersSpillOnlyUnhealthyKeys(t *testing.T) {
+	c := tieredRing()
+	onprem, cloud := tierRing("onprem"), tierRing("cloud")
+	keys := testKeys(5000)
+
+	// Tier 2 gets no keys while tier 1 is healthy
+	base := getAll(c, keys)
+	for _, k := range keys {
+		if base[k] != onprem.Get(k) {
+			t.Fatalf("%s: %s, tier 1 alone %s", k, base[k], onprem.Get(k))
+		}
+	}
+	if share := c.Stats().Shares["cloud0"] + c.Stats().Shares["cloud1"] + c.Stats().Shares["cloud2"]; share != 0 {
+		t.Fatalf("tier 2 has a share of %v", share)
+	}
+	checkTieredLookups(t, c, keys)
+
+	// Exactly the keys of unhealthy tier 1 items go to tier 2, where tier
+	// 2 alone would put them
+	down := map[string]bool{}
+	for _, node := range []string{"onprem0", "onprem2"} {
+		c.SetHealthy(node, false)
+		down[node] = true
+		for _, k := range keys {
+			got := c.Get(k)
+			if down[base[k]] && got != cloud.Get(k) || !down[base[k]] && got != base[k] {
+				t.Fatalf("%s of %s down: %s moved to %s", k, node, base[k], got)
+			}
+			if down[base[k]] != strings.HasPrefix(got, "cloud") {
+				t.Fatalf("%s of %s down: on %s", k, node, got)
+			}
+		}
+		checkTieredLookups(t, c, keys)
+	}
+
+	// And come back on recovery
+	for node := range down {
+		c.SetHealthy(node, true)
+	}
+	for _, k := range keys {
+		if got := c.Get(k); got != base[k] {
+			t.Fatalf("%s: %s after recovery, %s before", k, got, base[k])
+		}
+	}
+	checkTieredLookups(t, c, keys)
+
+	// Keys follow the capacity of their item to the next tier
+	c.SetCapacity("onprem1", 1)
+	c.Inc("onprem1")
+	for _, k := range keys {
+		if got := c.Get(k); base[k] == "onprem1" && got != cloud.Get(k) || base[k] != "onprem1" && got != base[k] {
+			t.Fatalf("%s: %s at capacity, %s moved to %s", k, "onprem1", base[k], got)
+		}
+	}
+	checkTieredLookups(t, c, keys)
+	c.Done("onprem1")
+
+	// With no tier able to take a key it goes to the lowest tier's first
+	// eligible item
+	for i := 0; i < 3; i++ {
+		c.SetHealthy(fmt.Sprint("cloud", i), false)
+	}
+	c.SetHealthy("onprem0", false)
+	for _, k := range keys {
+		got := c.Get(k)
+		if base[k] != "onprem0" && got != base[k] || got == "onprem0" || !strings.HasPrefix(got, "onprem") {
+			t.Fatalf("%s: %s on %s with tier 2 down", k, base[k], got)
+		}
+	}
+	checkTieredLookups(t, c, keys)
+
+	impact := c.FailoverImpactKeys("onprem1", keys)
+	for node := range impact {
+		if node != "onprem2" {
+			t.Fatalf("onprem1 fails over to %s, %v", node, impact)
+		}
+	}
+}
+
+func TestTiersPersist(t *testing.T) {
+	c := tieredRing()
+	if tier, ok := c.Tier("cloud1"); tier != 2 || !ok {
+		t.Fatal(tier, ok)
+	}
+	if err := c.AddTiered("cloud1", 3); err != ErrNodeExists {
+		t.Fatal(err)
+	}
+
+	r := New(nil, WithReplicas(40))
+	if err := r.Restore(c.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	d, err := c.ChangesSince(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(nil, WithReplicas(40))
+	if err := f.ApplyDelta(d); err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []*Consistent{r, f} {
+		if other.Fingerprint() != c.Fingerprint() {
+			t.Fatal("fingerprint differs")
+		}
+		if tier, _ := other.Tier("cloud1"); tier != 2 {
+			t.Fatal(tier)
+		}
+	}
+}
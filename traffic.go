@@ -0,0 +1,109 @@
+package consistent
+
+import (
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Call fn after every lookup by Get and NextN with the key and the item it
+// resolved to, or "" if the hash had no item for it. fn runs outside the
+// hash's lock on the caller's goroutine, so it must be cheap.
+func WithLookupObserver(fn func(key, node string)) Option {
+	return func(m *Consistent) {
+		m.onLookup = fn
+	}
+}
+
+// Count the lookups by Get and NextN each item resolves, for TrafficCounts.
+// Counting is one atomic increment per lookup.
+func WithTrafficCounts() Option {
+	return func(m *Consistent) {
+		m.traffic = &traffic{}
+	}
+}
+
+// Per-item lookup counters, created on an item's first lookup and then
+// updated without locks. The map is copied to add a counter, so a lookup
+// reads it with one atomic load.
+type traffic struct {
+	mu     sync.Mutex // Held to add a counter
+	counts atomic.Pointer[map[string]*atomic.Uint64]
+}
+
+func (t *traffic) inc(node string) {
+	if counts := t.counts.Load(); counts != nil {
+		if c, ok := (*counts)[node]; ok {
+			c.Add(1)
+			return
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var counts map[string]*atomic.Uint64
+	if old := t.counts.Load(); old != nil {
+		if c, ok := (*old)[node]; ok {
+			c.Add(1)
+			return
+		}
+		counts = maps.Clone(*old)
+	} else {
+		counts = make(map[string]*atomic.Uint64)
+	}
+	c := new(atomic.Uint64)
+	c.Add(1)
+	counts[node] = c
+	t.counts.Store(&counts)
+}
+
+// Report each non-zero counter, setting it to zero if reset.
+func (t *traffic) read(reset bool) map[string]uint64 {
+	counts := make(map[string]uint64)
+	all := t.counts.Load()
+	if all == nil {
+		return counts
+	}
+	for node, c := range *all {
+		var n uint64
+		if reset {
+			n = c.Swap(0)
+		} else {
+			n = c.Load()
+		}
+		if n > 0 {
+			counts[node] = n
+		}
+	}
+	return counts
+}
+
+// Record a lookup for the observer and the traffic counters.
+func (m *Consistent) observe(key, node string) {
+	if m.traffic != nil {
+		m.traffic.inc(node)
+	}
+	if m.onLookup != nil {
+		m.onLookup(key, node)
+	}
+}
+
+// Get the number of lookups by Get and NextN that resolved to each item
+// since the counts were last reset, with lookups that found no item under
+// "". An item that leaves keeps its count until the counts are reset.
+// Returns nil without WithTrafficCounts.
+func (m *Consistent) TrafficCounts() map[string]uint64 {
+	if m.traffic == nil {
+		return nil
+	}
+	return m.traffic.read(false)
+}
+
+// Get the traffic counts as TrafficCounts does and set them to zero. Each
+// lookup is counted in exactly one reading.
+func (m *Consistent) ResetTrafficCounts() map[string]uint64 {
+	if m.traffic == nil {
+		return nil
+	}
+	return m.traffic.read(true)
+}
@@ -0,0 +1,81 @@
+package consistent
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTrafficCounts(t *testing.T) {
+	if New(nil).TrafficCounts() != nil {
+		t.Fatal("counts without WithTrafficCounts")
+	}
+	var mu sync.Mutex
+	seen := make(map[string]uint64)
+	m := New(nil, WithTrafficCounts(), WithLookupTable(8), WithLookupObserver(func(key, node string) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[node]++
+	}))
+	m.Get("key")
+	if counts := m.TrafficCounts(); counts[""] != 1 || seen[""] != 1 {
+		t.Fatalf("a lookup on an empty hash counted %v, observed %v", counts, seen)
+	}
+
+	m.Add("a")
+	m.Add("b")
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				m.Get(fmt.Sprint(i))
+			}
+		}()
+	}
+	wg.Wait()
+	m.NextN("key", 2) // One lookup, of the first item
+	m.NextN("key", 0)
+
+	counts := m.ResetTrafficCounts()
+	if counts["a"]+counts["b"] != 4001 || counts[""] != 1 {
+		t.Fatalf("counted %v, want 4001 lookups and 1 without an item", counts)
+	}
+	for node, n := range counts {
+		if seen[node] != n {
+			t.Fatalf("%s: counted %d, observed %d", node, n, seen[node])
+		}
+	}
+	if counts := m.TrafficCounts(); len(counts) != 0 {
+		t.Fatalf("counts after a reset: %v", counts)
+	}
+	m.Get("key")
+	if counts := m.TrafficCounts(); counts[m.Get("key")] != 1 {
+		t.Fatalf("counts after a reset and a lookup: %v", counts)
+	}
+}
+
+func BenchmarkLookupObserver(b *testing.B) {
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"none", nil},
+		{"counts", []Option{WithTrafficCounts()}},
+		{"observer", []Option{WithLookupObserver(func(key, node string) {})}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			m := New(nil, append(bc.opts, WithReplicas(100))...)
+			for i := 0; i < 10; i++ {
+				m.Add(fmt.Sprint("n", i))
+			}
+			keys := testKeys(1024)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.Get(keys[i%len(keys)])
+			}
+		})
+	}
+}
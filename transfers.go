@@ -0,0 +1,88 @@
+package consistent
+
+// Add a key like Add and get the ranges that move to it, with the item each
+// moves from, in order of position. They are computed under the same lock
+// as the change, so they are exact. A range taken from an empty hash moves
+// from no item. Returns ErrNodeExists if the key is present.
+func (m *Consistent) AddDetailed(key string) ([]Transfer, error) {
+	var plan []Transfer
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[key]; ok {
+			err = ErrNodeExists
+			return nil
+		}
+
+		// The previous owners of positions the key takes over from another,
+		// which split placement never does
+		var taken map[int]string
+		for replica := 0; replica < m.replicas && !m.ring.splitArcs; replica++ {
+			pos := m.ring.position(key, replica)
+			if p, ok := m.ring.pointAt(pos); ok {
+				if taken == nil {
+					taken = make(map[int]string)
+				}
+				taken[pos] = m.ring.names[p.node]
+			}
+		}
+
+		m.ring.add(key, m.replicas)
+		plan = m.ring.addPlan(key, taken)
+		m.history.record(Change{Type: ChangeAdd, Key: key, Weight: m.replicas})
+		return &Event{Type: EventAdd, Added: []string{key}}
+	})
+
+	return plan, err
+}
+
+// Remove a key like Remove and get the ranges that move from it, as
+// DrainPlan describes them, computed under the same lock as the change.
+// Returns ErrNodeNotFound if the key is not present.
+func (m *Consistent) RemoveDetailed(key string) ([]Transfer, error) {
+	var plan []Transfer
+	var err error
+	m.mutate(&err, func() *Event {
+		if _, ok := m.ring.nodes[key]; !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		plan = m.ring.drainPlan(key)
+		m.ring.remove(key)
+		m.history.record(Change{Type: ChangeRemove, Key: key})
+		return &Event{Type: EventRemove, Removed: []string{key}}
+	})
+
+	return plan, err
+}
+
+// The ranges that moved to a key just added, each from the item serving it
+// before: the first eligible point counter-clockwise that is not the key's,
+// or the previous owner of a position the key took over.
+func (r *ring) addPlan(key string, taken map[int]string) []Transfer {
+	mem := r.nodes[key]
+	if !mem.eligible() {
+		return nil
+	}
+
+	var plan []Transfer
+	for _, pos := range mem.positions {
+		i := r.index.search(pos)
+		t := Transfer{Range: r.servedArc(i), To: key}
+		r.walkBack(i, func(j int) bool {
+			node := r.nodeAt(j)
+			if node == key {
+				prev, ok := taken[r.key(j)]
+				if !ok || !r.nodes[prev].eligible() {
+					return true
+				}
+				node = prev
+			} else if !r.nodes[node].eligible() {
+				return true
+			}
+			t.From = node
+			return false
+		})
+		plan = appendTransfer(plan, t)
+	}
+	return joinWrapped(plan)
+}
@@ -0,0 +1,100 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func byStart(plan []Transfer) []Transfer {
+	plan = slices.Clone(plan)
+	slices.SortFunc(plan, func(a, b Transfer) int { return a.Range.From - b.Range.From })
+	return plan
+}
+
+// Across random adds and removals, the transfers are exactly the difference
+// between the hash before and after: an add moves only to the new item and
+// a removal only from the removed one.
+func TestDetailedTransfers(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	m, before := New(nil, WithReplicas(30)), New(nil, WithReplicas(30))
+	for _, c := range []*Consistent{m, before} {
+		for i := 0; i < 6; i++ {
+			c.Add(fmt.Sprint("n", i))
+		}
+		c.AddStandby("s")
+		c.SetHealthy("n2", false)
+	}
+	keys := testKeys(2000)
+
+	for i := 0; i < 100; i++ {
+		var plan []Transfer
+		var key string
+		var err error
+		members := m.Members()
+		adding := i%2 == 0 || len(members) < 4
+		if adding {
+			key = fmt.Sprint("x", i)
+			plan, err = m.AddDetailed(key)
+		} else {
+			key = members[rnd.Intn(len(members))]
+			plan, err = m.RemoveDetailed(key)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := Diff(before, m); !slices.Equal(byStart(plan), byStart(want)) {
+			t.Fatalf("step %d: transfers %v, the hashes differ by %v", i, plan, want)
+		}
+		for _, tr := range plan {
+			if adding && tr.To != key || !adding && tr.From != key {
+				t.Fatalf("step %d: %v does not involve %s", i, tr, key)
+			}
+			if tr.From == "s" || tr.To == "s" || tr.From == "n2" || tr.To == "n2" {
+				t.Fatalf("step %d: %v involves an item serving no keys", i, tr)
+			}
+		}
+		for _, k := range keys {
+			h, moved := m.Hash(k), false
+			for _, tr := range plan {
+				moved = moved || contains(tr.Range, h)
+			}
+			if was, is := before.Get(k), m.Get(k); moved == (was == is) {
+				t.Fatalf("step %d: %s went from %s to %s, in a transfer: %v", i, k, was, is, moved)
+			}
+		}
+		if adding {
+			before.Add(key)
+		} else {
+			before.Remove(key)
+		}
+	}
+}
+
+func TestDetailedEdges(t *testing.T) {
+	m := New(nil)
+	plan, err := m.AddDetailed("a")
+	if err != nil || len(plan) != 1 || plan[0].From != "" || plan[0].To != "a" || rangeLength(plan[0].Range) != 1<<32 {
+		t.Fatalf("the first item took %v, %v, want the whole space", plan, err)
+	}
+	if plan, _ := m.AddDetailed("b"); len(plan) != 1 || plan[0].From != "a" {
+		t.Fatalf("the second item took %v", plan)
+	}
+	if _, err := m.AddDetailed("a"); !errors.Is(err, ErrNodeExists) {
+		t.Fatal(err)
+	}
+	if _, err := m.RemoveDetailed("missing"); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatal(err)
+	}
+
+	m.SetHealthy("b", false)
+	if plan, err := m.RemoveDetailed("b"); err != nil || plan != nil {
+		t.Fatalf("removing an unhealthy item moved %v, %v", plan, err)
+	}
+	if plan, _ := m.RemoveDetailed("a"); len(plan) != 1 || plan[0].To != "" || rangeLength(plan[0].Range) != 1<<32 {
+		t.Fatalf("removing the last item moved %v", plan)
+	}
+}
@@ -0,0 +1,106 @@
+package consistent
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func normalize(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+func TestKeyTransformLookups(t *testing.T) {
+	m := New(nil, WithKeyTransform(normalize), WithReplicas(20))
+	plain := New(nil, WithReplicas(20))
+	for _, c := range []*Consistent{m, plain} {
+		for _, node := range []string{"a", "b", "c", "d"} {
+			c.Add(node)
+		}
+	}
+	for _, key := range []string{"user:42", "User:42", " USER:42\t"} {
+		if got, want := m.Hash(key), plain.Hash("user:42"); got != want {
+			t.Errorf("Hash(%q) = %d, want %d", key, got, want)
+		}
+		if got, want := m.Get(key), plain.Get("user:42"); got != want {
+			t.Errorf("Get(%q) = %s, want %s", key, got, want)
+		}
+		if got, want := m.NextN(key, 3), plain.NextN("user:42", 3); !slices.Equal(got, want) {
+			t.Errorf("NextN(%q) = %v, want %v", key, got, want)
+		}
+		if got, want := m.PrevN(key, 3), plain.PrevN("user:42", 3); !slices.Equal(got, want) {
+			t.Errorf("PrevN(%q) = %v, want %v", key, got, want)
+		}
+		if got, want := m.HashParts(key, key), plain.HashParts("user:42", "user:42"); got != want {
+			t.Errorf("HashParts(%q) = %d, want %d", key, got, want)
+		}
+	}
+	// Reading bytes skips the transform
+	if got, _ := m.HashReader(strings.NewReader("User:42")); got != plain.Hash("User:42") {
+		t.Errorf("HashReader transformed the key")
+	}
+
+	// Pins are of lookup keys
+	owner := m.Get("user:42")
+	other := "a"
+	if owner == other {
+		other = "b"
+	}
+	m.Pin(" User:42", other)
+	if got := m.Get("USER:42"); got != other {
+		t.Errorf("the pin of a transformed key is not applied: got %s, want %s", got, other)
+	}
+}
+
+// The transform changes lookup keys only: items are added, placed and found
+// by their exact names.
+func TestKeyTransformNotAppliedToItems(t *testing.T) {
+	m := New(nil, WithKeyTransform(normalize), WithReplicas(20))
+	plain := New(nil, WithReplicas(20))
+	for _, c := range []*Consistent{m, plain} {
+		c.Add("Alpha")
+		c.Add("alpha")
+		c.AddWithWeight(" Beta ", 5)
+	}
+	if got := m.Members(); !slices.Equal(got, []string{" Beta ", "Alpha", "alpha"}) {
+		t.Fatalf("members %v", got)
+	}
+	if m.Fingerprint() != plain.Fingerprint() {
+		t.Fatal("items were placed by transformed names")
+	}
+	for _, node := range m.Members() {
+		got, _ := m.PositionsOf(node)
+		want, _ := plain.PositionsOf(node)
+		if !slices.Equal(got, want) {
+			t.Fatalf("%q placed at %v, want %v", node, got, want)
+		}
+	}
+	if from, _ := m.Range("Alpha"); from != int(m.sum([]byte("Alpha"))) {
+		t.Fatalf("Range of Alpha starts at %d, the hash of its exact name is %d", from, m.sum([]byte("Alpha")))
+	}
+
+	if err := m.SetWeight("ALPHA", 2); err == nil {
+		t.Fatal("set the weight of ALPHA, which is not an item")
+	}
+	if err := m.TryRemove("Alpha"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Members(); !slices.Equal(got, []string{" Beta ", "alpha"}) {
+		t.Fatalf("removing Alpha left %v", got)
+	}
+	if err := m.Rename(" Beta ", "BETA"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Members(); !slices.Equal(got, []string{"BETA", "alpha"}) {
+		t.Fatalf("renaming left %v", got)
+	}
+}
+
+// Without a transform, Hash allocates no more than hashing the key's bytes.
+func TestKeyTransformAllocations(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	key := "key"
+	bytes := testing.AllocsPerRun(1000, func() { m.sum([]byte(key)) })
+	if allocs := testing.AllocsPerRun(1000, func() { m.Hash(key) }); allocs > bytes {
+		t.Fatalf("Hash allocates %v times, hashing the bytes %v", allocs, bytes)
+	}
+}
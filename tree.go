@@ -0,0 +1,159 @@
+package consistent
+
+import (
+	"math/rand/v2"
+)
+
+// Use a balanced tree for the points instead of sorted slices, so adding
+// and removing items costs O(log n) per point instead of moving the points
+// after it. Lookups are up to twice as slow, so this suits rings whose
+// membership changes constantly; its changes are faster from a few
+// thousand points.
+func WithChurnOptimizedIndex() Option {
+	return func(m *Consistent) {
+		m.treeIndex = true
+	}
+}
+
+// Points in a treap ordered by position, where each node counts its subtree
+// so points can be found by rank.
+type treeIndex struct {
+	root *treeNode
+}
+
+type treeNode struct {
+	pos         int
+	point       point
+	priority    uint32
+	size        int
+	left, right *treeNode
+}
+
+func (n *treeNode) count() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *treeNode) update() *treeNode {
+	n.size = 1 + n.left.count() + n.right.count()
+	return n
+}
+
+// Build a balanced tree from sorted entries, with priorities decreasing
+// with depth so later inserts keep it a treap.
+func build(sorted []entry) *treeNode {
+	if len(sorted) == 0 {
+		return nil
+	}
+	levels := 0
+	for n := len(sorted); n > 0; n >>= 1 {
+		levels++
+	}
+	// Each level takes a band of priorities below its parent's
+	return buildLevel(sorted, levels-1, uint32(1<<32-1)/uint32(levels))
+}
+
+func buildLevel(sorted []entry, level int, band uint32) *treeNode {
+	if len(sorted) == 0 {
+		return nil
+	}
+	mid := len(sorted) / 2
+	n := &treeNode{
+		pos:      sorted[mid].pos,
+		point:    sorted[mid].point,
+		priority: uint32(level)*band + rand.Uint32N(band),
+		left:     buildLevel(sorted[:mid], level-1, band),
+		right:    buildLevel(sorted[mid+1:], level-1, band),
+	}
+	return n.update()
+}
+
+func (t *treeIndex) len() int {
+	return t.root.count()
+}
+
+func (t *treeIndex) node(i int) *treeNode {
+	n := t.root
+	for {
+		switch left := n.left.count(); {
+		case i < left:
+			n = n.left
+		case i == left:
+			return n
+		default:
+			i -= left + 1
+			n = n.right
+		}
+	}
+}
+
+func (t *treeIndex) at(i int) (int, point) {
+	n := t.node(i)
+	return n.pos, n.point
+}
+
+func (t *treeIndex) search(pos int) int {
+	rank := 0
+	for n := t.root; n != nil; {
+		if n.pos < pos {
+			rank += n.left.count() + 1
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return rank
+}
+
+func (t *treeIndex) set(i int, p point) {
+	t.node(i).point = p
+}
+
+func (t *treeIndex) insert(batch []entry) {
+	for _, e := range batch {
+		left, right := split(t.root, e.pos)
+		n := &treeNode{pos: e.pos, point: e.point, priority: rand.Uint32(), size: 1}
+		t.root = merge(merge(left, n), right)
+	}
+}
+
+func (t *treeIndex) delete(batch []int) {
+	for _, pos := range batch {
+		left, rest := split(t.root, pos)
+		_, right := split(rest, pos+1)
+		t.root = merge(left, right)
+	}
+}
+
+// Split a tree into the positions before pos and the rest.
+func split(n *treeNode, pos int) (*treeNode, *treeNode) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.pos < pos {
+		left, right := split(n.right, pos)
+		n.right = left
+		return n.update(), right
+	}
+	left, right := split(n.left, pos)
+	n.left = right
+	return left, n.update()
+}
+
+// Join two trees where every position of a is before those of b.
+func merge(a, b *treeNode) *treeNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.priority > b.priority:
+		a.right = merge(a.right, b)
+		return a.update()
+	default:
+		b.left = merge(a, b.left)
+		return b.update()
+	}
+}
@@ -0,0 +1,115 @@
+package consistent
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// Rings with the sorted and the tree index answer every lookup the same way
+// through a random sequence of mutations.
+func TestChurnIndexMatchesSorted(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   Hash
+		opts []Option
+	}{
+		{"default", nil, nil},
+		{"collisions", smallHash, nil},
+		{"arc splitting", nil, []Option{WithArcSplittingPlacement()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithReplicas(8)}, tc.opts...)
+			sorted := New(tc.fn, opts...)
+			tree := New(tc.fn, append(opts, WithChurnOptimizedIndex())...)
+			both := func(fn func(m *Consistent)) {
+				fn(sorted)
+				fn(tree)
+			}
+
+			rnd := rand.New(rand.NewSource(3))
+			name := func() string { return fmt.Sprint("n", rnd.Intn(25)) }
+			keys := testKeys(200)
+			for step := 0; step < 600; step++ {
+				key, other := name(), name()
+				weight, flag := rnd.Intn(16), rnd.Intn(2) == 0
+				switch rnd.Intn(10) {
+				case 0, 1:
+					both(func(m *Consistent) { m.Add(key) })
+				case 2:
+					both(func(m *Consistent) { m.AddWithWeight(key, weight) })
+				case 3:
+					both(func(m *Consistent) { m.Remove(key) })
+				case 4:
+					both(func(m *Consistent) { m.Rename(key, other) })
+				case 5:
+					both(func(m *Consistent) { m.AddStandby(key) })
+				case 6:
+					both(func(m *Consistent) { m.SetHealthy(key, flag) })
+				case 7:
+					both(func(m *Consistent) { m.Pin(keys[weight], key) })
+				case 8:
+					both(func(m *Consistent) { m.PinRange(uint32(weight)<<28, uint32(weight)<<28|1<<20, key) })
+				case 9:
+					both(func(m *Consistent) { m.UnpinRange(uint32(weight)<<28, uint32(weight)<<28|1<<20) })
+				}
+
+				if sorted.Fingerprint() != tree.Fingerprint() {
+					t.Fatalf("step %d: fingerprints differ", step)
+				}
+				if err := tree.Validate(); err != nil {
+					t.Fatal(step, err)
+				}
+				for _, k := range keys[:50] {
+					if s, tr := sorted.Get(k), tree.Get(k); s != tr {
+						t.Fatalf("step %d: Get(%s) = %s sorted, %s tree", step, k, s, tr)
+					}
+					if s, tr := sorted.NextN(k, 3), tree.NextN(k, 3); !reflect.DeepEqual(s, tr) {
+						t.Fatalf("step %d: NextN(%s) = %q sorted, %q tree", step, k, s, tr)
+					}
+					if s, tr := sorted.PrevN(k, 2), tree.PrevN(k, 2); !reflect.DeepEqual(s, tr) {
+						t.Fatalf("step %d: PrevN(%s) = %q sorted, %q tree", step, k, s, tr)
+					}
+				}
+				for _, member := range sorted.Members() {
+					if s, tr := sorted.Ranges(member), tree.Ranges(member); !reflect.DeepEqual(s, tr) {
+						t.Fatalf("step %d: Ranges(%s) = %v sorted, %v tree", step, member, s, tr)
+					}
+				}
+			}
+		})
+	}
+}
+
+// Adding and removing a one-point item, and a lookup, on rings of growing
+// size with each index, to find where the tree starts to pay off.
+func BenchmarkIndexCrossover(b *testing.B) {
+	for _, points := range []int{100, 1000, 10000, 100000} {
+		for _, tree := range []bool{false, true} {
+			opts := []Option{WithReplicas(100)}
+			if tree {
+				opts = append(opts, WithChurnOptimizedIndex())
+			}
+			m := New(nil, opts...)
+			m.Update(func(tx *Tx) error {
+				for i := 0; i < points/100; i++ {
+					tx.Add(fmt.Sprint("n", i))
+				}
+				return nil
+			})
+			b.Run(fmt.Sprintf("churn/points=%d/tree=%v", points, tree), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					m.AddWithWeight("worker", 1)
+					m.Remove("worker")
+				}
+			})
+			b.Run(fmt.Sprintf("get/points=%d/tree=%v", points, tree), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					m.Get("key")
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package consistent
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Tx stages changes to a copy of a hash's membership. See Update.
+type Tx struct {
+	r        *ring
+	replicas int
+	changes  []Change
+}
+
+// Add a key to the staged membership.
+func (tx *Tx) Add(key string) {
+	if tx.r.add(key, tx.replicas) {
+		tx.changes = append(tx.changes, Change{Type: ChangeAdd, Key: key, Weight: tx.replicas})
+	}
+}
+
+// Add a key with the given number of points, or change the weight of a key
+// already present.
+func (tx *Tx) AddWithWeight(key string, weight int) error {
+	if weight < 0 {
+		return ErrInvalidWeight
+	}
+	if tx.r.add(key, weight) {
+		tx.changes = append(tx.changes, Change{Type: ChangeAdd, Key: key, Weight: weight})
+	} else if tx.r.setWeight(key, weight) {
+		tx.changes = append(tx.changes, Change{Type: ChangeWeight, Key: key, Weight: weight})
+	}
+	tx.r.nodes[key].explicit = true
+	return nil
+}
+
+// Add a standby to the staged membership.
+func (tx *Tx) AddStandby(key string) {
+	if tx.r.add(key, tx.replicas) {
+		tx.r.nodes[key].standby = true
+		tx.changes = append(tx.changes, Change{Type: ChangeAdd, Key: key, Weight: tx.replicas, Standby: true})
+	}
+}
+
+// Make a standby in the staged membership a full member.
+func (tx *Tx) Promote(key string) error {
+	mem, ok := tx.r.nodes[key]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if mem.standby {
+		mem.standby = false
+		tx.changes = append(tx.changes, Change{Type: ChangePromote, Key: key})
+	}
+	return nil
+}
+
+// Remove a key from the staged membership.
+func (tx *Tx) Remove(key string) {
+	if tx.r.remove(key) {
+		tx.changes = append(tx.changes, Change{Type: ChangeRemove, Key: key})
+	}
+}
+
+// Change the number of points of a key in the staged membership.
+func (tx *Tx) SetWeight(key string, weight int) error {
+	if weight < 0 {
+		return ErrInvalidWeight
+	}
+	mem, ok := tx.r.nodes[key]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	mem.explicit = true
+	if tx.r.setWeight(key, weight) {
+		tx.changes = append(tx.changes, Change{Type: ChangeWeight, Key: key, Weight: weight})
+	}
+	return nil
+}
+
+// Label a key in the staged membership with a zone, see SetZone.
+func (tx *Tx) SetZone(key, zone string) error {
+	mem, ok := tx.r.nodes[key]
+	if !ok {
+		return ErrNodeNotFound
+	}
+	if mem.zone != zone {
+		mem.zone = zone
+		tx.changes = append(tx.changes, Change{Type: ChangeZone, Key: key, To: zone})
+	}
+	return nil
+}
+
+// Returns the keys in the staged membership, including standbys, sorted by
+// name.
+func (tx *Tx) Members() []string {
+	return sortedKeys(tx.r.nodes)
+}
+
+// Apply a batch of changes atomically. fn stages changes against a copy of
+// the membership, which replaces the hash's only if fn returns nil, so
+// lookups see either the old or the new membership and watchers receive a
+// single EventUpdate. fn runs under the hash's write lock and must not call
+// methods of the hash itself.
+func (m *Consistent) Update(fn func(tx *Tx) error) error {
+	return m.update(nil, fn)
+}
+
+// Update, first checking that the generation is expected if it is not nil.
+func (m *Consistent) update(expected *uint64, fn func(tx *Tx) error) error {
+	var err error
+	m.mutate(&err, func() *Event {
+		if expected != nil && *expected != m.generation {
+			err = fmt.Errorf("%w: expected %d, at %d", ErrGenerationMismatch, *expected, m.generation)
+			return nil
+		}
+		tx := &Tx{r: m.ring.clone(), replicas: m.replicas}
+		if err = fn(tx); err != nil {
+			return nil
+		}
+		tx.r.sortKeys()
+
+		e := diff(m.ring, tx.r)
+		if e == nil {
+			return nil
+		}
+		m.ring = tx.r
+		m.history.record(tx.changes...)
+		return e
+	})
+
+	return err
+}
+
+// Describe the membership changes between two rings, or nil if there are none.
+func diff(from, to *ring) *Event {
+	e := &Event{Type: EventUpdate}
+	for key, mem := range to.nodes {
+		prev, ok := from.nodes[key]
+		if !ok {
+			e.Added = append(e.Added, key)
+		} else if prev.weight != mem.weight || prev.standby != mem.standby || prev.zone != mem.zone {
+			e.Changed = append(e.Changed, key)
+		}
+	}
+	for key := range from.nodes {
+		if _, ok := to.nodes[key]; !ok {
+			e.Removed = append(e.Removed, key)
+		}
+	}
+	if len(e.Added) == 0 && len(e.Removed) == 0 && len(e.Changed) == 0 {
+		return nil
+	}
+
+	sort.Strings(e.Added)
+	sort.Strings(e.Removed)
+	sort.Strings(e.Changed)
+	return e
+}
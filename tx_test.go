@@ -0,0 +1,145 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestUpdateRollsBack(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	var events []Event
+	m.Watch(func(e Event) { events = append(events, e) })
+	gen, fp := m.Generation(), m.Fingerprint()
+	err := m.Update(func(tx *Tx) error {
+		tx.Add("x")
+		tx.Remove("n0")
+		return errors.New("changed my mind")
+	})
+	if err == nil || m.Generation() != gen || m.Fingerprint() != fp || len(events) != 0 {
+		t.Fatalf("failed update: %v, generation %d, %d events", err, m.Generation(), len(events))
+	}
+
+	err = m.Update(func(tx *Tx) error {
+		for i := 0; i < 50; i++ {
+			tx.AddWithWeight(fmt.Sprint("new", i), 20)
+		}
+		tx.Remove("n0")
+		return tx.SetWeight("n1", 3)
+	})
+	if err != nil || len(events) != 1 || len(events[0].Added) != 50 || len(events[0].Removed) != 1 || len(events[0].Changed) != 1 {
+		t.Fatalf("update: %v, events %+v", err, events)
+	}
+
+	// The result is the ring the same changes make one by one
+	want := New(nil, WithReplicas(20))
+	for i := 1; i < 10; i++ {
+		want.Add(fmt.Sprint("n", i))
+	}
+	want.SetWeight("n1", 3)
+	for i := 0; i < 50; i++ {
+		want.AddWithWeight(fmt.Sprint("new", i), 20)
+	}
+	if m.Fingerprint() != want.Fingerprint() {
+		t.Fatal("the update gave another ring than the same changes one by one")
+	}
+}
+
+// Readers racing transactions that swap between two memberships see one or
+// the other, never a mix. Run with -race.
+func TestUpdateConcurrentReaders(t *testing.T) {
+	m := New(nil, WithReplicas(20))
+	for i := 0; i < 10; i++ {
+		m.Add(fmt.Sprint("n", i))
+	}
+	toB := func(tx *Tx) error {
+		for i := 0; i < 5; i++ {
+			tx.Remove(fmt.Sprint("n", i))
+			tx.Add(fmt.Sprint("m", i))
+		}
+		return tx.SetWeight("n9", 40)
+	}
+	toA := func(tx *Tx) error {
+		for i := 0; i < 5; i++ {
+			tx.Remove(fmt.Sprint("m", i))
+			tx.AddWithWeight(fmt.Sprint("n", i), 20)
+		}
+		return tx.SetWeight("n9", 20)
+	}
+
+	keys := testKeys(300)
+	type state struct {
+		fp      uint64
+		members []string
+		entries []Entry
+		owners  map[string]string
+	}
+	capture := func() state {
+		s := state{fp: m.Fingerprint(), members: m.Members(), entries: m.Entries(), owners: make(map[string]string, len(keys))}
+		for _, key := range keys {
+			s.owners[key] = m.Get(key)
+		}
+		return s
+	}
+	a := capture()
+	if err := m.Update(toB); err != nil {
+		t.Fatal(err)
+	}
+	b := capture()
+	if err := m.Update(toA); err != nil {
+		t.Fatal(err)
+	}
+	if m.Fingerprint() != a.fp {
+		t.Fatal("swapping back did not restore the ring")
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if fp := m.Fingerprint(); fp != a.fp && fp != b.fp {
+					t.Errorf("fingerprint %016x of neither membership", fp)
+					return
+				}
+				if members := m.Members(); !reflect.DeepEqual(members, a.members) && !reflect.DeepEqual(members, b.members) {
+					t.Errorf("members %q of neither membership", members)
+					return
+				}
+				if entries := m.Entries(); !reflect.DeepEqual(entries, a.entries) && !reflect.DeepEqual(entries, b.entries) {
+					t.Errorf("%d points of neither membership", len(entries))
+					return
+				}
+				for _, key := range keys {
+					if got := m.Get(key); got != a.owners[key] && got != b.owners[key] {
+						t.Errorf("%s: routed to %s, want %s or %s", key, got, a.owners[key], b.owners[key])
+						return
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		fn := toB
+		if i%2 == 1 {
+			fn = toA
+		}
+		if err := m.Update(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,77 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var ErrInconsistent = errors.New("consistent: internal state is inconsistent")
+
+// Check that the internal indexes of the hash agree with each other: the
+// points are sorted, every point belongs to a present item, and each item's
+// positions are exactly its points. A failure is a bug in this package.
+func (m *Consistent) Validate() error {
+	m.RLock()
+	defer m.RUnlock()
+	if err := m.ring.validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInconsistent, err)
+	}
+	return nil
+}
+
+func (r *ring) validate() error {
+	if r.staged != nil {
+		return errors.New("points are still staged")
+	}
+
+	counts := make(map[int32]int, len(r.nodes))
+	for i := 0; i < r.size(); i++ {
+		pos, p := r.index.at(i)
+		if i > 0 && r.key(i-1) >= pos {
+			return fmt.Errorf("position %d is out of order", pos)
+		}
+		if r.index.search(pos) != i {
+			return fmt.Errorf("position %d is not found at its index %d", pos, i)
+		}
+		if pos < 0 || pos > MaxPosition {
+			return fmt.Errorf("position %d is out of range", pos)
+		}
+		if p.node < 0 || int(p.node) >= len(r.names) {
+			return fmt.Errorf("position %d has unknown id %d", pos, p.node)
+		}
+		mem, ok := r.nodes[r.names[p.node]]
+		if !ok || mem.id != p.node {
+			return fmt.Errorf("position %d belongs to %q, which is not present", pos, r.names[p.node])
+		}
+		if int(p.replica) >= mem.weight {
+			return fmt.Errorf("position %d is replica %d of %q with weight %d", pos, p.replica, r.names[p.node], mem.weight)
+		}
+		counts[p.node]++
+	}
+
+	free := make(map[int32]bool, len(r.free))
+	for _, id := range r.free {
+		free[id] = true
+	}
+	for key, mem := range r.nodes {
+		if int(mem.id) >= len(r.names) || r.names[mem.id] != key || free[mem.id] {
+			return fmt.Errorf("%q has id %d, which names another item", key, mem.id)
+		}
+		if !sort.IntsAreSorted(mem.positions) {
+			return fmt.Errorf("positions of %q are not sorted", key)
+		}
+		if len(mem.positions) != counts[mem.id] {
+			return fmt.Errorf("%q lists %d positions but has %d points", key, len(mem.positions), counts[mem.id])
+		}
+		for _, pos := range mem.positions {
+			if p, ok := r.pointAt(pos); !ok || p.node != mem.id {
+				return fmt.Errorf("%q lists position %d, which it does not hold", key, pos)
+			}
+		}
+	}
+	if len(r.names)-len(r.free) != len(r.nodes) {
+		return fmt.Errorf("%d ids in use for %d items", len(r.names)-len(r.free), len(r.nodes))
+	}
+	return nil
+}
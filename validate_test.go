@@ -0,0 +1,105 @@
+package consistent
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// A hash onto few positions, so points collide often.
+func smallHash(data []byte) uint32 {
+	return fnv32(data) % 500
+}
+
+// Validate holds after every step of a random sequence of mutations, with
+// both indexes, arc-splitting placement and colliding points.
+func TestValidateRandomMutations(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		fn   Hash
+		opts []Option
+	}{
+		{"default", nil, nil},
+		{"collisions", smallHash, nil},
+		{"churn", nil, []Option{WithChurnOptimizedIndex()}},
+		{"churn collisions", smallHash, []Option{WithChurnOptimizedIndex()}},
+		{"arc splitting", nil, []Option{WithArcSplittingPlacement()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := New(tc.fn, append([]Option{WithReplicas(5)}, tc.opts...)...)
+			rnd := rand.New(rand.NewSource(7))
+			name := func() string { return fmt.Sprint("n", rnd.Intn(30)) }
+			for step := 0; step < 3000; step++ {
+				key := name()
+				switch rnd.Intn(12) {
+				case 0:
+					m.Add(key)
+				case 1:
+					m.AddWithWeight(key, rnd.Intn(20))
+				case 2:
+					m.Remove(key)
+				case 3:
+					m.SetWeight(key, rnd.Intn(20))
+				case 4:
+					m.Rename(key, name())
+				case 5:
+					m.Update(func(tx *Tx) error {
+						for i := 0; i < 5; i++ {
+							k := name()
+							switch rnd.Intn(4) {
+							case 0:
+								tx.AddWithWeight(k, rnd.Intn(20))
+							case 1:
+								tx.Remove(k)
+							case 2:
+								tx.SetWeight(k, rnd.Intn(20))
+							case 3:
+								tx.AddStandby(k)
+							}
+						}
+						return nil
+					})
+				case 6:
+					m.AddStandby(key)
+				case 7:
+					m.Promote(key)
+				case 8:
+					m.AddTiered(key, rnd.Intn(3))
+				case 9:
+					m.SetHealthy(key, rnd.Intn(2) == 0)
+				case 10:
+					m.Pin(fmt.Sprint("key", rnd.Intn(10)), key)
+				case 11:
+					r, err := m.restore(m.Snapshot())
+					if err != nil {
+						t.Fatal(step, err)
+					}
+					if err := r.validate(); err != nil {
+						t.Fatal(step, "restored:", err)
+					}
+				}
+				if err := m.Validate(); err != nil {
+					t.Fatal(step, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDetectsCorruption(t *testing.T) {
+	m := New(nil, WithReplicas(3))
+	m.Add("a")
+	m.Add("b")
+	m.ring.nodes["a"].positions = m.ring.nodes["a"].positions[:2]
+	if err := m.Validate(); !errors.Is(err, ErrInconsistent) {
+		t.Fatalf("missing position: %v", err)
+	}
+
+	m = New(nil, WithReplicas(3))
+	m.Add("a")
+	delete(m.ring.nodes, "a")
+	if err := m.Validate(); !errors.Is(err, ErrInconsistent) {
+		t.Fatalf("point of an absent item: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package consistent
+
+import (
+	"iter"
+)
+
+// Reader is the non-mutating surface of a hash.
+type Reader interface {
+	IsEmpty() bool
+	Members() []string
+	Weight(key string) (int, bool)
+	IsStandby(key string) bool
+	IsHealthy(key string) bool
+	Generation() uint64
+	Fingerprint() uint64
+	Hash(key string) int
+	Get(key string) string
+	GetOwner(key string) (Owner, bool)
+	NextN(key string, n int) []string
+	NextNOwners(key string, n int) []Owner
+	PrevN(key string, n int) []string
+	PrevNDistinct(key string, n int, includeSelf bool) []string
+	Next(key string) string
+	Range(host string) (int, int)
+	Ranges(key string) []HashRange
+	OwnersInRange(from, to int) []string
+	RangeOwners(from, to int) []OwnedRange
+	ArcLength(key string) (uint64, bool)
+	HashParts(parts ...string) int
+	GetParts(parts ...string) string
+	Stats() Stats
+	VirtualNodes(node string) []VirtualNode
+	PositionsOf(key string) ([]int, bool)
+	Entries() []Entry
+	Positions() []uint32
+	Before(key string) iter.Seq2[uint32, string]
+	BeforePosition(pos uint32) iter.Seq2[uint32, string]
+	BeforeDistinct(key string) iter.Seq2[uint32, string]
+}
+
+// View is a read-only handle on a hash. It shares the underlying hash, so it
+// always reflects the current membership.
+type View struct {
+	m *Consistent
+}
+
+var (
+	_ Reader = (*Consistent)(nil)
+	_ Reader = View{}
+)
+
+// Get a read-only view of the hash to hand to code that should only look
+// items up.
+func (m *Consistent) ReadOnly() View {
+	return View{m: m}
+}
+
+func (v View) IsEmpty() bool                         { return v.m.IsEmpty() }
+func (v View) Members() []string                     { return v.m.Members() }
+func (v View) Weight(key string) (int, bool)         { return v.m.Weight(key) }
+func (v View) Generation() uint64                    { return v.m.Generation() }
+func (v View) Hash(key string) int                   { return v.m.Hash(key) }
+func (v View) Get(key string) string                 { return v.m.Get(key) }
+func (v View) Next(key string) string                { return v.m.Next(key) }
+func (v View) GetOwner(key string) (Owner, bool)     { return v.m.GetOwner(key) }
+func (v View) NextN(key string, n int) []string      { return v.m.NextN(key, n) }
+func (v View) NextNOwners(key string, n int) []Owner { return v.m.NextNOwners(key, n) }
+func (v View) PrevN(key string, n int) []string      { return v.m.PrevN(key, n) }
+func (v View) PrevNDistinct(key string, n int, includeSelf bool) []string {
+	return v.m.PrevNDistinct(key, n, includeSelf)
+}
+func (v View) Range(host string) (int, int)          { return v.m.Range(host) }
+func (v View) Ranges(key string) []HashRange         { return v.m.Ranges(key) }
+func (v View) OwnersInRange(from, to int) []string   { return v.m.OwnersInRange(from, to) }
+func (v View) RangeOwners(from, to int) []OwnedRange { return v.m.RangeOwners(from, to) }
+func (v View) ArcLength(key string) (uint64, bool)   { return v.m.ArcLength(key) }
+func (v View) Fingerprint() uint64                   { return v.m.Fingerprint() }
+func (v View) IsStandby(key string) bool             { return v.m.IsStandby(key) }
+func (v View) IsHealthy(key string) bool             { return v.m.IsHealthy(key) }
+func (v View) HashParts(parts ...string) int         { return v.m.HashParts(parts...) }
+func (v View) GetParts(parts ...string) string       { return v.m.GetParts(parts...) }
+
+func (v View) Stats() Stats                           { return v.m.Stats() }
+func (v View) VirtualNodes(node string) []VirtualNode { return v.m.VirtualNodes(node) }
+func (v View) PositionsOf(key string) ([]int, bool)   { return v.m.PositionsOf(key) }
+func (v View) Entries() []Entry                       { return v.m.Entries() }
+func (v View) Positions() []uint32                    { return v.m.Positions() }
+func (v View) Before(key string) iter.Seq2[uint32, string] {
+	return v.m.Before(key)
+}
+func (v View) BeforePosition(pos uint32) iter.Seq2[uint32, string] {
+	return v.m.BeforePosition(pos)
+}
+func (v View) BeforeDistinct(key string) iter.Seq2[uint32, string] {
+	return v.m.BeforeDistinct(key)
+}
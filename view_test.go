@@ -0,0 +1,78 @@
+package consistent
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestViewFollowsParent(t *testing.T) {
+	c := New(nil, WithReplicas(8))
+	v := c.ReadOnly()
+	if !v.IsEmpty() || v.Get("x") != "" {
+		t.Fatal("not empty")
+	}
+
+	same := func(step string) {
+		t.Helper()
+		if v.Generation() != c.Generation() || v.Fingerprint() != c.Fingerprint() {
+			t.Fatalf("%s: generation %d/%d", step, v.Generation(), c.Generation())
+		}
+		if !reflect.DeepEqual(v.Members(), c.Members()) || !reflect.DeepEqual(v.Stats(), c.Stats()) ||
+			!reflect.DeepEqual(v.Entries(), c.Entries()) || !reflect.DeepEqual(v.Positions(), c.Positions()) {
+			t.Fatalf("%s: membership differs", step)
+		}
+		for i := 0; i < 100; i++ {
+			k := fmt.Sprint("key", i)
+			if v.Get(k) != c.Get(k) || !reflect.DeepEqual(v.NextN(k, 3), c.NextN(k, 3)) ||
+				!reflect.DeepEqual(v.PrevN(k, 3), c.PrevN(k, 3)) {
+				t.Fatalf("%s: %s differs", step, k)
+			}
+			var vs, cs []string
+			for _, n := range v.BeforeDistinct(k) {
+				vs = append(vs, n)
+			}
+			for _, n := range c.BeforeDistinct(k) {
+				cs = append(cs, n)
+			}
+			if !slices.Equal(vs, cs) {
+				t.Fatalf("%s: %s iterates %v, want %v", step, k, vs, cs)
+			}
+		}
+		for _, n := range c.Members() {
+			if !reflect.DeepEqual(v.Ranges(n), c.Ranges(n)) || !reflect.DeepEqual(v.VirtualNodes(n), c.VirtualNodes(n)) {
+				t.Fatalf("%s: ranges of %s differ", step, n)
+			}
+		}
+	}
+
+	c.Add("a")
+	same("add")
+	if v.Get("x") != "a" || len(v.Members()) != 1 {
+		t.Fatal(v.Members())
+	}
+	c.Add("b")
+	c.AddStandby("s")
+	same("standby")
+	c.SetWeight("b", 20)
+	same("weight")
+	c.SetHealthy("a", false)
+	same("health")
+	if err := c.Rename("b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	same("rename")
+	if _, ok := v.Weight("b"); ok {
+		t.Fatal("view kept the old name")
+	}
+	c.Promote("s")
+	same("promote")
+	c.Remove("a")
+	c.Remove("c")
+	c.Remove("s")
+	same("remove")
+	if !v.IsEmpty() {
+		t.Fatal(v.Members())
+	}
+}
@@ -0,0 +1,55 @@
+package consistent
+
+// VirtualNode is one point of an item and the name hashed to place it.
+type VirtualNode struct {
+	Node     string
+	Key      string // The replica name, as written by the replica formatter, before any domain prefix
+	Replica  int
+	Position int
+}
+
+// Get the points of the provided item, in order of position. The key of
+// each point is the replica name of the item's current name, so after a
+// Rename it no longer hashes to the point's position.
+func (m *Consistent) VirtualNodes(node string) []VirtualNode {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[node]
+	if !ok {
+		return nil
+	}
+
+	vnodes := make([]VirtualNode, 0, len(mem.positions))
+	for _, pos := range mem.positions {
+		p, _ := m.ring.pointAt(pos)
+		vnodes = append(vnodes, m.virtualNode(node, int(p.replica), pos))
+	}
+	return vnodes
+}
+
+// Get the point a lookup of the provided key matches, as GetOwner does,
+// with the name hashed to place it.
+func (m *Consistent) GetVirtualNode(key string) (VirtualNode, bool) {
+	o, ok := m.GetOwner(key)
+	if !ok {
+		return VirtualNode{}, false
+	}
+	if o.Position < 0 {
+		// A pinned item without points
+		return VirtualNode{Node: o.Node, Position: -1}, true
+	}
+	return m.virtualNode(o.Node, o.Replica, o.Position), true
+}
+
+func (m *Consistent) virtualNode(node string, replica, pos int) VirtualNode {
+	format := DefaultReplicaFormatter
+	if m.formatter != nil {
+		format = m.formatter
+	}
+	return VirtualNode{
+		Node:     node,
+		Key:      string(format(node, replica)),
+		Replica:  replica,
+		Position: pos,
+	}
+}
@@ -0,0 +1,73 @@
+package consistent
+
+import "testing"
+
+func TestVirtualNodes(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"default":           nil,
+		"prefix":            {WithReplicaFormatter(PrefixIndexFormatter)},
+		"domain separation": {WithDomainSeparation()},
+	} {
+		m := New(nil, append(opts, WithReplicas(4))...)
+		m.Add("a")
+		m.Add("b")
+		vnodes := m.VirtualNodes("a")
+		if len(vnodes) != 4 {
+			t.Fatalf("%s: %d virtual nodes, want 4", name, len(vnodes))
+		}
+		replicas := make(map[int]bool)
+		for i, v := range vnodes {
+			if v.Node != "a" || m.nodeHash(v.Key) != v.Position {
+				t.Errorf("%s: %+v does not hash to its position", name, v)
+			}
+			if i > 0 && vnodes[i-1].Position >= v.Position {
+				t.Errorf("%s: virtual nodes out of order at %d", name, i)
+			}
+			replicas[v.Replica] = true
+		}
+		if len(replicas) != 4 {
+			t.Errorf("%s: replicas %v, want 4 distinct", name, replicas)
+		}
+
+		for _, key := range testKeys(200) {
+			v, ok := m.GetVirtualNode(key)
+			o, _ := m.GetOwner(key)
+			if !ok || v.Node != o.Node || v.Replica != o.Replica || v.Position != o.Position || m.nodeHash(v.Key) != v.Position {
+				t.Fatalf("%s: GetVirtualNode(%s) = %+v, owner %+v", name, key, v, o)
+			}
+		}
+	}
+}
+
+func TestVirtualNodesEdges(t *testing.T) {
+	m := New(nil, WithReplicas(2))
+	if _, ok := m.GetVirtualNode("k"); ok {
+		t.Error("a virtual node in an empty hash")
+	}
+	if vnodes := m.VirtualNodes("missing"); vnodes != nil {
+		t.Errorf("virtual nodes of a missing item: %v", vnodes)
+	}
+
+	m.Add("a")
+	if err := m.AddWithWeight("pointless", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Pin("k", "pointless"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := m.GetVirtualNode("k"); !ok || v != (VirtualNode{Node: "pointless", Position: -1}) {
+		t.Errorf("pinned to an item without points: %+v", v)
+	}
+
+	// A rename keeps the points but names them after the new name
+	before := m.VirtualNodes("a")
+	if err := m.Rename("a", "c"); err != nil {
+		t.Fatal(err)
+	}
+	after := m.VirtualNodes("c")
+	for i, v := range after {
+		if v.Position != before[i].Position || v.Key != string(DefaultReplicaFormatter("c", v.Replica)) {
+			t.Errorf("after a rename: %+v, before %+v", v, before[i])
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package consistent
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// The symbols given to items in order of name, cycling when there are more
+// items than symbols.
+const symbols = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// The ANSI foreground colors given to items, cycling like symbols.
+var colors = []int{31, 32, 33, 34, 35, 36, 91, 92, 93, 94, 95, 96}
+
+// VisualizeOption configures Visualize.
+type VisualizeOption func(*visualizeConfig)
+
+type visualizeConfig struct {
+	color *bool
+}
+
+// Turn ANSI colors on or off, rather than using them only when writing to
+// a terminal.
+func VisualizeColor(on bool) VisualizeOption {
+	return func(c *visualizeConfig) {
+		c.color = &on
+	}
+}
+
+// Draw the ring as a bar of width cells, each showing the symbol of the item
+// Get maps the middle of that slice of the hash space to, followed by a
+// legend with the share of each item. The output only depends on the ring,
+// so it can be compared against a golden file.
+func (m *Consistent) Visualize(w io.Writer, width int, opts ...VisualizeOption) error {
+	if width < 1 {
+		width = 64
+	}
+	var cfg visualizeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	color := isTerminal(w)
+	if cfg.color != nil {
+		color = *cfg.color
+	}
+
+	keys, owners := m.arcOwners()
+	stats := m.Stats()
+	members := m.Members()
+
+	bw := bufio.NewWriter(w)
+	if len(keys) == 0 {
+		fmt.Fprintln(bw, "(empty)")
+		return bw.Flush()
+	}
+
+	index := make(map[string]int, len(members))
+	for i, key := range members {
+		index[key] = i
+	}
+	paint := func(key string, s string) string {
+		if !color {
+			return s
+		}
+		return fmt.Sprintf("\x1b[%dm%s\x1b[0m", colors[index[key]%len(colors)], s)
+	}
+	symbol := func(key string) string {
+		return string(symbols[index[key]%len(symbols)])
+	}
+
+	fmt.Fprint(bw, "|")
+	for cell := 0; cell < width; cell++ {
+		mid := int((uint64(2*cell+1) * (MaxPosition + 1)) / uint64(2*width))
+		if key := ownerAt(keys, owners, mid); key != "" {
+			fmt.Fprint(bw, paint(key, symbol(key)))
+		} else {
+			fmt.Fprint(bw, " ")
+		}
+	}
+	fmt.Fprintln(bw, "|")
+
+	// Largest share first, then by name
+	sorted := append([]string(nil), members...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return stats.Shares[sorted[i]] > stats.Shares[sorted[j]]
+	})
+	for _, key := range sorted {
+		line := fmt.Sprintf("%s %6.2f%% %s", symbol(key), 100*stats.Shares[key], key)
+		if _, ok := stats.Shares[key]; !ok {
+			line += " (not serving)"
+		}
+		fmt.Fprintln(bw, paint(key, line))
+	}
+
+	return bw.Flush()
+}
+
+// Returns true if w is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,127 @@
+package consistent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVisualize(t *testing.T) {
+	m := New(fixedHash(map[string]uint32{"a": 0, "b": 1 << 30, "c": 1 << 31, "tiny": 1<<31 - 1000, "standby": 3 << 30}))
+	for _, tc := range []struct {
+		name  string
+		setup func()
+		width int
+		opts  []VisualizeOption
+		want  string
+	}{{
+		name:  "empty",
+		setup: func() {},
+		width: 8,
+		want:  "(empty)\n",
+	}, {
+		name: "uneven shares",
+		setup: func() {
+			m.Add("a")
+			m.Add("b")
+			m.Add("c")
+		},
+		width: 8,
+		want:  "|AABBCCCC|\nC  50.00% c\nA  25.00% a\nB  25.00% b\n",
+	}, {
+		name: "an arc smaller than a cell and a standby",
+		setup: func() {
+			m.Add("tiny")
+			m.AddStandby("standby")
+		},
+		width: 4,
+		want: "|ABCC|\nC  50.00% c\nA  25.00% a\nB  25.00% b\n" +
+			"E   0.00% tiny\nD   0.00% standby (not serving)\n",
+	}, {
+		name:  "colors",
+		setup: func() { m.Remove("tiny") },
+		width: 2,
+		opts:  []VisualizeOption{VisualizeColor(true)},
+		want: "|\x1b[32mB\x1b[0m\x1b[33mC\x1b[0m|\n" +
+			"\x1b[33mC  50.00% c\x1b[0m\n\x1b[31mA  25.00% a\x1b[0m\n\x1b[32mB  25.00% b\x1b[0m\n" +
+			"\x1b[34mD   0.00% standby (not serving)\x1b[0m\n",
+	}} {
+		tc.setup()
+		var b bytes.Buffer
+		if err := m.Visualize(&b, tc.width, tc.opts...); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != tc.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tc.name, b.String(), tc.want)
+		}
+	}
+}
+
+// The drawing of a ring placed by the default hash, which changes only if
+// placement or the format does.
+func TestVisualizeGolden(t *testing.T) {
+	m := New(nil)
+	for i := 0; i < 5; i++ {
+		m.AddWithWeight(fmt.Sprint("node", i), 20)
+	}
+	want := `|CCCDCCDDDDAAAAADDDDCBCCCCBACCCCCAAEEDEEDDAACCCEE|
+C  31.74% node2
+D  25.97% node3
+A  21.02% node0
+E  14.94% node4
+B   6.34% node1
+`
+	for i := 0; i < 2; i++ {
+		var b bytes.Buffer
+		m.Visualize(&b, 48)
+		if b.String() != want {
+			t.Fatalf("got\n%s\nwant\n%s", b.String(), want)
+		}
+	}
+}
+
+func TestVisualizeCyclesSymbols(t *testing.T) {
+	m := New(nil)
+	for i := 0; i < len(symbols)+2; i++ {
+		m.Add(fmt.Sprintf("n%03d", i))
+	}
+	var b bytes.Buffer
+	m.Visualize(&b, 0)
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(symbols)+3 || len(lines[0]) != 64+2 {
+		t.Fatalf("%d lines, a bar of %d", len(lines), len(lines[0]))
+	}
+	for _, line := range lines[1:] {
+		var symbol, name string
+		var share float64
+		fmt.Sscanf(line, "%s %f%% %s", &symbol, &share, &name)
+		var i int
+		fmt.Sscanf(name, "n%d", &i)
+		if want := string(symbols[i%len(symbols)]); symbol != want {
+			t.Errorf("%s has symbol %s, want %s", name, symbol, want)
+		}
+	}
+}
+
+func TestVisualizeFileWithoutColors(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	f, err := os.Create(filepath.Join(t.TempDir(), "ring.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := m.Visualize(f, 4); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "|AAAA|\nA 100.00% a\n"; string(b) != want {
+		t.Fatalf("got %q, want %q", b, want)
+	}
+}
@@ -0,0 +1,155 @@
+package consistent
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// OwnerChange reports that a key moved to another item. An empty owner means
+// the key had or has no item.
+type OwnerChange struct {
+	Key        string
+	Old        string
+	New        string
+	Generation uint64
+}
+
+// Watch the owners of the provided keys until ctx is done, when the channel
+// is closed. A change is sent only when a key's owner differs after a change
+// to the hash. Changes the receiver has not yet taken are coalesced per key,
+// keeping the owner before the first and after the last, so the final owner
+// is always delivered.
+func (m *Consistent) WatchKey(ctx context.Context, keys ...string) <-chan OwnerChange {
+	w := &keyWatch{
+		m:       m,
+		keys:    keys,
+		pending: make(map[string]OwnerChange),
+		signal:  make(chan struct{}, 1),
+	}
+	// Looked up after watching, so a change in between is not missed; an
+	// update waits for the owners under w.mu
+	w.mu.Lock()
+	cancel := m.Watch(func(Event) { w.update() })
+	w.owners, _ = m.owners(keys)
+	w.mu.Unlock()
+
+	ch := make(chan OwnerChange)
+	go func() {
+		defer close(ch)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.signal:
+			}
+			for _, c := range w.take() {
+				select {
+				case ch <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+type keyWatch struct {
+	m    *Consistent
+	keys []string
+
+	mu      sync.Mutex
+	owners  map[string]string
+	pending map[string]OwnerChange
+	signal  chan struct{}
+}
+
+func (w *keyWatch) update() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Looked up under w.mu so concurrent updates apply in order.
+	owners, generation := w.m.owners(w.keys)
+	changed := false
+	for key, node := range owners {
+		old := w.owners[key]
+		if node == old {
+			continue
+		}
+		w.owners[key] = node
+		c, ok := w.pending[key]
+		if !ok {
+			c = OwnerChange{Key: key, Old: old}
+		}
+		c.New, c.Generation = node, generation
+		if c.New == c.Old {
+			delete(w.pending, key)
+		} else {
+			w.pending[key] = c
+		}
+		changed = true
+	}
+	if changed {
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Take the pending changes, sorted by key.
+func (w *keyWatch) take() []OwnerChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changes := make([]OwnerChange, 0, len(w.pending))
+	for _, c := range w.pending {
+		changes = append(changes, c)
+	}
+	clear(w.pending)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// Get the owner of each key against a single view of the hash, and its
+// generation.
+func (m *Consistent) owners(keys []string) (map[string]string, uint64) {
+	m.RLock()
+	defer m.RUnlock()
+	hashes := make([]int, len(keys))
+	for i, key := range keys {
+		hashes[i] = m.Hash(key)
+	}
+	owners := make(map[string]string, len(keys))
+	for i, key := range keys {
+		owners[key] = m.ownerOf(key, hashes[i])
+	}
+	return owners, m.generation
+}
+
+// Get the item for a key, waiting until ctx is done for the hash to have
+// one, such as while discovery fills the ring at startup. The key is looked
+// up again after every change to the hash. Returns the context's error if
+// it is done first.
+func (m *Consistent) WaitForOwner(ctx context.Context, key string) (string, error) {
+	changed := make(chan struct{}, 1)
+	cancel := m.Watch(func(Event) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer cancel()
+
+	for {
+		// Looked up after watching, so a change in between is not missed
+		if node := m.Get(key); node != "" {
+			return node, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-changed:
+		}
+	}
+}
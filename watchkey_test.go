@@ -0,0 +1,150 @@
+package consistent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// Follow the changes of a watch until every key has its final owner.
+func followOwners(t *testing.T, ch <-chan OwnerChange, owners, final map[string]string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for !mapsMatch(owners, final) {
+		select {
+		case c := <-ch:
+			if owners[c.Key] != c.Old || c.Old == c.New {
+				t.Fatalf("change %+v, owner %q", c, owners[c.Key])
+			}
+			owners[c.Key] = c.New
+		case <-timeout:
+			t.Fatalf("owners %v, want %v", owners, final)
+		}
+	}
+}
+
+func mapsMatch(a, b map[string]string) bool {
+	for k, v := range b {
+		if a[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestWatchKeyDeliversFinalOwners(t *testing.T) {
+	c := New(nil, WithReplicas(20))
+	c.Add("a")
+	keys := testKeys(50)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch := c.WatchKey(ctx, keys...)
+	owners := getAll(c, keys)
+	for i := 0; i < 4; i++ {
+		c.Add(fmt.Sprint("n", i))
+	}
+	c.Remove("n2")
+	followOwners(t, ch, owners, getAll(c, keys))
+
+	// A key returning to its owner before the receiver takes the change
+	// sends nothing
+	c.Remove("n0")
+	c.Add("n0")
+	select {
+	case change := <-ch:
+		t.Fatalf("change %+v for a move undone", change)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	for range ch {
+	}
+}
+
+// A change made right after WatchKey returns is delivered.
+func TestWatchKeyMutateRightAfter(t *testing.T) {
+	keys := testKeys(20)
+	for i := 0; i < 100; i++ {
+		c := New(nil)
+		c.Add("a")
+		before := getAll(c, keys)
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := c.WatchKey(ctx, keys...)
+		c.Add("b")
+		followOwners(t, ch, before, getAll(c, keys))
+		cancel()
+	}
+}
+
+func TestWaitForOwnerDelayedAdd(t *testing.T) {
+	m := New(nil)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		m.AddStandby("standby") // Still no owner
+		m.Add("a")
+	}()
+	start := time.Now()
+	node, err := m.WaitForOwner(context.Background(), "key")
+	if err != nil || node != "a" {
+		t.Fatalf("got %q, %v", node, err)
+	}
+	if waited := time.Since(start); waited < 40*time.Millisecond {
+		t.Fatalf("returned after %v, before the item was added", waited)
+	}
+}
+
+func TestWaitForOwnerTimeout(t *testing.T) {
+	m := New(nil)
+	m.AddStandby("standby")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if node, err := m.WaitForOwner(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) || node != "" {
+		t.Fatalf("got %q, %v", node, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.WaitForOwner(ctx, "key"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: %v", err)
+	}
+	if len(m.watchers) != 0 {
+		t.Fatalf("%d watchers left behind", len(m.watchers))
+	}
+}
+
+func TestWaitForOwnerPopulated(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	m.Add("b")
+	// A done context does not matter when the key has an owner
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if node, err := m.WaitForOwner(ctx, "key"); err != nil || node != m.Get("key") {
+		t.Fatalf("got %q, %v, want %q", node, err, m.Get("key"))
+	}
+}
+
+// An item coming back from unhealthy is an owner too.
+func TestWaitForOwnerHealthy(t *testing.T) {
+	m := New(nil)
+	m.Add("a")
+	m.SetHealthy("a", false)
+	done := make(chan string)
+	go func() {
+		node, _ := m.WaitForOwner(context.Background(), "key")
+		done <- node
+	}()
+	time.Sleep(10 * time.Millisecond)
+	m.SetHealthy("a", true)
+	select {
+	case node := <-done:
+		if node != "a" {
+			t.Fatalf("got %q", node)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("still waiting after the item recovered")
+	}
+}
@@ -0,0 +1,54 @@
+package consistent
+
+// Label an item with the zone, such as an availability zone, it runs in.
+// Zones are not used for placement, so a frozen hash still takes them, but a
+// change moves the generation and reaches watchers and ChangesSince. An
+// empty zone clears the label.
+func (m *Consistent) SetZone(key, zone string) error {
+	var err error
+	m.reroute(func() *Event {
+		mem, ok := m.ring.nodes[key]
+		if !ok {
+			err = ErrNodeNotFound
+			return nil
+		}
+		if mem.zone == zone {
+			return nil
+		}
+		mem.zone = zone
+		m.history.record(Change{Type: ChangeZone, Key: key, To: zone})
+		return &Event{Type: EventZone, Changed: []string{key}}
+	})
+	return err
+}
+
+// Returns the zone of an item.
+func (m *Consistent) Zone(key string) (string, bool) {
+	m.RLock()
+	defer m.RUnlock()
+	mem, ok := m.ring.nodes[key]
+	if !ok {
+		return "", false
+	}
+	return mem.zone, true
+}
+
+// Get the first of the rf owners NextN returns for the provided key whose
+// zone is callerZone, or the first owner if none is in that zone, so callers
+// in a zone holding a replica read from it. The result is always one of the
+// owners. Returns "" if the hash has no items; panics if rf is negative.
+func (m *Consistent) GetLocal(key, callerZone string, rf int) string {
+	owners := m.NextN(key, rf)
+	if len(owners) == 0 {
+		return ""
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+	for _, node := range owners {
+		if mem, ok := m.ring.nodes[node]; ok && mem.zone == callerZone {
+			return node
+		}
+	}
+	return owners[0]
+}
@@ -0,0 +1,114 @@
+package consistent
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// A zone change moves the generation, reaches watchers and is replayed by
+// followers, also while the hash is frozen.
+func TestSetZoneIsRecorded(t *testing.T) {
+	leader := New(nil, WithReplicas(4))
+	leader.Add("a")
+	leader.Add("b")
+	path := filepath.Join(t.TempDir(), "ring.json")
+	if err := leader.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	follower, err := Load(path, WithReplicas(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	leader.Watch(func(e Event) { events = append(events, e) })
+	gen := leader.Generation()
+	leader.Freeze()
+	if err := leader.SetZone("a", "z1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.SetZone("a", "z1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.SetZone("x", "z1"); err != ErrNodeNotFound {
+		t.Fatalf("zone of a missing item: %v", err)
+	}
+	if leader.Generation() != gen+1 {
+		t.Fatalf("generation %d, want %d", leader.Generation(), gen+1)
+	}
+	if len(events) != 1 || events[0].Type != EventZone || len(events[0].Changed) != 1 || events[0].Changed[0] != "a" {
+		t.Fatalf("events %+v", events)
+	}
+
+	d, err := leader.ChangesSince(follower.Generation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := follower.ApplyDelta(d); err != nil {
+		t.Fatal(err)
+	}
+	if zone, _ := follower.Zone("a"); zone != "z1" {
+		t.Fatalf("follower has zone %q", zone)
+	}
+}
+
+func TestGetLocal(t *testing.T) {
+	m := New(nil, WithReplicas(50))
+	zones := []string{"a", "b", "c"}
+	for _, zone := range zones {
+		for i := 0; i < 2; i++ {
+			node := fmt.Sprint(zone, i)
+			m.Add(node)
+			m.SetZone(node, zone)
+		}
+	}
+	for _, key := range testKeys(500) {
+		owners := m.NextN(key, 3)
+		for _, zone := range zones {
+			got := m.GetLocal(key, zone, 3)
+			if !slices.Contains(owners, got) {
+				t.Fatalf("%s from zone %s: %s is not one of the owners %v", key, zone, got, owners)
+			}
+			// The first owner in the zone, or the primary if there is none
+			want := owners[0]
+			for _, node := range owners {
+				if z, _ := m.Zone(node); z == zone {
+					want = node
+					break
+				}
+			}
+			if got != want {
+				t.Fatalf("%s from zone %s: %s, want %s of %v", key, zone, got, want, owners)
+			}
+		}
+		if got := m.GetLocal(key, "elsewhere", 3); got != owners[0] {
+			t.Fatalf("%s from another zone: %s, want the primary %s", key, got, owners[0])
+		}
+		if got := m.GetLocal(key, "a", 0); got != "" {
+			t.Fatalf("%s with no owners: %q", key, got)
+		}
+	}
+}
+
+// With one item per zone and a replica in each, every caller reads from its
+// own zone.
+func TestGetLocalReplicaPerZone(t *testing.T) {
+	m := New(nil, WithReplicas(50))
+	zones := []string{"a", "b", "c"}
+	for _, zone := range zones {
+		m.Add("n-" + zone)
+		m.SetZone("n-"+zone, zone)
+	}
+	for _, key := range testKeys(500) {
+		for _, zone := range zones {
+			if got := m.GetLocal(key, zone, 3); got != "n-"+zone {
+				t.Fatalf("%s from zone %s: %s", key, zone, got)
+			}
+		}
+	}
+	if got := New(nil).GetLocal("k", "a", 3); got != "" {
+		t.Fatalf("empty hash: %q", got)
+	}
+}